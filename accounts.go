@@ -0,0 +1,128 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Account is a DirectAdmin user whose login key manages some of the zones,
+// see Provider.Accounts.
+type Account struct {
+	// Zones lists the zones of the account. An entry also covers the zones
+	// below it, so `example.com` matches `shop.example.com` too.
+	Zones []string `json:"zones"`
+
+	User         string `json:"user"`
+	LoginKey     string `json:"login_key,omitempty"`
+	LoginKeyFile string `json:"login_key_file,omitempty"`
+}
+
+// matches returns how specific the account's best entry for zone is, 0 if
+// no entry matches.
+func (a *Account) matches(zone string) int {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	best := 0
+	for _, z := range a.Zones {
+		z = strings.ToLower(strings.TrimSuffix(z, "."))
+		if (zone == z || strings.HasSuffix(zone, "."+z)) && len(z) > best {
+			best = len(z)
+		}
+	}
+
+	return best
+}
+
+// ErrNoAccount is returned when Accounts is configured but none of them,
+// and no default User, covers the zone.
+var ErrNoAccount = errors.New("no account configured for zone")
+
+type accountKey struct{}
+
+// withAccount makes requests made with ctx use the account regardless of
+// their zone, for requests such as listing domains that aren't for a zone.
+func withAccount(ctx context.Context, account *Account) context.Context {
+	return context.WithValue(ctx, accountKey{}, account)
+}
+
+// account returns the account whose zones match zone most specifically,
+// or nil if there's none.
+func (p *Provider) account(ctx context.Context, zone string) *Account {
+	if account, ok := ctx.Value(accountKey{}).(*Account); ok {
+		return account
+	}
+
+	var best *Account
+	bestMatch := 0
+	for i := range p.Accounts {
+		if match := p.Accounts[i].matches(zone); match > bestMatch {
+			best, bestMatch = &p.Accounts[i], match
+		}
+	}
+
+	return best
+}
+
+// accountCredentials returns the credentials of an account, reading its
+// login key file on first use.
+func (p *Provider) accountCredentials(account *Account) (Credentials, error) {
+	if len(account.LoginKey) > 0 {
+		return Credentials{User: account.User, LoginKey: account.LoginKey}, nil
+	}
+
+	if key, ok := p.accountKeys.Load(account.LoginKeyFile); ok {
+		return Credentials{User: account.User, LoginKey: key.(string)}, nil
+	}
+
+	key, err := readLoginKeyFile(account.LoginKeyFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.accountKeys.Store(account.LoginKeyFile, key)
+
+	return Credentials{User: account.User, LoginKey: key}, nil
+}
+
+// reloadAccountKey re-reads the account's login key file and reports
+// whether the key changed.
+func (p *Provider) reloadAccountKey(account *Account) (bool, error) {
+	if len(account.LoginKey) > 0 {
+		return false, nil
+	}
+
+	key, err := readLoginKeyFile(account.LoginKeyFile)
+	if err != nil {
+		return false, err
+	}
+
+	old, _ := p.accountKeys.Load(account.LoginKeyFile)
+	p.accountKeys.Store(account.LoginKeyFile, key)
+
+	return old != key, nil
+}
+
+func (a *Account) validate(i int) []error {
+	var errs []error
+
+	name := a.User
+	if len(name) == 0 {
+		name = fmt.Sprintf("#%d", i+1)
+	}
+
+	if len(a.User) == 0 {
+		errs = append(errs, fmt.Errorf("account %v: user is required", name))
+	}
+	if len(a.Zones) == 0 {
+		errs = append(errs, fmt.Errorf("account %v: zones are required", name))
+	}
+	if len(a.LoginKey) > 0 && len(a.LoginKeyFile) > 0 {
+		errs = append(errs, fmt.Errorf("account %v: login key and login key file are mutually exclusive", name))
+	}
+	if len(a.LoginKey) == 0 && len(a.LoginKeyFile) == 0 {
+		errs = append(errs, fmt.Errorf("account %v: login key or login key file is required", name))
+	}
+
+	return errs
+}
@@ -0,0 +1,107 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProvider_Accounts(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("shop-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	domains := map[string]string{
+		"admin:admin-key":    `["example.com"]`,
+		"client:client-key":  `["client.example", "unrelated.example"]`,
+		"shop:shop-key":      `["shop.client.example"]`,
+		"default:unexpected": `[]`,
+	}
+
+	var user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, key, _ := r.BasicAuth()
+		user = u
+		if r.URL.Path == "/CMD_API_SHOW_DOMAINS" {
+			_, _ = w.Write([]byte(domains[u+":"+key]))
+			return
+		}
+		_, _ = w.Write([]byte(`{"records":[]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "admin-key",
+		WithAllowInsecureHTTP(),
+		WithAccount("client", "client-key", "client.example"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Accounts = append(provider.Accounts, Account{Zones: []string{"shop.client.example."}, User: "shop", LoginKeyFile: keyFile})
+
+	var tests = []struct {
+		zone string
+		user string
+	}{
+		{zone: "example.com", user: "admin"},
+		{zone: "client.example.", user: "client"},
+		{zone: "blog.client.example", user: "client"},
+		{zone: "shop.client.example", user: "shop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			if _, err := provider.GetRecords(context.Background(), tt.zone); err != nil {
+				t.Fatal(err)
+			}
+			if user != tt.user {
+				t.Errorf("expected request as %v, got %v", tt.user, user)
+			}
+		})
+	}
+
+	zones, err := provider.ListZones(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []libdns.Zone{{Name: "example.com."}, {Name: "client.example."}, {Name: "shop.client.example."}}
+	if !reflect.DeepEqual(zones, want) {
+		t.Errorf("expected zones %v, got %v", want, zones)
+	}
+
+	provider.User, provider.LoginKey = "", ""
+	if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, ErrNoAccount) {
+		t.Errorf("expected ErrNoAccount without a default user, got %v", err)
+	}
+}
+
+func TestValidate_Accounts(t *testing.T) {
+	var tests = []struct {
+		name     string
+		user     string
+		accounts []Account
+		valid    bool
+	}{
+		{name: "accounts only", accounts: []Account{{Zones: []string{"example.com"}, User: "a", LoginKey: "k"}}, valid: true},
+		{name: "missing zones", accounts: []Account{{User: "a", LoginKey: "k"}}},
+		{name: "missing key", accounts: []Account{{Zones: []string{"example.com"}, User: "a"}}},
+		{name: "missing user", accounts: []Account{{Zones: []string{"example.com"}, LoginKey: "k"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{ServerURL: "https://da.example.com", User: tt.user, Accounts: tt.accounts}
+			if err := p.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// Package acmedns serves the acme-dns HTTP API, writing the TXT records of
+// ACME challenges into a DirectAdmin zone through the directadmin provider.
+// ACME clients that only support acme-dns can then solve DNS-01 challenges
+// against DirectAdmin.
+//
+// As with acme-dns, each domain delegates its challenge with a CNAME from
+// _acme-challenge.<domain> to <subdomain>.<zone>, where subdomain is the
+// one returned by /register. The two most recent values are kept per
+// subdomain so certificates for a domain and its wildcard can be issued
+// together.
+package acmedns
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/libdns"
+)
+
+// Account is a registered acme-dns account. The password is only stored as
+// a hash.
+type Account struct {
+	Username     string   `json:"username"`
+	PasswordHash string   `json:"password_hash"`
+	Subdomain    string   `json:"subdomain"`
+	AllowFrom    []string `json:"allowfrom,omitempty"`
+}
+
+// Server is an http.Handler serving /register, /update and /health.
+type Server struct {
+	Provider *directadmin.Provider
+
+	// Zone receives the challenge records, e.g. acme.example.com
+	Zone string
+
+	// TTL of the challenge records, the zone's default if 0
+	TTL time.Duration
+
+	// Register allows new accounts to be registered through the API
+	Register bool
+
+	// OnRegister is called with every new account before it's used, e.g.
+	// to persist it. Registration fails if it returns an error.
+	OnRegister func(Account) error
+
+	mu       sync.Mutex
+	accounts map[string]Account
+}
+
+// AddAccount adds an existing account, such as one saved by OnRegister.
+func (s *Server) AddAccount(a Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accounts == nil {
+		s.accounts = make(map[string]Account)
+	}
+	s.accounts[a.Username] = a
+}
+
+// ServeHTTP dispatches to the acme-dns API's endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/register" && r.Method == http.MethodPost && s.Register:
+		s.register(w, r)
+	case r.URL.Path == "/update" && r.Method == http.MethodPost:
+		s.update(w, r)
+	case r.URL.Path == "/health" && r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type registerRequest struct {
+	AllowFrom []string `json:"allowfrom"`
+}
+
+type registerResponse struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	FullDomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed_json_payload")
+			return
+		}
+	}
+	for _, cidr := range req.AllowFrom {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_allowfrom_cidr")
+			return
+		}
+	}
+
+	username, err := randomID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	subdomain, err := randomID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	password, err := randomPassword()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+
+	account := Account{
+		Username:     username,
+		PasswordHash: hashPassword(password),
+		Subdomain:    subdomain,
+		AllowFrom:    req.AllowFrom,
+	}
+	if s.OnRegister != nil {
+		if err := s.OnRegister(account); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error")
+			return
+		}
+	}
+	s.AddAccount(account)
+
+	allowFrom := req.AllowFrom
+	if allowFrom == nil {
+		allowFrom = []string{}
+	}
+
+	writeJSON(w, http.StatusCreated, registerResponse{
+		Username:   username,
+		Password:   password,
+		FullDomain: subdomain + "." + strings.TrimSuffix(s.Zone, "."),
+		Subdomain:  subdomain,
+		AllowFrom:  allowFrom,
+	})
+}
+
+type updateRequest struct {
+	Subdomain string `json:"subdomain"`
+	TXT       string `json:"txt"`
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "forbidden")
+		return
+	}
+
+	var req updateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed_json_payload")
+		return
+	}
+	if req.Subdomain != account.Subdomain {
+		writeError(w, http.StatusUnauthorized, "forbidden")
+		return
+	}
+	// Challenge values are base64url encoded SHA-256 digests
+	if len(req.TXT) != 43 {
+		writeError(w, http.StatusBadRequest, "bad_txt")
+		return
+	}
+
+	if err := s.setTXT(r.Context(), req.Subdomain, req.TXT); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"txt": req.TXT})
+}
+
+func (s *Server) authenticate(r *http.Request) (Account, bool) {
+	s.mu.Lock()
+	account, ok := s.accounts[r.Header.Get("X-Api-User")]
+	s.mu.Unlock()
+	if !ok {
+		return Account{}, false
+	}
+
+	hash := hashPassword(r.Header.Get("X-Api-Key"))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(account.PasswordHash)) != 1 {
+		return Account{}, false
+	}
+
+	return account, allowed(r.RemoteAddr, account.AllowFrom)
+}
+
+// setTXT adds the value for the subdomain, keeping only the previous one.
+func (s *Server) setTXT(ctx context.Context, subdomain, value string) error {
+	// Serialize updates so concurrent challenges don't delete each other's
+	// values
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.Provider.GetRecords(ctx, s.Zone)
+	if err != nil {
+		return err
+	}
+
+	var existing []libdns.Record
+	for _, rec := range records {
+		if rec.Type == "TXT" && rec.Name == subdomain {
+			existing = append(existing, rec)
+		}
+	}
+
+	if len(existing) > 1 {
+		if _, err := s.Provider.DeleteRecords(ctx, s.Zone, existing[:len(existing)-1]); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.Provider.AppendRecords(ctx, s.Zone, []libdns.Record{{
+		Type:  "TXT",
+		Name:  subdomain,
+		Value: value,
+		TTL:   s.TTL,
+	}})
+
+	return err
+}
+
+// allowed reports whether the request's address is in one of the
+// networks, or whether there are no restrictions.
+func allowed(remoteAddr string, allowFrom []string) bool {
+	if len(allowFrom) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range allowFrom {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomID returns a random UUID, which is how acme-dns names users and
+// subdomains.
+func randomID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func randomPassword() (string, error) {
+	var b [30]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// Validate checks the server's configuration.
+func (s *Server) Validate() error {
+	if s.Provider == nil {
+		return errors.New("acme-dns provider is required")
+	}
+	if len(strings.TrimSuffix(s.Zone, ".")) == 0 {
+		return errors.New("acme-dns zone is required")
+	}
+
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
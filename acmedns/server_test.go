@@ -0,0 +1,136 @@
+package acmedns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestServer(t *testing.T) {
+	server := directadmintest.NewTLSServer("admin", "key")
+	defer server.Close()
+	server.AddZone("acme.example.com")
+
+	provider, err := directadmin.New(server.URL, "admin", "key", directadmin.WithInsecureRequests())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var registered []Account
+	s := &Server{
+		Provider: provider,
+		Zone:     "acme.example.com",
+		Register: true,
+		OnRegister: func(a Account) error {
+			registered = append(registered, a)
+			return nil
+		},
+	}
+
+	do := func(path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/register", "", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %v", http.StatusCreated, w.Code, w.Body)
+	}
+	var account registerResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 1 || registered[0].Username != account.Username || registered[0].PasswordHash == account.Password {
+		t.Fatalf("expected the account to be passed to OnRegister with a hashed password, got %+v", registered)
+	}
+	if account.FullDomain != account.Subdomain+".acme.example.com" {
+		t.Errorf("expected full domain in the zone, got %v", account.FullDomain)
+	}
+
+	auth := http.Header{"X-Api-User": {account.Username}, "X-Api-Key": {account.Password}}
+	values := []string{
+		strings.Repeat("a", 43),
+		strings.Repeat("b", 43),
+		strings.Repeat("c", 43),
+	}
+	for _, value := range values {
+		w = do("/update", `{"subdomain": "`+account.Subdomain+`", "txt": "`+value+`"}`, auth)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %v", http.StatusOK, w.Code, w.Body)
+		}
+	}
+
+	want := []directadmintest.Record{
+		{Type: "TXT", Name: account.Subdomain, Value: values[1], TTL: directadmintest.DefaultTTL},
+		{Type: "TXT", Name: account.Subdomain, Value: values[2], TTL: directadmintest.DefaultTTL},
+	}
+	if got := server.Records("acme.example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the two latest values %v, got %v", want, got)
+	}
+
+	var tests = []struct {
+		name   string
+		body   string
+		header http.Header
+		status int
+	}{
+		{
+			name:   "wrong key",
+			body:   `{"subdomain": "` + account.Subdomain + `", "txt": "` + values[0] + `"}`,
+			header: http.Header{"X-Api-User": {account.Username}, "X-Api-Key": {"wrong"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "other subdomain",
+			body:   `{"subdomain": "other", "txt": "` + values[0] + `"}`,
+			header: auth,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "bad txt",
+			body:   `{"subdomain": "` + account.Subdomain + `", "txt": "short"}`,
+			header: auth,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("/update", tt.body, tt.header); w.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %v", tt.status, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	var tests = []struct {
+		remoteAddr string
+		allowFrom  []string
+		want       bool
+	}{
+		{remoteAddr: "192.0.2.1:1234", want: true},
+		{remoteAddr: "192.0.2.1:1234", allowFrom: []string{"192.0.2.0/24"}, want: true},
+		{remoteAddr: "198.51.100.1:1234", allowFrom: []string{"192.0.2.0/24"}, want: false},
+		{remoteAddr: "[2001:db8::1]:1234", allowFrom: []string{"192.0.2.0/24", "2001:db8::/32"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			if got := allowed(tt.remoteAddr, tt.allowFrom); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package directadmin
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ChangeEvent describes a record change DirectAdmin accepted, see
+// Provider.OnChange.
+type ChangeEvent struct {
+	Zone string
+	Kind ChangeKind
+
+	// Before is the record as it was before a RecordModified or
+	// RecordDeleted change. For changes that identified the record by its
+	// ID only the name, type and value are known.
+	Before libdns.Record
+
+	// After is the record as it is after a RecordAdded or RecordModified
+	// change
+	After libdns.Record
+
+	// User is the DirectAdmin user the change was made as, `reseller|user`
+	// in reseller mode
+	User string
+
+	// Time is when DirectAdmin accepted the change
+	Time time.Time
+}
+
+// notifyChange calls OnChange, if set, for a change that was applied.
+func (p *Provider) notifyChange(ctx context.Context, zone string, kind ChangeKind, before, after libdns.Record) {
+	if p.OnChange == nil || p.DryRun {
+		return
+	}
+
+	// Leases and ownership markers are the provider's own bookkeeping, not
+	// changes the caller made
+	if isInternalRecord(before) || isInternalRecord(after) {
+		return
+	}
+
+	event := ChangeEvent{
+		Zone:   zone,
+		Kind:   kind,
+		Before: before,
+		After:  after,
+		Time:   time.Now(),
+	}
+
+	// The credentials were resolved for the request already, so this is
+	// served from the configuration or the provider's caches
+	if creds, err := p.credentials(ctx, zone); err == nil {
+		event.User = creds.User
+	} else {
+		p.logger(ctx).Warnw("failed to determine the user for the change event", "zone", zone, "error", err)
+	}
+
+	p.OnChange(event)
+}
+
+// isInternalRecord reports whether the record is a lease or an ownership
+// marker.
+func isInternalRecord(rec libdns.Record) bool {
+	return isLeaseRecord(rec) || isOwnerMarker(rec)
+}
+
+// recordFromID reconstructs a record from the identifier DirectAdmin
+// assigns it, for changes that only name the record by its ID.
+func recordFromID(id, recordType, zone string) libdns.Record {
+	record := libdns.Record{ID: id, Type: recordType}
+
+	params, err := url.ParseQuery(id)
+	if err != nil {
+		return record
+	}
+
+	parsed, err := daRecord{
+		Type:     recordType,
+		Name:     params.Get("name"),
+		Value:    params.Get("value"),
+		Combined: id,
+	}.libdnsRecord(zone)
+	if err != nil {
+		return record
+	}
+
+	return parsed
+}
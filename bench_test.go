@@ -0,0 +1,72 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func BenchmarkGetRecords(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d records", size), func(b *testing.B) {
+			provider, server := newFakeProvider(b)
+
+			records := make([]directadmintest.Record, 0, size)
+			for i := 0; i < size; i++ {
+				records = append(records, directadmintest.Record{Type: "A", Name: fmt.Sprintf("host%d", i), Value: "192.0.2.1", TTL: 300})
+			}
+			server.AddZone(fakeZone, records...)
+
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppendRecords(b *testing.B) {
+	benchmarkBatch(b, func(ctx context.Context, provider *Provider, records []libdns.Record) error {
+		_, err := provider.AppendRecords(ctx, fakeZone, records)
+		return err
+	})
+}
+
+func BenchmarkSetRecords(b *testing.B) {
+	benchmarkBatch(b, func(ctx context.Context, provider *Provider, records []libdns.Record) error {
+		_, err := provider.SetRecords(ctx, fakeZone, records)
+		return err
+	})
+}
+
+func benchmarkBatch(b *testing.B, apply func(ctx context.Context, provider *Provider, records []libdns.Record) error) {
+	for _, size := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("%d records", size), func(b *testing.B) {
+			provider, server := newFakeProvider(b)
+
+			records := make([]libdns.Record, 0, size)
+			for i := 0; i < size; i++ {
+				records = append(records, libdns.Record{Type: "TXT", Name: fmt.Sprintf("bench%d", i), Value: "value", TTL: 300 * time.Second})
+			}
+
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				server.AddZone(fakeZone)
+				b.StartTimer()
+
+				if err := apply(ctx, provider, records); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
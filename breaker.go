@@ -0,0 +1,124 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned without contacting DirectAdmin while the
+// circuit breaker is open after repeated failures.
+var ErrCircuitOpen = errors.New("circuit breaker open: DirectAdmin failed repeatedly, not sending requests during cool-down")
+
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a request may be sent. Once the cool-down has
+// passed a single probe request is let through; its outcome decides whether
+// the breaker closes again.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(cb.openUntil) || cb.probing {
+		return false
+	}
+
+	cb.probing = true
+	return true
+}
+
+// record updates the breaker with a request's outcome and returns the
+// number of consecutive failures.
+func (cb *circuitBreaker) record(failed bool, threshold int, cooldown time.Duration, now time.Time) int {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.probing = false
+
+	if !failed {
+		cb.failures = 0
+		cb.openUntil = time.Time{}
+		return 0
+	}
+
+	cb.failures++
+	if cb.failures >= threshold {
+		cb.openUntil = now.Add(cooldown)
+	}
+
+	return cb.failures
+}
+
+// release lets the next probe through after a request whose outcome says
+// nothing about DirectAdmin's health, leaving the failures as they were.
+func (cb *circuitBreaker) release() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.probing = false
+}
+
+// transportError is an error sending a request to DirectAdmin or reading
+// its response, as opposed to one failing the request before it was sent.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// breakerMiddleware short-circuits requests while DirectAdmin is failing.
+// Transport errors, including timeouts, 5xx and 429 responses count as
+// failures. Errors before the request is sent, e.g. loading credentials,
+// say nothing about DirectAdmin and don't count.
+func (p *Provider) breakerMiddleware(next Handler) Handler {
+	return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+		if p.CircuitBreakerThreshold <= 0 {
+			return next(ctx, req)
+		}
+
+		cooldown := p.CircuitBreakerCooldown
+		if cooldown <= 0 {
+			cooldown = defaultCircuitBreakerCooldown
+		}
+
+		if !p.breaker.allow(time.Now()) {
+			return nil, ErrCircuitOpen
+		}
+
+		resp, err := next(ctx, req)
+
+		// The caller giving up says nothing about the backend's health, but
+		// a deadline expiring while DirectAdmin hangs does
+		var transportErr *transportError
+		if err != nil && (errors.Is(ctx.Err(), context.Canceled) || !errors.As(err, &transportErr)) {
+			p.breaker.release()
+			return resp, err
+		}
+
+		failed := err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		failures := p.breaker.record(failed, p.CircuitBreakerThreshold, cooldown, time.Now())
+		if failures >= p.CircuitBreakerThreshold {
+			p.logger(ctx).Warnw("circuit breaker open", "command", req.Command, "consecutive_failures", failures, "cooldown", cooldown)
+		}
+
+		return resp, err
+	}
+}
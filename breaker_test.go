@@ -0,0 +1,120 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var cb circuitBreaker
+	now := time.Now()
+	cooldown := 10 * time.Second
+
+	for i := 0; i < 3; i++ {
+		if !cb.allow(now) {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+		cb.record(true, 3, cooldown, now)
+	}
+
+	if cb.allow(now.Add(time.Second)) {
+		t.Error("expected breaker to be open after 3 failures")
+	}
+
+	probeTime := now.Add(cooldown + time.Second)
+	if !cb.allow(probeTime) {
+		t.Fatal("expected a probe request after the cool-down")
+	}
+	if cb.allow(probeTime) {
+		t.Error("expected only a single probe request")
+	}
+
+	cb.record(false, 3, cooldown, probeTime)
+	if !cb.allow(probeTime) {
+		t.Error("expected breaker to close after a successful probe")
+	}
+}
+
+func TestProvider_CircuitBreakerHangingPanel(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(), WithLogger(NopLogger()),
+		WithOperationTimeout(50*time.Millisecond), WithCircuitBreaker(2, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the request to time out, got %v", err)
+		}
+	}
+
+	if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected timeouts to open the breaker, got %v", err)
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 2 {
+		t.Errorf("expected 2 requests to reach the panel, got %d", requests)
+	}
+
+	// A probe the caller cancels neither closes the breaker nor blocks the
+	// next probe
+	provider.breaker.openUntil = time.Now().Add(-time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+	if _, err := provider.GetRecords(ctx, "example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the probe to be canceled, got %v", err)
+	}
+	if provider.breaker.failures != 2 || provider.breaker.openUntil.IsZero() || provider.breaker.probing {
+		t.Errorf("expected the canceled probe to leave the breaker open, got %+v", &provider.breaker)
+	}
+}
+
+func TestProvider_CircuitBreakerLocalErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	errVault := errors.New("vault sealed")
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(), WithLogger(NopLogger()),
+		WithCircuitBreaker(1, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Credentials = CredentialsFunc(func(ctx context.Context, zone string) (Credentials, error) {
+		return Credentials{}, errVault
+	})
+
+	// Failing to load credentials says nothing about DirectAdmin
+	for i := 0; i < 2; i++ {
+		if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, errVault) {
+			t.Fatalf("expected the credentials error, got %v", err)
+		}
+	}
+
+	// Throttling does, once the retries are used up
+	provider.Credentials = nil
+	provider.MaxRetries = -1
+	if _, err := provider.GetRecords(context.Background(), "example.com"); err == nil {
+		t.Fatal("expected the throttled request to fail")
+	}
+	if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected throttling to open the breaker, got %v", err)
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 1 {
+		t.Errorf("expected 1 request to reach the panel, got %d", requests)
+	}
+}
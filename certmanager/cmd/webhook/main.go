@@ -0,0 +1,19 @@
+// Command webhook runs the cert-manager webhook solver for DirectAdmin. The
+// API group is read from $GROUP_NAME and must match the issuers' groupName.
+package main
+
+import (
+	"os"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"github.com/libdns/directadmin/certmanager"
+)
+
+func main() {
+	groupName := os.Getenv("GROUP_NAME")
+	if len(groupName) == 0 {
+		panic("GROUP_NAME must be set")
+	}
+
+	cmd.RunWebhookServer(groupName, &certmanager.Solver{})
+}
@@ -0,0 +1,213 @@
+// Package certmanager implements a cert-manager DNS-01 webhook solver that
+// presents ACME challenges as TXT records on a DirectAdmin panel.
+//
+// It lives in its own module so the Kubernetes dependencies don't end up in
+// programs only using the provider. The webhook binary is in cmd/webhook.
+//
+// An issuer configures the solver like this:
+//
+//	dns01:
+//	  webhook:
+//	    groupName: acme.example.com
+//	    solverName: directadmin
+//	    config:
+//	      serverURL: https://da.example.com:2222
+//	      user: admin
+//	      loginKeySecretRef:
+//	        name: directadmin-credentials
+//	        key: login-key
+//
+// The secret is read from the namespace of the challenge, which is the
+// namespace of the Certificate for an Issuer and cert-manager's cluster
+// resource namespace for a ClusterIssuer.
+package certmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/libdns/directadmin"
+	"github.com/libdns/libdns"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// SolverName is the solver name issuers refer to.
+const SolverName = "directadmin"
+
+// Config is the solver's configuration in the issuer.
+type Config struct {
+	ServerURL         string                   `json:"serverURL"`
+	User              string                   `json:"user"`
+	LoginKeySecretRef cmmeta.SecretKeySelector `json:"loginKeySecretRef"`
+
+	// InsecureRequests disables verification of the panel's certificate
+	InsecureRequests bool `json:"insecureRequests,omitempty"`
+
+	// TTL of the challenge records in seconds, the zone's default if 0
+	TTL int `json:"ttl,omitempty"`
+}
+
+// Solver presents challenges through the directadmin provider.
+type Solver struct {
+	// Client reads the login key secrets. Initialize sets it from the
+	// webhook's kube config if it's nil.
+	Client kubernetes.Interface
+
+	// Options are passed to directadmin.New for every challenge
+	Options []directadmin.Option
+
+	// Timeout bounds the API calls for one challenge, 1 minute if 0
+	Timeout time.Duration
+}
+
+// Name returns SolverName.
+func (s *Solver) Name() string {
+	return SolverName
+}
+
+// Initialize creates the Kubernetes client.
+func (s *Solver) Initialize(kubeClientConfig *rest.Config, _ <-chan struct{}) error {
+	if s.Client != nil {
+		return nil
+	}
+
+	client, err := kubernetes.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	s.Client = client
+
+	return nil
+}
+
+// Present adds the challenge's TXT record.
+func (s *Solver) Present(ch *v1alpha1.ChallengeRequest) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	provider, zone, rec, err := s.prepare(ctx, ch)
+	if err != nil {
+		return err
+	}
+
+	// The record may already exist when cert-manager retries a challenge
+	records, err := provider.GetRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+	for _, existing := range records {
+		if existing.Type == rec.Type && existing.Name == rec.Name && existing.Value == rec.Value {
+			return nil
+		}
+	}
+
+	_, err = provider.AppendRecords(ctx, zone, []libdns.Record{rec})
+	return err
+}
+
+// CleanUp deletes the challenge's TXT record, leaving records of other
+// challenges for the same name in place.
+func (s *Solver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	provider, zone, rec, err := s.prepare(ctx, ch)
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.DeleteRecords(ctx, zone, []libdns.Record{rec})
+	return err
+}
+
+func (s *Solver) context() (context.Context, context.CancelFunc) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// prepare creates a provider for the challenge's configuration and returns
+// it with the zone and the challenge record.
+func (s *Solver) prepare(ctx context.Context, ch *v1alpha1.ChallengeRequest) (*directadmin.Provider, string, libdns.Record, error) {
+	cfg, err := loadConfig(ch)
+	if err != nil {
+		return nil, "", libdns.Record{}, err
+	}
+
+	loginKey, err := s.loginKey(ctx, ch.ResourceNamespace, cfg.LoginKeySecretRef)
+	if err != nil {
+		return nil, "", libdns.Record{}, err
+	}
+
+	opts := append([]directadmin.Option(nil), s.Options...)
+	if cfg.InsecureRequests {
+		opts = append(opts, directadmin.WithInsecureRequests())
+	}
+
+	provider, err := directadmin.New(cfg.ServerURL, cfg.User, loginKey, opts...)
+	if err != nil {
+		return nil, "", libdns.Record{}, err
+	}
+
+	zone := strings.TrimSuffix(ch.ResolvedZone, ".")
+	rec := libdns.Record{
+		Type:  "TXT",
+		Name:  libdns.RelativeName(ch.ResolvedFQDN, ch.ResolvedZone),
+		Value: ch.Key,
+		TTL:   time.Duration(cfg.TTL) * time.Second,
+	}
+
+	return provider, zone, rec, nil
+}
+
+func loadConfig(ch *v1alpha1.ChallengeRequest) (Config, error) {
+	var cfg Config
+	if ch.Config == nil {
+		return cfg, errors.New("missing solver config")
+	}
+	if err := json.Unmarshal(ch.Config.Raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid solver config: %w", err)
+	}
+
+	switch {
+	case len(cfg.ServerURL) == 0:
+		return cfg, errors.New("solver config: serverURL is required")
+	case len(cfg.User) == 0:
+		return cfg, errors.New("solver config: user is required")
+	case len(cfg.LoginKeySecretRef.Name) == 0 || len(cfg.LoginKeySecretRef.Key) == 0:
+		return cfg, errors.New("solver config: loginKeySecretRef needs a name and a key")
+	case cfg.TTL < 0:
+		return cfg, errors.New("solver config: ttl can't be negative")
+	}
+
+	return cfg, nil
+}
+
+func (s *Solver) loginKey(ctx context.Context, namespace string, ref cmmeta.SecretKeySelector) (string, error) {
+	if s.Client == nil {
+		return "", errors.New("solver isn't initialized")
+	}
+
+	secret, err := s.Client.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %v/%v: %w", namespace, ref.Name, err)
+	}
+
+	key, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %v/%v has no key %q", namespace, ref.Name, ref.Key)
+	}
+
+	return strings.TrimSpace(string(key)), nil
+}
@@ -0,0 +1,86 @@
+package certmanager
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/directadmintest"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSolver(t *testing.T) {
+	server := directadmintest.NewTLSServer("admin", "key")
+	defer server.Close()
+	server.AddZone("example.com")
+
+	solver := &Solver{
+		Client: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "directadmin"},
+			Data:       map[string][]byte{"login-key": []byte("key\n")},
+		}),
+		Options: []directadmin.Option{directadmin.WithInsecureRequests()},
+	}
+
+	challenge := func(key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResourceNamespace: "default",
+			ResolvedFQDN:      "_acme-challenge.www.example.com.",
+			ResolvedZone:      "example.com.",
+			Key:               key,
+			Config: &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(
+				`{"serverURL": %q, "user": "admin", "ttl": 60, "loginKeySecretRef": {"name": "directadmin", "key": "login-key"}}`,
+				server.URL,
+			))},
+		}
+	}
+
+	for _, key := range []string{"token1", "token1", "token2"} {
+		if err := solver.Present(challenge(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []directadmintest.Record{
+		{Type: "TXT", Name: "_acme-challenge.www", Value: "token1", TTL: 60},
+		{Type: "TXT", Name: "_acme-challenge.www", Value: "token2", TTL: 60},
+	}
+	if got := server.Records("example.com"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if err := solver.CleanUp(challenge("token1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Records("example.com"); !reflect.DeepEqual(got, want[1:]) {
+		t.Fatalf("expected %v, got %v", want[1:], got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	var tests = []struct {
+		config  string
+		wantErr bool
+	}{
+		{config: `{"serverURL": "https://da.example.com", "user": "admin", "loginKeySecretRef": {"name": "da", "key": "key"}}`},
+		{config: `{"user": "admin", "loginKeySecretRef": {"name": "da", "key": "key"}}`, wantErr: true},
+		{config: `{"serverURL": "https://da.example.com", "loginKeySecretRef": {"name": "da", "key": "key"}}`, wantErr: true},
+		{config: `{"serverURL": "https://da.example.com", "user": "admin", "loginKeySecretRef": {"name": "da"}}`, wantErr: true},
+		{config: `{"serverURL": "https://da.example.com", "user": "admin", "ttl": -1, "loginKeySecretRef": {"name": "da", "key": "key"}}`, wantErr: true},
+		{config: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.config, func(t *testing.T) {
+			ch := &v1alpha1.ChallengeRequest{Config: &apiextensionsv1.JSON{Raw: []byte(tt.config)}}
+			if _, err := loadConfig(ch); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
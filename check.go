@@ -0,0 +1,94 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// probeDomain is queried when the user owns no domain, or they can't be
+// listed. DirectAdmin only reports the domain as unknown once it has
+// accepted the command, which is all CheckCredentials needs to know.
+const probeDomain = "libdns-permission-check.invalid"
+
+// PermissionError is returned by CheckCredentials when the login key lacks
+// permissions the provider needs. It matches ErrPermissionDenied with
+// errors.Is.
+type PermissionError struct {
+	// Missing lists the commands the login key may not execute
+	Missing []string
+
+	// Err is the error the first rejected command failed with
+	Err error
+}
+
+func (e *PermissionError) Error() string {
+	msg := fmt.Sprintf("login key lacks permission for %v", strings.Join(e.Missing, " and "))
+	if len(e.Missing) > 1 {
+		msg += ", or the user or login key is wrong"
+	}
+
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermissionDenied
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// CheckCredentials verifies that DirectAdmin accepts the credentials and
+// that the login key may execute the commands the provider relies on,
+// without changing anything. If a permission is missing, the returned
+// *PermissionError names it. Run it at startup to find out about a
+// misconfigured key before the first certificate renewal does.
+func (p *Provider) CheckCredentials(ctx context.Context) (err error) {
+	ctx, end := p.startOperation(ctx, "CheckCredentials", "")
+	defer end(&err)
+
+	permErr := &PermissionError{}
+	missing := func(command string, err error) {
+		permErr.Missing = append(permErr.Missing, command)
+		if permErr.Err == nil {
+			permErr.Err = err
+		}
+	}
+
+	probe := probeDomain
+	domains, err := p.listDomains(ctx)
+	switch {
+	case deniedErr(err):
+		missing("CMD_API_SHOW_DOMAINS", err)
+	case err != nil:
+		return err
+	case len(domains) > 0:
+		probe = domains[0]
+	}
+
+	_, err = p.getZone(ctx, probe)
+	switch {
+	case deniedErr(err):
+		missing("CMD_API_DNS_CONTROL", err)
+	case errors.Is(err, ErrDomainNotFound) && probe == probeDomain:
+		// the command was accepted, the domain just doesn't exist
+	case err != nil:
+		return err
+	}
+
+	if len(permErr.Missing) > 0 {
+		p.logger(ctx).Warnw("login key lacks permissions", "missing", strings.Join(permErr.Missing, ","))
+		return permErr
+	}
+
+	return nil
+}
+
+// deniedErr reports whether DirectAdmin refused to execute a command.
+// DirectAdmin answers a command the login key doesn't allow the same way as
+// a wrong key, so both count as denied.
+func deniedErr(err error) bool {
+	return errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrAuthFailed)
+}
@@ -2,273 +2,489 @@ package directadmin
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/libdns/libdns"
-	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/libdns/directadmin/daapi"
+	"github.com/libdns/libdns"
 )
 
 func (p *Provider) getZoneRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
 	callerSkipDepth := 2
 
-	reqURL, err := url.Parse(p.ServerURL)
+	respData, err := p.getZone(ctx, zone)
 	if err != nil {
-		fmt.Printf("[%s] failed to parse server url: %v\n", p.caller(callerSkipDepth), err)
 		return nil, err
 	}
 
-	reqURL.Path = "/CMD_API_DNS_CONTROL"
+	recs := make([]libdns.Record, 0, len(respData.Records))
+	for i := range respData.Records {
+		libDnsRecord, err := respData.Records[i].libdnsRecord(zone)
+		if err != nil {
+			switch err {
+			case ErrUnsupported:
+				p.logger(ctx).Warnw("unsupported record conversion", "caller", p.caller(callerSkipDepth), "type", libDnsRecord.Type, "name", libDnsRecord.Name)
+				continue
+			default:
+				return nil, err
+			}
+		}
+		recs = append(recs, libDnsRecord)
+	}
 
-	queryString := make(url.Values)
-	queryString.Set("json", "yes")
-	queryString.Set("full_mx_records", "yes")
-	queryString.Set("allow_dns_underscore", "yes")
-	queryString.Set("ttl", "yes")
-	queryString.Set("domain", zone)
+	return recs, nil
+}
+
+func (p *Provider) getZone(ctx context.Context, zone string) (*daZone, error) {
+	for attempt := 0; ; attempt++ {
+		respData, err := p.readZone(ctx, zone)
+		if err == nil || !zoneRewriting(err) || attempt >= zoneRewriteRetries {
+			return respData, err
+		}
+
+		delay := zoneRewriteDelay * time.Duration(attempt+1)
+		p.logger(ctx).Warnw("zone is being rewritten, retrying", "zone", zone, "attempt", attempt+1, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w while the zone was being rewritten: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
 
-	reqURL.RawQuery = queryString.Encode()
+func (p *Provider) readZone(ctx context.Context, zone string) (*daZone, error) {
+	callerSkipDepth := 4
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	resp, err := p.zoneResponse(ctx, zone)
 	if err != nil {
-		fmt.Printf("[%s] failed to build new request: %v\n", p.caller(callerSkipDepth), err)
 		return nil, err
 	}
 
-	req.SetBasicAuth(p.User, p.LoginKey)
+	var respData daZone
+	err = json.Unmarshal(resp.Body, &respData)
+	if err != nil {
+		p.logger(ctx).Errorw("failed to json decode response", "caller", p.caller(callerSkipDepth), "error", err)
+		return nil, &partialZoneError{err: err}
+	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: p.InsecureRequests,
-			},
-		}}
+	p.fullMX.Store(zone, daBool(respData.FullMxRecords))
+
+	return &respData, nil
+}
 
-	resp, err := client.Do(req)
+// zoneResponse requests the zone and returns the response if it holds the
+// zone rather than an error.
+func (p *Provider) zoneResponse(ctx context.Context, zone string) (*APIResponse, error) {
+	callerSkipDepth := 5
+
+	queryString := make(url.Values)
+	queryString.Set("json", "yes")
+	queryString.Set("allow_dns_underscore", "yes")
+	queryString.Set("ttl", "yes")
+	queryString.Set("domain", zone)
+
+	resp, err := p.doRequest(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	})
 	if err != nil {
-		fmt.Printf("[%s] failed to execute request: %v\n", p.caller(callerSkipDepth), err)
 		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("[%s] failed to close body: %v\n", p.caller(callerSkipDepth), err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[%s] api response error, status code: %v\n", p.caller(callerSkipDepth), resp.StatusCode)
+	if err := daapi.CheckResponse("CMD_API_DNS_CONTROL", resp); err != nil {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", err)
 		return nil, err
 	}
 
-	var respData daZone
-	err = json.NewDecoder(resp.Body).Decode(&respData)
-	if err != nil {
-		fmt.Printf("[%s] failed to json decode response: %v\n", p.caller(callerSkipDepth), err)
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "status_code", resp.StatusCode)
+		return nil, fmt.Errorf("api response error, status code: %v", resp.StatusCode)
 	}
 
-	recs := make([]libdns.Record, 0, len(respData.Records))
-	for i := range respData.Records {
-		libDnsRecord, err := respData.Records[i].libdnsRecord(zone)
-		if err != nil {
-			switch err {
-			case ErrUnsupported:
-				fmt.Printf("[%s] unsupported record conversion of type %v: %v\n", p.caller(callerSkipDepth), libDnsRecord.Type, libDnsRecord.Name)
-				continue
-			default:
-				return nil, err
-			}
-		}
-		recs = append(recs, libDnsRecord)
+	// DirectAdmin reports unknown domains and missing permissions in the
+	// body of a 200 response instead of the zone
+	if errData, err := daapi.ParseResult(resp.Body); err == nil && errData.Failed() {
+		apiErr := errData.Err("CMD_API_DNS_CONTROL").(*APIError)
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", apiErr.Message, "result", apiErr.Details)
+		return nil, apiErr
 	}
 
-	return recs, nil
+	return resp, nil
 }
 
 func (p *Provider) appendZoneRecord(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	reqURL, err := url.Parse(p.ServerURL)
+	ctx, unlock, err := p.lockZone(ctx, zone)
 	if err != nil {
-		fmt.Printf("[%s] failed to parse server url: %v\n", p.caller(2), err)
 		return libdns.Record{}, err
 	}
+	defer unlock()
 
-	reqURL.Path = "/CMD_API_DNS_CONTROL"
+	record.TTL = p.recordTTL(record.TTL)
 
 	queryString := make(url.Values)
 	queryString.Set("action", "add")
+	p.setAffectPointers(queryString)
 	queryString.Set("json", "yes")
-	queryString.Set("full_mx_records", "yes")
 	queryString.Set("allow_dns_underscore", "yes")
 	queryString.Set("domain", zone)
 	queryString.Set("type", record.Type)
-	queryString.Set("name", record.Name)
-	queryString.Set("value", record.Value)
+	queryString.Set("name", daName(record.Name, zone))
+
+	value, err := p.setRecordValue(ctx, queryString, zone, record)
+	if err != nil {
+		return libdns.Record{}, err
+	}
 
 	if record.Type != "NS" {
 		queryString.Set("ttl", strconv.Itoa(int(record.TTL.Seconds())))
 	}
 
-	reqURL.RawQuery = queryString.Encode()
-
-	err = p.executeRequest(ctx, http.MethodGet, reqURL.String())
+	err = p.executeChange(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	}, func(current []libdns.Record) bool {
+		return hasRecord(zone, current, record)
+	})
 	if err != nil {
 		return libdns.Record{}, err
 	}
 
-	record.ID = fmt.Sprintf("name=%v&value=%v", record.Name, record.Value)
+	record.ID = fmt.Sprintf("name=%v&value=%v", daName(record.Name, zone), value)
+	p.notifyChange(ctx, zone, RecordAdded, libdns.Record{}, record)
 
 	return record, nil
 }
 
 func (p *Provider) setZoneRecord(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	reqURL, err := url.Parse(p.ServerURL)
+	ctx, unlock, err := p.lockZone(ctx, zone)
 	if err != nil {
-		fmt.Printf("[%s] failed to parse server url: %v\n", p.caller(2), err)
 		return libdns.Record{}, err
 	}
+	defer unlock()
 
-	reqURL.Path = "/CMD_API_DNS_CONTROL"
+	record.TTL = p.recordTTL(record.TTL)
 
 	queryString := make(url.Values)
 	queryString.Set("action", "edit")
+	p.setAffectPointers(queryString)
 	queryString.Set("json", "yes")
 	queryString.Set("domain", zone)
 	queryString.Set("type", record.Type)
-	queryString.Set("name", record.Name)
-	queryString.Set("value", record.Value)
+	queryString.Set("name", daName(record.Name, zone))
+
+	value, err := p.setRecordValue(ctx, queryString, zone, record)
+	if err != nil {
+		return libdns.Record{}, err
+	}
 
 	if record.Type != "NS" {
 		queryString.Set("ttl", strconv.Itoa(int(record.TTL.Seconds())))
 	}
 
-	existingRecords, _ := p.getZoneRecords(ctx, zone)
-	var existingRecordIndex = -1
-	for i := range existingRecords {
-		if existingRecords[i].Name == record.Name && existingRecords[i].Type == record.Type {
-			existingRecordIndex = i
-			break
+	// A record with an ID identifies exactly which record to replace, which
+	// matters when several records share a name and type
+	editKey := fmt.Sprintf("%vrecs0", strings.ToLower(record.Type))
+	var before libdns.Record
+	if len(record.ID) > 0 {
+		queryString.Set(editKey, record.ID)
+		before = recordFromID(record.ID, record.Type, zone)
+	} else {
+		existingRecords, _ := p.getZoneRecords(ctx, zone)
+
+		// If we found a matching existing record, this changes the API call
+		// from create only to edit.
+		if existing, ok := existingRecordFor(zone, existingRecords, record); ok {
+			if verifyKey(zone, existing) == verifyKey(zone, record) && (existing.TTL == record.TTL || record.Type == "NS") {
+				p.logger(ctx).Debugw("record unchanged, skipping", "type", record.Type, "name", record.Name)
+				record.ID, record.TTL = existing.ID, existing.TTL
+				return record, nil
+			}
+
+			queryString.Set(editKey, existing.ID)
+			before = existing
+		}
+	}
+
+	err = p.executeChange(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	}, func(current []libdns.Record) bool {
+		key := verifyKey(zone, record)
+		if len(before.Type) > 0 && verifyKey(zone, before) != key && hasRecord(zone, current, before) {
+			return false
+		}
+		for _, cur := range current {
+			// A change of only the TTL leaves the value as it was
+			if verifyKey(zone, cur) == key && (cur.TTL == record.TTL || record.Type == "NS") {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	record.ID = fmt.Sprintf("name=%v&value=%v", daName(record.Name, zone), value)
+	if len(before.Type) > 0 {
+		p.notifyChange(ctx, zone, RecordModified, before, record)
+	} else {
+		// Without a record to replace DirectAdmin adds the record
+		p.notifyChange(ctx, zone, RecordAdded, libdns.Record{}, record)
+	}
+
+	return record, nil
+}
+
+// existingRecordFor returns the record of the zone a record without an ID
+// replaces: the one with the same value, so changing only the TTL edits it
+// in place, or else the first with the same name and type.
+func existingRecordFor(zone string, existing []libdns.Record, record libdns.Record) (libdns.Record, bool) {
+	key := verifyKey(zone, record)
+	for _, rec := range existing {
+		if verifyKey(zone, rec) == key {
+			return rec, true
 		}
 	}
 
-	// If we're not -1, we found a matching existing record. This changes the API call
-	// from create only to edit.
-	if existingRecordIndex != -1 {
-		editKey := fmt.Sprintf("%vrecs0", strings.ToLower(record.Type))
-		editValue := existingRecords[existingRecordIndex].ID
-		queryString.Set(editKey, editValue)
+	for _, rec := range existing {
+		if relativeName(rec.Name, zone) == relativeName(record.Name, zone) && rec.Type == record.Type {
+			return rec, true
+		}
 	}
 
-	reqURL.RawQuery = queryString.Encode()
+	return libdns.Record{}, false
+}
+
+// setRecordValue sets the value parameters of a record change and returns
+// the value as DirectAdmin stores it. MX records are sent as the priority
+// and a separate target, composed according to the zone's full MX records
+// setting.
+func (p *Provider) setRecordValue(ctx context.Context, params url.Values, zone string, record libdns.Record) (string, error) {
+	if record.Type != "MX" {
+		params.Set("value", record.Value)
+		return record.Value, nil
+	}
 
-	err = p.executeRequest(ctx, http.MethodGet, reqURL.String())
+	fullMX, err := p.fullMXRecords(ctx, zone)
 	if err != nil {
-		return libdns.Record{}, err
+		return "", err
 	}
 
-	record.ID = fmt.Sprintf("name=%v&value=%v", record.Name, record.Value)
+	target, err := mxTarget(record.Value, zone, fullMX)
+	if err != nil {
+		return "", err
+	}
 
-	return record, nil
+	params.Set("value", strconv.Itoa(int(record.Priority)))
+	params.Set("mx_value", target)
+
+	return fmt.Sprintf("%d %s", record.Priority, target), nil
 }
 
-func (p *Provider) deleteZoneRecord(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+// fullMXRecords reports the zone's full MX records setting, reading the
+// zone if it wasn't read before.
+func (p *Provider) fullMXRecords(ctx context.Context, zone string) (bool, error) {
+	if fullMX, ok := p.fullMX.Load(zone); ok {
+		return fullMX.(bool), nil
+	}
 
-	reqURL, err := url.Parse(p.ServerURL)
+	if _, err := p.getZone(ctx, zone); err != nil {
+		return false, err
+	}
+
+	fullMX, _ := p.fullMX.Load(zone)
+	return fullMX.(bool), nil
+}
+
+// setAffectPointers tells DirectAdmin whether a record change is copied to
+// the domain's pointers, if AffectPointers is configured.
+func (p *Provider) setAffectPointers(params url.Values) {
+	if p.AffectPointers == nil {
+		return
+	}
+
+	if *p.AffectPointers {
+		params.Set("affect_pointers", "yes")
+	} else {
+		params.Set("affect_pointers", "no")
+	}
+}
+
+func (p *Provider) deleteZoneRecord(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
+	ctx, unlock, err := p.lockZone(ctx, zone)
 	if err != nil {
-		fmt.Printf("[%s] failed to parse server url: %v\n", p.caller(2), err)
 		return libdns.Record{}, err
 	}
-
-	reqURL.Path = "/CMD_API_DNS_CONTROL"
+	defer unlock()
 
 	queryString := make(url.Values)
 	queryString.Set("action", "select")
+	p.setAffectPointers(queryString)
 	queryString.Set("json", "yes")
 	queryString.Set("domain", zone)
 
+	// Records read from the zone carry DirectAdmin's own identifier, which
+	// is more reliable than rebuilding it from the name and value
 	editKey := fmt.Sprintf("%vrecs0", strings.ToLower(record.Type))
-	editValue := fmt.Sprintf("name=%v&value=%v", record.Name, record.Value)
+	editValue := record.ID
+	if len(editValue) == 0 {
+		editValue = fmt.Sprintf("name=%v&value=%v", daName(record.Name, zone), record.Value)
+	}
 	queryString.Set(editKey, editValue)
 
-	reqURL.RawQuery = queryString.Encode()
-
-	err = p.executeRequest(ctx, http.MethodGet, reqURL.String())
+	err = p.executeChange(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	}, func(current []libdns.Record) bool {
+		return !hasRecord(zone, current, record)
+	})
 	if err != nil {
 		return libdns.Record{}, err
 	}
 
+	p.notifyChange(ctx, zone, RecordDeleted, record, libdns.Record{})
+
 	return record, nil
 }
 
-func (p *Provider) executeRequest(ctx context.Context, method, url string) error {
-	callerSkipDepth := 3
+// doRequest passes the request through the configured middleware before
+// sending it to DirectAdmin.
+func (p *Provider) doRequest(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+	p.applyExtraParams(ctx, req)
+
+	handler := p.roundTrip
+	handler = p.latencyMiddleware(handler)
+	handler = p.tracingMiddleware(handler)
+	handler = p.credentialsMiddleware(handler)
+	handler = p.retryMiddleware(handler)
+	handler = p.breakerMiddleware(handler)
+	for i := len(p.Middleware) - 1; i >= 0; i-- {
+		handler = p.Middleware[i](handler)
+	}
+
+	return handler(ctx, req)
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+// roundTrip sends the request to DirectAdmin and reads the full response.
+func (p *Provider) roundTrip(ctx context.Context, apiReq *APIRequest) (*APIResponse, error) {
+	baseURL, err := p.baseURL()
 	if err != nil {
-		fmt.Printf("[%s] failed to build new request: %v\n", p.caller(callerSkipDepth), err)
-		return err
+		p.logger(ctx).Errorw("failed to parse server url", "command", apiReq.Command, "error", err)
+		return nil, err
 	}
 
-	req.SetBasicAuth(p.User, p.LoginKey)
+	creds, err := p.credentials(ctx, apiReq.Zone)
+	if err != nil {
+		p.logger(ctx).Errorw("failed to load credentials", "command", apiReq.Command, "error", err)
+		return nil, err
+	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: p.InsecureRequests,
-			},
-		}}
+	client := &daapi.Client{
+		BaseURL:    baseURL,
+		User:       creds.User,
+		LoginKey:   creds.LoginKey,
+		HTTPClient: p.httpClient(),
+		UserAgent:  p.userAgent(),
+		Headers:    p.Headers,
+
+		MaxResponseBytes: p.maxResponseBytes(),
+	}
 
-	resp, err := client.Do(req)
+	resp, err := client.Do(ctx, apiReq)
 	if err != nil {
-		fmt.Printf("[%s] failed to execute request: %v\n", p.caller(callerSkipDepth), err)
-		return err
+		reqURL := daapi.CommandURL(baseURL, apiReq.Command)
+		reqURL.RawQuery = apiReq.Params.Encode()
+
+		err = p.redactError(err, creds.LoginKey)
+		p.logger(ctx).Errorw("failed to execute request", "command", apiReq.Command, "url", redactURL(reqURL), "error", err)
+		if errors.Is(err, daapi.ErrResponseTooLarge) {
+			return nil, err
+		}
+		return nil, &transportError{err: err}
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("[%s] failed to close body: %v\n", p.caller(callerSkipDepth), err)
+
+	p.noteServerVersion(resp.Header.Get("Server"))
+
+	return resp, nil
+}
+
+// executeRequest makes a request changing the zone, repeating it while
+// DirectAdmin rejects it because another process holds the zone's lock.
+func (p *Provider) executeRequest(ctx context.Context, req *APIRequest) error {
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := p.sendRequest(ctx, req)
+		if !errors.Is(err, ErrZoneLocked) {
+			return err
 		}
-	}(resp.Body)
+		if err := p.waitForZoneLock(ctx, req, err, start, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Provider) sendRequest(ctx context.Context, req *APIRequest) error {
+	callerSkipDepth := 4
 
-	var respData daResponse
-	err = json.NewDecoder(resp.Body).Decode(&respData)
+	if p.DryRun {
+		p.applyExtraParams(ctx, req)
+		p.logger(ctx).Infow("dry run, skipping api call", "caller", p.caller(callerSkipDepth), "command", req.Command, "params", req.Params.Encode())
+		return nil
+	}
+
+	resp, err := p.doRequest(ctx, req)
 	if err != nil {
-		fmt.Printf("[%s] failed to json decode response: %v\n", p.caller(callerSkipDepth), err)
 		return err
 	}
 
-	if len(respData.Error) > 0 {
-		trimmedResult := strings.Split(respData.Result, "\n")[0]
-		fmt.Printf("[%s] api response error: %v: %v\n", p.caller(callerSkipDepth), respData.Error, trimmedResult)
-		return fmt.Errorf("[%s] api response error: %v: %v\n", p.caller(callerSkipDepth), respData.Error, trimmedResult)
+	if err := daapi.CheckResponse(req.Command, resp); err != nil {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", err)
+		return err
+	}
+
+	respData, err := daapi.ParseResult(resp.Body)
+	if err != nil {
+		p.logger(ctx).Errorw("failed to decode response", "caller", p.caller(callerSkipDepth), "error", err)
+		return err
+	}
+
+	if respData.Failed() {
+		apiErr := respData.Err(req.Command).(*APIError)
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", apiErr.Message, "result", apiErr.Details)
+		return p.redactError(apiErr)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Printf("[%s] failed to read response body: %v\n", p.caller(callerSkipDepth), err)
-			return err
-		}
-		bodyString := string(bodyBytes)
-		log.Println(bodyString)
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "status_code", resp.StatusCode, "body", string(resp.Body))
+		return fmt.Errorf("api response error, status code: %v", resp.StatusCode)
+	}
 
+	if err := daapi.CheckDemo(req.Command, respData); err != nil {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", err)
 		return err
 	}
 
+	collectResult(ctx, req, respData)
+
 	return nil
 }
 
@@ -0,0 +1,261 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProvider_IPv6ServerURL(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("ipv6 loopback unavailable: %v", err)
+	}
+
+	var host string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"records":[{"type":"A","name":"www","value":"192.0.2.1","combined":"name=www&value=192.0.2.1","ttl":"300"}]}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	provider := &Provider{
+		ServerURL:         server.URL,
+		User:              "admin",
+		LoginKey:          "key",
+		AllowInsecureHTTP: true,
+	}
+
+	records, err := provider.GetRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Errorf("expected 1 record, got %d", len(records))
+	}
+
+	if want := listener.Addr().String(); host != want {
+		t.Errorf("expected host header %v, got %v", want, host)
+	}
+}
+
+func TestProvider_Headers(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_, _ = w.Write([]byte(`{"records":[]}`))
+	}))
+	defer server.Close()
+
+	provider := &Provider{
+		ServerURL:         server.URL,
+		User:              "admin",
+		LoginKey:          "key",
+		AllowInsecureHTTP: true,
+		UserAgent:         "caddy/2.8",
+		Headers: map[string]string{
+			"X-Correlation-Id": "abc",
+			"Authorization":    "Bearer ignored",
+		},
+	}
+
+	if _, err := provider.GetRecords(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := header.Get("X-Correlation-Id"); got != "abc" {
+		t.Errorf("expected custom header, got %q", got)
+	}
+
+	if got, want := header.Get("User-Agent"), "libdns-directadmin/"+Version+" caddy/2.8"; got != want {
+		t.Errorf("expected user agent %q, got %q", want, got)
+	}
+
+	if user, key, ok := (&http.Request{Header: header}).BasicAuth(); !ok || user != "admin" || key != "key" {
+		t.Errorf("expected basic auth to be kept, got %q", header.Get("Authorization"))
+	}
+}
+
+func TestProvider_AffectPointers(t *testing.T) {
+	var tests = []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{name: "panel default", want: nil},
+		{name: "affect", opts: []Option{WithAffectPointers(true)}, want: []string{"yes"}},
+		{name: "don't affect", opts: []Option{WithAffectPointers(false)}, want: []string{"no"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				params = r.URL.Query()
+				_, _ = w.Write([]byte(`{"success":"Record added"}`))
+			}))
+			defer server.Close()
+
+			provider, err := New(server.URL, "admin", "key", append(tt.opts, WithAllowInsecureHTTP())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := provider.appendZoneRecord(context.Background(), "example.com", libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := params["affect_pointers"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected affect_pointers %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProvider_ExtraParams(t *testing.T) {
+	var params url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/CMD_API_DNS_CONTROL" {
+			params = r.URL.Query()
+		}
+		_, _ = w.Write([]byte(`{"success":"Record added"}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(),
+		WithExtraParams(url.Values{"allow_dns_underscore": {"no"}, "flag": {"provider"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}
+	if _, err := provider.appendZoneRecord(context.Background(), "example.com", record); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("allow_dns_underscore") != "no" || params.Get("flag") != "provider" || params.Get("action") != "add" {
+		t.Errorf("expected the extra params to be merged, got %v", params)
+	}
+
+	ctx := WithCallParams(context.Background(), url.Values{"flag": {"call"}})
+	if _, err := provider.appendZoneRecord(ctx, "example.com", record); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("flag") != "call" {
+		t.Errorf("expected the call params to take precedence, got %v", params)
+	}
+}
+
+func TestProvider_MaxResponseBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"records":[{"type":"A","name":"www","value":"192.0.2.1","combined":"name=www&value=192.0.2.1","ttl":"300"}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(), WithMaxResponseBytes(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.GetRecords(context.Background(), "example.com"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestProvider_NumericErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`error=1&text=Cannot%20Add%20Record&details=Record%20already%20exists`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.appendZoneRecord(context.Background(), "example.com", libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"})
+	if !errors.Is(err, ErrDuplicateRecord) {
+		t.Errorf("expected ErrDuplicateRecord, got %v", err)
+	}
+}
+
+func TestProvider_DemoMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":"Record Added","result":"This is a demo server, changes are not saved"}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	_, err = provider.AppendRecords(context.Background(), "example.com", []libdns.Record{rec})
+	if !errors.Is(err, ErrDemoMode) {
+		t.Errorf("expected ErrDemoMode, got %v", err)
+	}
+}
+
+func TestProvider_ConnectTimeouts(t *testing.T) {
+	// A panel that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+
+	for _, tt := range []struct {
+		name   string
+		scheme string
+		option Option
+	}{
+		{name: "tls handshake", scheme: "https", option: WithConnectTimeouts(0, 50*time.Millisecond, 0)},
+		{name: "response header", scheme: "http", option: WithConnectTimeouts(0, 0, 50*time.Millisecond)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(tt.scheme+"://"+listener.Addr().String(), "admin", "key",
+				WithAllowInsecureHTTP(), WithLogger(NopLogger()), tt.option)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			if _, err := provider.GetRecords(ctx, "example.com"); err == nil {
+				t.Fatal("expected an error")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("expected the timeout to fail the request early, took %v", elapsed)
+			}
+		})
+	}
+}
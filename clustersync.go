@@ -0,0 +1,87 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultSyncInterval is how often the nameservers are polled while waiting
+// for a change to reach them.
+const defaultSyncInterval = 2 * time.Second
+
+// ErrNotSynced is returned when a change was made but not every nameserver
+// served it within SyncTimeout. The records were changed on the panel.
+var ErrNotSynced = errors.New("change not served by all nameservers")
+
+// waitForSync polls the zone's authoritative nameservers, or the
+// PropagationResolvers, until all of them serve the records, or none of
+// them if present is false. Records of types that can't be looked up are
+// not waited for.
+func (p *Provider) waitForSync(ctx context.Context, zone string, records []libdns.Record, present bool) error {
+	if p.SyncTimeout <= 0 || p.DryRun || len(records) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.SyncTimeout)
+	defer cancel()
+
+	interval := p.SyncInterval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+
+	start := time.Now()
+	for {
+		lagging, err := p.laggingNameservers(ctx, zone, records, present)
+		if err == nil && len(lagging) == 0 {
+			p.logger(ctx).Debugw("change served by all nameservers", "zone", zone, "duration", time.Since(start))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("%w within %v: %v", ErrNotSynced, p.SyncTimeout, err)
+			}
+			return fmt.Errorf("%w within %v: %v", ErrNotSynced, p.SyncTimeout, strings.Join(lagging, ", "))
+		case <-time.After(interval):
+		}
+	}
+}
+
+// laggingNameservers returns the nameservers that don't yet serve the
+// change.
+func (p *Provider) laggingNameservers(ctx context.Context, zone string, records []libdns.Record, present bool) ([]string, error) {
+	authoritative, resolvers := p.propagationTargets()
+
+	lagging := map[string]bool{}
+	for _, rec := range records {
+		if _, ok := lookups[strings.ToUpper(rec.Type)]; !ok {
+			continue
+		}
+
+		results, err := checkPropagation(ctx, zone, rec, authoritative, p.SyncNameservers, resolvers)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result.Err != nil || result.Found != present {
+				lagging[result.Server] = true
+			}
+		}
+	}
+
+	servers := make([]string, 0, len(lagging))
+	for server := range lagging {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	return servers, nil
+}
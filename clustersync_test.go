@@ -0,0 +1,169 @@
+package directadmin
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// fakeNameserver answers TXT queries over UDP with the values txt returns
+// for the queried name.
+func fakeNameserver(t *testing.T, txt func(name string) []string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := answerTXT(buf[:n], txt); resp != nil {
+				_, _ = conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func answerTXT(query []byte, txt func(name string) []string) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// Read the question's name to find where the question ends
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		l := int(query[i])
+		if i+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[i+1:i+1+l]))
+		i += 1 + l
+	}
+	end := i + 5
+	if end > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[i+1:])
+
+	var values []string
+	if qtype == 16 {
+		values = txt(strings.ToLower(strings.Join(labels, ".")))
+	}
+
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[2:], 0x8400|uint16(query[2]&0x01)<<8)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(values)))
+	resp = append(resp, query[12:end]...)
+
+	for _, value := range values {
+		resp = append(resp, 0xc0, 0x0c, 0, 16, 0, 1, 0, 0, 0, 60)
+		resp = append(resp, byte((len(value)+1)>>8), byte(len(value)+1), byte(len(value)))
+		resp = append(resp, value...)
+	}
+
+	return resp
+}
+
+func TestProvider_SyncTimeout(t *testing.T) {
+	provider, server := newFakeProvider(t)
+
+	var mu sync.Mutex
+	synced := false
+	addr := fakeNameserver(t, func(name string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var values []string
+		for _, rec := range server.Records(fakeZone) {
+			if synced && rec.Type == "TXT" && rec.Name+"."+fakeZone == name {
+				values = append(values, rec.Value)
+			}
+		}
+		return values
+	})
+
+	provider.SyncNameservers = []string{addr}
+	provider.SyncTimeout = 5 * time.Second
+	provider.SyncInterval = 10 * time.Millisecond
+
+	// The secondary catches up while the provider is waiting
+	time.AfterFunc(100*time.Millisecond, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		synced = true
+	})
+
+	ctx := context.Background()
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	start := time.Now()
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected AppendRecords to wait for the nameserver, returned after %v", elapsed)
+	}
+
+	mu.Lock()
+	synced = false
+	mu.Unlock()
+
+	provider.SyncTimeout = 100 * time.Millisecond
+	added, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "lagging", Value: "token"}})
+	if !errors.Is(err, ErrNotSynced) || !strings.Contains(err.Error(), addr) {
+		t.Errorf("expected ErrNotSynced naming the nameserver, got %v", err)
+	}
+	if len(added) != 1 {
+		t.Errorf("expected the added record to be returned, got %v", added)
+	}
+
+	// Deleting waits until the nameserver stops serving the record
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProvider_PropagationResolvers(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	resolver := fakeDoHResolver(t, func(name string) []string {
+		var values []string
+		for _, rec := range server.Records(fakeZone) {
+			if rec.Type == "TXT" && rec.Name+"."+fakeZone == name {
+				values = append(values, rec.Value)
+			}
+		}
+		return values
+	})
+
+	// The zone's nameservers aren't polled unless listed
+	provider.PropagationResolvers = []string{resolver}
+	provider.SyncTimeout = 5 * time.Second
+	provider.SyncInterval = 10 * time.Millisecond
+
+	if err := provider.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+// Command acmedns-server serves the acme-dns API and writes the challenge
+// records into a DirectAdmin zone, for ACME clients that only speak
+// acme-dns.
+//
+// The panel and credentials are read from the DIRECTADMIN_* environment
+// variables (see directadmin.NewFromEnv). Accounts are kept in the JSON file
+// given by -accounts; with -register, new accounts can be registered through
+// the API and are added to it.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/acmedns"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("acmedns-server: ")
+
+	listen := flag.String("listen", ":8053", "address of the acme-dns API")
+	zone := flag.String("zone", "", "zone receiving the challenge records, e.g. acme.example.com")
+	accountsFile := flag.String("accounts", "accounts.json", "file storing the accounts")
+	register := flag.Bool("register", false, "allow registering accounts through the API")
+	ttl := flag.Duration("ttl", 0, "TTL of the challenge records (default: the zone's default)")
+	tlsCert := flag.String("tls-cert", "", "certificate file, to serve HTTPS")
+	tlsKey := flag.String("tls-key", "", "key file of -tls-cert")
+	debug := flag.Bool("debug", false, "log api calls")
+	flag.Parse()
+
+	provider, err := directadmin.NewFromEnv(directadmin.WithLogger(directadmin.NewStdLogger(log.New(os.Stderr, "acmedns-server: ", 0), *debug)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accounts, err := loadAccounts(*accountsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	server := &acmedns.Server{
+		Provider: provider,
+		Zone:     *zone,
+		TTL:      *ttl,
+		Register: *register,
+		OnRegister: func(a acmedns.Account) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			accounts = append(accounts, a)
+			return saveAccounts(*accountsFile, accounts)
+		},
+	}
+	if err := server.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	for _, a := range accounts {
+		server.AddAccount(a)
+	}
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if len(*tlsCert) > 0 {
+		log.Fatal(srv.ListenAndServeTLS(*tlsCert, *tlsKey))
+	}
+	log.Fatal(srv.ListenAndServe())
+}
+
+func loadAccounts(path string) ([]acmedns.Account, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []acmedns.Account
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// saveAccounts replaces the file atomically so a crash doesn't lose the
+// accounts already registered.
+func saveAccounts(path string, accounts []acmedns.Account) error {
+	data, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,387 @@
+// Command dadns manages DNS records on a DirectAdmin panel from the command
+// line, for scripting changes from cron or CI.
+//
+// Usage:
+//
+//	dadns [flags] zones
+//	dadns [flags] list <zone>
+//	dadns [flags] add <zone> <name> <type> <value>
+//	dadns [flags] set <zone> <name> <type> <value>
+//	dadns [flags] delete <zone> <name> <type> <value>
+//	dadns [flags] export [-format zone|json] <zone>
+//	dadns [flags] import [-replace] <zone> <file>
+//	dadns [flags] verify [-resolvers list] <zone> <name> <type> [value]
+//
+// The panel and credentials are read from the DIRECTADMIN_* environment
+// variables (see directadmin.NewFromEnv) and can be overridden with flags.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/libdns"
+)
+
+const usage = `usage: dadns [flags] <command> [arguments]
+
+commands:
+  zones                                  list the zones of the user
+  list   <zone>                          list the records of a zone
+  add    <zone> <name> <type> <value>    add a record
+  set    <zone> <name> <type> <value>    add a record or replace the one with the same name and type
+  delete <zone> <name> <type> <value>    delete a record
+  export [-format zone|json] <zone>      write all records of a zone to stdout
+  import [-replace] <zone> <file>        add the records of a zone file, or - for stdin;
+                                         with -replace also delete records not in the file
+  verify [-resolvers list] <zone> <name> <type> [value]
+                                         check which nameservers serve a record; the zone's
+                                         authoritative servers and public resolvers are asked
+
+flags:
+`
+
+type config struct {
+	serverURL    string
+	user         string
+	loginKeyFile string
+	insecure     bool
+	dryRun       bool
+	force        bool
+	debug        bool
+	timeout      time.Duration
+	ttl          time.Duration
+	priority     uint
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("dadns: ")
+
+	var cfg config
+	flags := flag.NewFlagSet("dadns", flag.ExitOnError)
+	flags.StringVar(&cfg.serverURL, "server", "", "DirectAdmin server url, overrides $"+directadmin.EnvServerURL)
+	flags.StringVar(&cfg.user, "user", "", "DirectAdmin user, overrides $"+directadmin.EnvUser)
+	flags.StringVar(&cfg.loginKeyFile, "login-key-file", "", "file containing the login key, overrides $"+directadmin.EnvLoginKeyFile)
+	flags.BoolVar(&cfg.insecure, "insecure", false, "don't verify the panel's certificate")
+	flags.BoolVar(&cfg.dryRun, "dry-run", false, "log changes instead of making them")
+	flags.BoolVar(&cfg.force, "force", false, "allow deleting the apex NS records and the SOA record")
+	flags.BoolVar(&cfg.debug, "debug", false, "log api calls")
+	flags.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "timeout for the whole command")
+	flags.DurationVar(&cfg.ttl, "ttl", 0, "TTL of added or set records, e.g. 5m (default: the zone's default)")
+	flags.UintVar(&cfg.priority, "priority", 0, "priority of added or set MX records")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	if err := run(ctx, cfg, flags.Args(), os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+var errUsage = errors.New("invalid usage")
+
+func run(ctx context.Context, cfg config, args []string, out io.Writer) error {
+	// verify only talks DNS, so it doesn't need panel credentials
+	if args[0] == "verify" {
+		return verifyRecord(ctx, cfg, args[1:], out)
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "zones":
+		if len(args) != 0 {
+			return errUsage
+		}
+		return listZones(ctx, provider, out)
+	case "list":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return listRecords(ctx, provider, args[0], out)
+	case "add", "set", "delete":
+		if len(args) != 4 {
+			return errUsage
+		}
+
+		zone := args[0]
+		records := []libdns.Record{{
+			Name:     args[1],
+			Type:     strings.ToUpper(args[2]),
+			Value:    args[3],
+			TTL:      cfg.ttl,
+			Priority: cfg.priority,
+		}}
+
+		var changed []libdns.Record
+		switch command {
+		case "add":
+			changed, err = provider.AppendRecords(ctx, zone, records)
+		case "set":
+			changed, err = provider.SetRecords(ctx, zone, records)
+		case "delete":
+			changed, err = provider.DeleteRecords(ctx, zone, records)
+		}
+		if err != nil {
+			return err
+		}
+
+		return printRecords(out, changed)
+	case "export":
+		return exportZone(ctx, provider, args, out)
+	case "import":
+		return importZone(ctx, provider, args, out)
+	default:
+		return errUsage
+	}
+}
+
+func newProvider(cfg config) (*directadmin.Provider, error) {
+	overrides := map[string]string{
+		directadmin.EnvServerURL:    cfg.serverURL,
+		directadmin.EnvUser:         cfg.user,
+		directadmin.EnvLoginKeyFile: cfg.loginKeyFile,
+	}
+	for key, value := range overrides {
+		if len(value) > 0 {
+			if err := os.Setenv(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The key file flag wins over a key in the environment
+	if len(cfg.loginKeyFile) > 0 {
+		if err := os.Unsetenv(directadmin.EnvLoginKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var opts []directadmin.Option
+	if cfg.insecure {
+		opts = append(opts, directadmin.WithInsecureRequests())
+	}
+	if cfg.dryRun {
+		opts = append(opts, directadmin.WithDryRun())
+	}
+	if cfg.force {
+		opts = append(opts, directadmin.WithForce())
+	}
+	opts = append(opts, directadmin.WithLogger(directadmin.NewStdLogger(log.New(os.Stderr, "dadns: ", 0), cfg.debug)))
+
+	return directadmin.NewFromEnv(opts...)
+}
+
+func listZones(ctx context.Context, provider *directadmin.Provider, out io.Writer) error {
+	zones, err := provider.ListZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		if _, err := fmt.Fprintln(out, zone.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func listRecords(ctx context.Context, provider *directadmin.Provider, zone string, out io.Writer) error {
+	records, err := provider.GetRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	return printRecords(out, records)
+}
+
+func printRecords(out io.Writer, records []libdns.Record) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, rec := range records {
+		value := rec.Value
+		if rec.Type == "MX" {
+			value = fmt.Sprintf("%d %s", rec.Priority, rec.Value)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", rec.Name, int(rec.TTL.Seconds()), rec.Type, value)
+	}
+
+	return w.Flush()
+}
+
+func exportZone(ctx context.Context, provider *directadmin.Provider, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "zone", "output format, zone or json")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+	zone := flags.Arg(0)
+
+	switch *format {
+	case "zone":
+		zoneFile, err := provider.ExportZone(ctx, zone)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, zoneFile)
+		return err
+	case "json":
+		snapshot, err := provider.SnapshotZone(ctx, zone)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot)
+	default:
+		return errUsage
+	}
+}
+
+func importZone(ctx context.Context, provider *directadmin.Provider, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	replace := flags.Bool("replace", false, "delete records that aren't in the zone file")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	zone, path := flags.Arg(0), flags.Arg(1)
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records, err := directadmin.ParseZoneFile(in, zone)
+	if err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+
+	plan, err := provider.SyncZone(ctx, zone, records, directadmin.SyncOptions{
+		Apply: true,
+		Merge: !*replace,
+	})
+	if plan != nil {
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, change := range []struct {
+			action  string
+			records []libdns.Record
+		}{
+			{"delete", plan.Delete},
+			{"update", plan.Update},
+			{"create", plan.Create},
+		} {
+			for _, rec := range change.records {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", change.action, rec.Name, int(rec.TTL.Seconds()), rec.Type, rec.Value)
+			}
+		}
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+
+	return err
+}
+
+var errNotPropagated = errors.New("record is not served by every nameserver")
+
+func verifyRecord(ctx context.Context, cfg config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	resolvers := flags.String("resolvers", strings.Join(directadmin.DefaultPublicResolvers, ","), "comma separated resolvers to ask, as host:port, ip or https:// DNS-over-HTTPS url, empty for none")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 3 || flags.NArg() > 4 {
+		return errUsage
+	}
+
+	rec := libdns.Record{
+		Name:     flags.Arg(1),
+		Type:     strings.ToUpper(flags.Arg(2)),
+		Value:    flags.Arg(3),
+		Priority: cfg.priority,
+	}
+
+	var servers []string
+	for _, resolver := range strings.Split(*resolvers, ",") {
+		if resolver = strings.TrimSpace(resolver); len(resolver) > 0 {
+			servers = append(servers, resolver)
+		}
+	}
+
+	results, err := directadmin.CheckPropagation(ctx, flags.Arg(0), rec, servers)
+	if err != nil {
+		return err
+	}
+
+	missing := false
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, result := range results {
+		// CheckPropagation falls back to the default resolvers for an
+		// empty list, so drop their results when none were asked for
+		if !result.Authoritative && len(servers) == 0 {
+			continue
+		}
+
+		kind := "resolver"
+		if result.Authoritative {
+			kind = "authoritative"
+		}
+
+		status := "ok"
+		switch {
+		case result.Err != nil:
+			status = "error: " + result.Err.Error()
+		case !result.Found:
+			status = "missing"
+		}
+		if status != "ok" {
+			missing = true
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Server, kind, status, strings.Join(result.Values, " "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if missing {
+		return errNotPropagated
+	}
+
+	return nil
+}
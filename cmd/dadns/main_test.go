@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestRun(t *testing.T) {
+	server := directadmintest.NewTLSServer("admin", "key")
+	defer server.Close()
+	server.AddZone("example.com", directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300})
+
+	t.Setenv(directadmin.EnvServerURL, server.URL)
+	t.Setenv(directadmin.EnvUser, "admin")
+	t.Setenv(directadmin.EnvLoginKey, "key")
+
+	cfg := config{insecure: true}
+
+	var tests = []struct {
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{args: []string{"zones"}, want: "example.com."},
+		{args: []string{"add", "example.com", "api", "A", "192.0.2.2"}, want: "192.0.2.2"},
+		{args: []string{"list", "example.com"}, want: "api  3600  A  192.0.2.2"},
+		{args: []string{"delete", "example.com", "api", "A", "192.0.2.2"}, want: "192.0.2.2"},
+		{args: []string{"export", "example.com"}, want: "www\t300\tIN\tA\t192.0.2.1"},
+		{args: []string{"export", "-format", "json", "example.com"}, want: `"value": "192.0.2.1"`},
+		{args: []string{"import", "example.com", "testdata/import.zone"}, want: "create  mail  300  A  192.0.2.3"},
+		{args: []string{"import", "-replace", "example.com", "testdata/import.zone"}, want: "delete  www  300  A  192.0.2.1"},
+		{args: []string{"list"}, wantErr: errUsage},
+		{args: []string{"rename"}, wantErr: errUsage},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := run(context.Background(), cfg, tt.args, &out)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%v: expected %v, got %v", tt.args, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%v: expected output to contain %q, got %q", tt.args, tt.want, out.String())
+		}
+	}
+
+	want := []directadmintest.Record{{Type: "A", Name: "mail", Value: "192.0.2.3", TTL: 300}}
+	if records := server.Records("example.com"); !reflect.DeepEqual(records, want) {
+		t.Errorf("expected the zone to match the imported file, got %v", records)
+	}
+}
@@ -0,0 +1,60 @@
+// Command externaldns-webhook runs the external-dns webhook provider for
+// DirectAdmin as a sidecar of external-dns.
+//
+// The panel and credentials are read from the DIRECTADMIN_* environment
+// variables (see directadmin.NewFromEnv). The webhook API is served on
+// -listen, the /healthz endpoint Kubernetes probes on -health-listen.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/externaldns"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("externaldns-webhook: ")
+
+	listen := flag.String("listen", "localhost:8888", "address of the webhook API")
+	healthListen := flag.String("health-listen", ":8080", "address of the health check")
+	zones := flag.String("zones", "", "comma separated zones to manage (default: all zones of the user)")
+	debug := flag.Bool("debug", false, "log api calls")
+	flag.Parse()
+
+	provider, err := directadmin.NewFromEnv(directadmin.WithLogger(directadmin.NewStdLogger(log.New(os.Stderr, "externaldns-webhook: ", 0), *debug)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhook := &externaldns.Webhook{Provider: provider}
+	for _, zone := range strings.Split(*zones, ",") {
+		if zone = strings.TrimSpace(zone); len(zone) > 0 {
+			webhook.Zones = append(webhook.Zones, zone)
+		}
+	}
+
+	health := http.NewServeMux()
+	health.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	go func() {
+		log.Fatal(newServer(*healthListen, health).ListenAndServe())
+	}()
+
+	log.Fatal(newServer(*listen, webhook).ListenAndServe())
+}
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
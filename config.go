@@ -0,0 +1,102 @@
+package directadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// UnmarshalJSON decodes a Provider configuration such as Caddy's. The server
+// can be given as `host` or `server_url`, and surrounding whitespace is
+// trimmed from the server, user and login key. Type errors name the
+// offending field.
+func (p *Provider) UnmarshalJSON(data []byte) error {
+	type plain Provider
+	aux := struct {
+		*plain
+		ServerURLAlias string `json:"server_url"`
+	}{plain: (*plain)(p)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && len(typeErr.Field) > 0 {
+			return fmt.Errorf("invalid directadmin configuration: %v must be a %v, not a %v", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("invalid directadmin configuration: %w", err)
+	}
+
+	alias := strings.TrimSpace(aux.ServerURLAlias)
+	switch {
+	case len(alias) == 0:
+	case len(p.ServerURL) == 0:
+		p.ServerURL = alias
+	case strings.TrimSpace(p.ServerURL) != alias:
+		return errors.New("invalid directadmin configuration: host and server_url are both set and differ")
+	}
+
+	p.trimConfig()
+
+	return nil
+}
+
+// Provision prepares a Provider configured as a struct literal or from JSON
+// for use, as Caddy does for its modules: placeholders for environment
+// variables in the server, users and login keys, `{env.NAME}` or `{$NAME}`,
+// are replaced, and the configuration is validated and normalized the way
+// New does. An unset variable is an error, rather than a login failure on
+// the first API call.
+func (p *Provider) Provision() error {
+	fields := []*string{&p.ServerURL, &p.User, &p.LoginKey, &p.LoginKeyFile}
+	for i := range p.Accounts {
+		fields = append(fields, &p.Accounts[i].User, &p.Accounts[i].LoginKey, &p.Accounts[i].LoginKeyFile)
+	}
+	for _, field := range fields {
+		value, err := expandEnv(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	p.trimConfig()
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	u, _ := p.baseURL()
+	p.ServerURL = u.String()
+
+	return nil
+}
+
+func (p *Provider) trimConfig() {
+	p.ServerURL = strings.TrimRight(strings.TrimSpace(p.ServerURL), "/")
+	p.User = strings.TrimSpace(p.User)
+	p.LoginKey = strings.TrimSpace(p.LoginKey)
+	p.LoginKeyFile = strings.TrimSpace(p.LoginKeyFile)
+	for i := range p.Accounts {
+		p.Accounts[i].User = strings.TrimSpace(p.Accounts[i].User)
+		p.Accounts[i].LoginKey = strings.TrimSpace(p.Accounts[i].LoginKey)
+		p.Accounts[i].LoginKeyFile = strings.TrimSpace(p.Accounts[i].LoginKeyFile)
+	}
+}
+
+var envPlaceholder = regexp.MustCompile(`\{(?:env\.|\$)([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces the environment variable placeholders in s.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envPlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := envPlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("invalid directadmin configuration: environment variable %v is not set", name)
+		}
+		return value
+	})
+
+	return expanded, err
+}
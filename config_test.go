@@ -0,0 +1,125 @@
+package directadmin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	var tests = []struct {
+		name    string
+		config  string
+		want    [3]string
+		wantErr string
+	}{
+		{
+			name:   "host",
+			config: `{"host": " https://da.example.com:2222/ ", "user": "admin\n", "login_key": " key "}`,
+			want:   [3]string{"https://da.example.com:2222", "admin", "key"},
+		},
+		{
+			name:   "server_url",
+			config: `{"server_url": "https://da.example.com:2222", "user": "admin", "login_key": "key"}`,
+			want:   [3]string{"https://da.example.com:2222", "admin", "key"},
+		},
+		{
+			name:   "both keys agreeing",
+			config: `{"host": "https://da.example.com:2222", "server_url": "https://da.example.com:2222"}`,
+			want:   [3]string{"https://da.example.com:2222", "", ""},
+		},
+		{
+			name:    "both keys differing",
+			config:  `{"host": "https://da.example.com:2222", "server_url": "https://other.example.com:2222"}`,
+			wantErr: "host and server_url are both set and differ",
+		},
+		{
+			name:    "wrong type",
+			config:  `{"insecure_requests": "yes"}`,
+			wantErr: "insecure_requests must be a bool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provider
+			err := json.Unmarshal([]byte(tt.config), &p)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [3]string{p.ServerURL, p.User, p.LoginKey}; got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProvision(t *testing.T) {
+	t.Setenv("DA_TEST_HOST", "da.example.com")
+	t.Setenv("DA_TEST_KEY", "secret")
+
+	var tests = []struct {
+		name      string
+		serverURL string
+		user      string
+		loginKey  string
+		wantURL   string
+		wantKey   string
+		wantErr   string
+	}{
+		{
+			name:      "env placeholders",
+			serverURL: "{env.DA_TEST_HOST}",
+			user:      "admin",
+			loginKey:  "{env.DA_TEST_KEY}",
+			wantURL:   "https://da.example.com:2222",
+			wantKey:   "secret",
+		},
+		{
+			name:      "caddyfile placeholders",
+			serverURL: "https://{$DA_TEST_HOST}:2223",
+			user:      "admin",
+			loginKey:  "{$DA_TEST_KEY}",
+			wantURL:   "https://da.example.com:2223",
+			wantKey:   "secret",
+		},
+		{
+			name:      "unset variable",
+			serverURL: "da.example.com",
+			user:      "admin",
+			loginKey:  "{env.DA_TEST_UNSET}",
+			wantErr:   "environment variable DA_TEST_UNSET is not set",
+		},
+		{
+			name:      "invalid",
+			serverURL: "da.example.com",
+			loginKey:  "key",
+			wantErr:   "user is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{ServerURL: tt.serverURL, User: tt.user, LoginKey: tt.loginKey}
+			err := p.Provision()
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ServerURL != tt.wantURL || p.LoginKey != tt.wantKey {
+				t.Errorf("expected %q %q, got %q %q", tt.wantURL, tt.wantKey, p.ServerURL, p.LoginKey)
+			}
+		})
+	}
+}
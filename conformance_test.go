@@ -0,0 +1,197 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// libdns v0.2 doesn't ship a shared provider test harness, so these tests
+// check the contract documented on its interfaces: names relative to the
+// zone, zone names with or without a trailing dot, the semantics of each
+// method and safety for concurrent use. They run against the fake panel.
+
+func TestConformance(t *testing.T) {
+	var tests = []struct {
+		name string
+		run  func(t *testing.T, provider *Provider, zone string)
+	}{
+		{name: "relative names", run: conformanceRelativeNames},
+		{name: "trailing dot", run: conformanceTrailingDot},
+		{name: "append returns created", run: conformanceAppend},
+		{name: "set replaces", run: conformanceSet},
+		{name: "delete only matching", run: conformanceDelete},
+		{name: "concurrent appends", run: conformanceConcurrent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, _ := newFakeProvider(t)
+			tt.run(t, provider, fakeZone+".")
+		})
+	}
+}
+
+func conformanceRelativeNames(t *testing.T, provider *Provider, zone string) {
+	records := mustGetRecords(t, provider, zone)
+
+	for _, rec := range records {
+		if len(rec.Name) == 0 || rec.Name[len(rec.Name)-1] == '.' {
+			t.Errorf("expected a relative name, got %q", rec.Name)
+		}
+	}
+
+	if findRecord(records, "NS", "@", "ns1.example.net.") == nil {
+		t.Errorf("expected the apex NS record to be named @, got %v", records)
+	}
+}
+
+func conformanceTrailingDot(t *testing.T, provider *Provider, zone string) {
+	withDot := mustGetRecords(t, provider, zone)
+	withoutDot := mustGetRecords(t, provider, zone[:len(zone)-1])
+
+	if len(withDot) != len(withoutDot) {
+		t.Errorf("expected the same records, got %d and %d", len(withDot), len(withoutDot))
+	}
+}
+
+func conformanceAppend(t *testing.T, provider *Provider, zone string) {
+	input := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "one", TTL: 120 * time.Second},
+		{Type: "A", Name: "@", Value: "192.0.2.50", TTL: 120 * time.Second},
+	}
+
+	created, err := provider.AppendRecords(context.Background(), zone, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != len(input) {
+		t.Fatalf("expected %d created records, got %d", len(input), len(created))
+	}
+
+	records := mustGetRecords(t, provider, zone)
+	for _, rec := range input {
+		if findRecord(records, rec.Type, rec.Name, rec.Value) == nil {
+			t.Errorf("expected %v %v to be in the zone", rec.Type, rec.Name)
+		}
+	}
+
+	if findRecord(records, "A", "www", "192.0.2.1") == nil {
+		t.Error("expected existing records to be left alone")
+	}
+}
+
+func conformanceSet(t *testing.T, provider *Provider, zone string) {
+	set := []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.99", TTL: 300 * time.Second}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.SetRecords(context.Background(), zone, set); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := mustGetRecords(t, provider, zone)
+	if findRecord(records, "A", "www", "192.0.2.99") == nil {
+		t.Error("expected the record to be updated")
+	}
+	if findRecord(records, "A", "www", "192.0.2.1") != nil {
+		t.Error("expected the old value to be replaced")
+	}
+	if n := countRecords(records, "A", "www"); n != 1 {
+		t.Errorf("expected setting twice to leave 1 record, got %d", n)
+	}
+}
+
+func conformanceDelete(t *testing.T, provider *Provider, zone string) {
+	_, err := provider.AppendRecords(context.Background(), zone, []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "keep"},
+		{Type: "TXT", Name: "_acme-challenge", Value: "remove"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := provider.DeleteRecords(context.Background(), zone, []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "remove"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("expected 1 deleted record, got %d", len(deleted))
+	}
+
+	records := mustGetRecords(t, provider, zone)
+	if findRecord(records, "TXT", "_acme-challenge", "remove") != nil {
+		t.Error("expected the record to be deleted")
+	}
+	if findRecord(records, "TXT", "_acme-challenge", "keep") == nil {
+		t.Error("expected the other record with the same name to be kept")
+	}
+}
+
+func conformanceConcurrent(t *testing.T, provider *Provider, zone string) {
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := provider.AppendRecords(context.Background(), zone, []libdns.Record{
+				{Type: "TXT", Name: "concurrent", Value: fmt.Sprintf("value-%d", i)},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	if got := countRecords(mustGetRecords(t, provider, zone), "TXT", "concurrent"); got != n {
+		t.Errorf("expected all %d records to be visible, got %d", n, got)
+	}
+}
+
+func mustGetRecords(t *testing.T, provider *Provider, zone string) []libdns.Record {
+	t.Helper()
+
+	records, err := provider.GetRecords(context.Background(), zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return records
+}
+
+func findRecord(records []libdns.Record, typ, name, value string) *libdns.Record {
+	for i, rec := range records {
+		if rec.Type == typ && rec.Name == name && rec.Value == value {
+			return &records[i]
+		}
+	}
+
+	return nil
+}
+
+func countRecords(records []libdns.Record, typ, name string) int {
+	n := 0
+	for _, rec := range records {
+		if rec.Type == typ && rec.Name == name {
+			n++
+		}
+	}
+
+	return n
+}
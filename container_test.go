@@ -0,0 +1,136 @@
+//go:build container
+
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The container harness runs the TestProvider_* suite against a panel in a
+// throwaway container, so it never touches a production zone:
+//
+//	go test -tags container ./...
+//
+// By default it builds the fake panel in testdata/container. Set
+// LIBDNS_DA_TEST_IMAGE to run a DirectAdmin evaluation image instead, with
+// LIBDNS_DA_TEST_USER and LIBDNS_DA_TEST_LOGIN_KEY set to credentials it
+// accepts. Docker has to be available.
+const (
+	containerImage = "libdns-directadmin-fakepanel"
+	containerZone  = "libdns-container.test"
+	containerPort  = "2222/tcp"
+)
+
+func TestMain(m *testing.M) {
+	cleanup, err := startContainer()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the DirectAdmin container: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+func startContainer() (func(), error) {
+	image := os.Getenv("LIBDNS_DA_TEST_IMAGE")
+	user := defaultEnv("LIBDNS_DA_TEST_USER", "admin")
+	loginKey := defaultEnv("LIBDNS_DA_TEST_LOGIN_KEY", "container-key")
+
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + containerPort}
+	if len(image) == 0 {
+		image = containerImage
+		if _, err := docker("build", "--tag", image, "--file", "testdata/container/Dockerfile", "."); err != nil {
+			return nil, err
+		}
+		args = append(args, "--env", "FAKEPANEL_LOGIN_KEY="+loginKey, image, "--user", user)
+	} else {
+		args = append(args, image)
+	}
+
+	id, err := docker(args...)
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		_, _ = docker("stop", id)
+	}
+
+	addr, err := docker("port", id, containerPort)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	addr = strings.Split(addr, "\n")[0]
+
+	if err := waitForPort(addr, 2*time.Minute); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	serverURL := "https://" + addr
+	env := map[string]string{
+		"LIBDNS_DA_TEST_MODE":                testModeLive,
+		"LIBDNS_DA_TEST_ZONE":                containerZone + ".",
+		"LIBDNS_DA_TEST_SERVER_URL":          serverURL,
+		"LIBDNS_DA_TEST_INSECURE_SERVER_URL": serverURL,
+		"LIBDNS_DA_TEST_INSECURE_REQUESTS":   "true",
+		"LIBDNS_DA_TEST_USER":                user,
+		"LIBDNS_DA_TEST_LOGIN_KEY":           loginKey,
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+
+	seed := &Provider{
+		ServerURL:        serverURL,
+		User:             user,
+		LoginKey:         loginKey,
+		InsecureRequests: true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := seed.CreateZone(ctx, containerZone, CreateZoneOptions{}); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to seed zone: %v", err)
+	}
+
+	return cleanup, nil
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %v: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func waitForPort(addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v did not come up: %v", addr, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
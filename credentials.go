@@ -0,0 +1,205 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+// Credentials authenticate requests against DirectAdmin.
+type Credentials struct {
+	User     string
+	LoginKey string
+}
+
+// CredentialsProvider supplies the credentials for every API request, so
+// they can come from Vault, a cloud secret manager or any other rotating
+// store. zone is the zone the request is for, or empty for requests that
+// don't concern a single zone. Implementations should cache as they see fit
+// and must be safe for concurrent use.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context, zone string) (Credentials, error)
+}
+
+// CredentialsFunc adapts a function to a CredentialsProvider.
+type CredentialsFunc func(ctx context.Context, zone string) (Credentials, error)
+
+// Credentials calls f.
+func (f CredentialsFunc) Credentials(ctx context.Context, zone string) (Credentials, error) {
+	return f(ctx, zone)
+}
+
+// credentials returns the credentials for a request. In reseller mode,
+// requests for a zone log in as the zone's owner.
+func (p *Provider) credentials(ctx context.Context, zone string) (Credentials, error) {
+	creds, err := p.baseCredentials(ctx, zone)
+	if err != nil || !p.Reseller || len(zone) == 0 {
+		return creds, err
+	}
+
+	owner, err := p.zoneOwner(ctx, zone)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if len(owner) > 0 {
+		creds.User += "|" + owner
+	}
+
+	return creds, nil
+}
+
+// baseCredentials returns the configured credentials for a request, asking
+// the CredentialsProvider if there is one, then the account covering the
+// zone, and using User and LoginKey (or LoginKeyFile) otherwise.
+func (p *Provider) baseCredentials(ctx context.Context, zone string) (Credentials, error) {
+	if p.Credentials != nil {
+		creds, err := p.Credentials.Credentials(ctx, zone)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to get credentials: %w", err)
+		}
+		return creds, nil
+	}
+
+	if account := p.account(ctx, zone); account != nil {
+		return p.accountCredentials(account)
+	}
+	if len(p.Accounts) > 0 && len(p.User) == 0 {
+		// Requests that aren't for a zone fall back to the first account
+		if len(zone) == 0 {
+			return p.accountCredentials(&p.Accounts[0])
+		}
+		return Credentials{}, fmt.Errorf("%w %v", ErrNoAccount, zone)
+	}
+
+	key, err := p.loginKey()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{User: p.User, LoginKey: key}, nil
+}
+
+// loginKeyCache holds the login key read from LoginKeyFile.
+type loginKeyCache struct {
+	mutex  sync.Mutex
+	key    string
+	loaded bool
+}
+
+// loginKey returns the login key, reading LoginKeyFile on first use when
+// no LoginKey is configured.
+func (p *Provider) loginKey() (string, error) {
+	if len(p.LoginKey) > 0 || len(p.LoginKeyFile) == 0 {
+		return p.LoginKey, nil
+	}
+
+	p.keyCache.mutex.Lock()
+	defer p.keyCache.mutex.Unlock()
+
+	if p.keyCache.loaded {
+		return p.keyCache.key, nil
+	}
+
+	key, err := readLoginKeyFile(p.LoginKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	p.keyCache.key = key
+	p.keyCache.loaded = true
+
+	return key, nil
+}
+
+// reloadLoginKey re-reads LoginKeyFile and reports whether the key changed,
+// e.g. because a mounted secret was rotated.
+func (p *Provider) reloadLoginKey() (bool, error) {
+	if len(p.LoginKey) > 0 || len(p.LoginKeyFile) == 0 {
+		return false, nil
+	}
+
+	key, err := readLoginKeyFile(p.LoginKeyFile)
+	if err != nil {
+		return false, err
+	}
+
+	p.keyCache.mutex.Lock()
+	defer p.keyCache.mutex.Unlock()
+
+	changed := key != p.keyCache.key
+	p.keyCache.key = key
+	p.keyCache.loaded = true
+
+	return changed, nil
+}
+
+func readLoginKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read login key file: %w", err)
+	}
+
+	key := strings.TrimSpace(string(data))
+	if len(key) == 0 {
+		return "", fmt.Errorf("login key file %v is empty", path)
+	}
+
+	return key, nil
+}
+
+// CredentialsRefresher is implemented by CredentialsProviders that cache
+// credentials. RefreshCredentials is called when DirectAdmin rejects the
+// credentials, before they are requested again.
+type CredentialsRefresher interface {
+	RefreshCredentials(ctx context.Context, zone string) error
+}
+
+// credentialsMiddleware retries a request once when DirectAdmin rejects the
+// credentials and fresh ones may be available: a CredentialsProvider is
+// asked again, after being told to refresh if it supports that, and
+// LoginKeyFile is re-read to see whether the key was rotated.
+func (p *Provider) credentialsMiddleware(next Handler) Handler {
+	return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+		resp, err := next(ctx, req)
+		if err != nil || !errors.Is(daapi.CheckResponse(req.Command, resp), ErrAuthFailed) {
+			return resp, err
+		}
+
+		retry, refreshErr := p.refreshCredentials(ctx, req.Zone)
+		if refreshErr != nil {
+			p.logger(ctx).Warnw("failed to refresh credentials", "error", refreshErr)
+			return resp, nil
+		}
+		if !retry {
+			return resp, nil
+		}
+
+		p.logger(ctx).Infow("credentials rejected, retrying with refreshed credentials", "command", req.Command)
+
+		return next(ctx, req)
+	}
+}
+
+// refreshCredentials reports whether credentials may have changed since
+// they were rejected.
+func (p *Provider) refreshCredentials(ctx context.Context, zone string) (bool, error) {
+	if p.Credentials == nil {
+		if account := p.account(ctx, zone); account != nil {
+			return p.reloadAccountKey(account)
+		}
+		return p.reloadLoginKey()
+	}
+
+	if refresher, ok := p.Credentials.(CredentialsRefresher); ok {
+		if err := refresher.RefreshCredentials(ctx, zone); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,157 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestFake_LoginKeyFileRotation(t *testing.T) {
+	server := directadmintest.NewServer("admin", "old-key")
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	path := filepath.Join(t.TempDir(), "login-key")
+	if err := os.WriteFile(path, []byte("old-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := New(server.URL, "admin", "", WithAllowInsecureHTTP(), WithLoginKeyFile(path), WithLogger(NopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent int
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	ctx := context.Background()
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is rotated on the panel and in the mounted secret
+	server.LoginKey = "new-key"
+	if err := os.WriteFile(path, []byte("new-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatalf("expected the rotated key to be read, got %v", err)
+	}
+	if key, _ := provider.loginKey(); key != "new-key" {
+		t.Errorf("expected the new key to be kept, got %q", key)
+	}
+
+	// A key that is rejected and unchanged isn't retried
+	server.LoginKey = "newer-key"
+	sent = 0
+	if _, err := provider.GetRecords(ctx, fakeZone); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("expected the request not to be retried with the same key, got %d requests", sent)
+	}
+}
+
+func TestFake_CredentialsProvider(t *testing.T) {
+	server := directadmintest.NewServer("admin", "vault-key")
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	var zones []string
+	provider, err := New(server.URL, "", "", WithAllowInsecureHTTP(), WithLogger(NopLogger()),
+		WithCredentials(CredentialsFunc(func(ctx context.Context, zone string) (Credentials, error) {
+			zones = append(zones, zone)
+			return Credentials{User: "admin", LoginKey: "vault-key"}, nil
+		})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 || zones[0] != fakeZone {
+		t.Errorf("expected the credentials to be asked for every request with the zone, got %v", zones)
+	}
+
+	failing := errors.New("vault sealed")
+	provider.Credentials = CredentialsFunc(func(ctx context.Context, zone string) (Credentials, error) {
+		return Credentials{}, failing
+	})
+	if _, err := provider.GetRecords(ctx, fakeZone); !errors.Is(err, failing) {
+		t.Errorf("expected the credentials provider's error, got %v", err)
+	}
+}
+
+// rotatingCredentials caches a key until it's told to refresh.
+type rotatingCredentials struct {
+	current   string
+	cached    string
+	refreshes int
+}
+
+func (c *rotatingCredentials) Credentials(ctx context.Context, zone string) (Credentials, error) {
+	if len(c.cached) == 0 {
+		c.cached = c.current
+	}
+	return Credentials{User: "admin", LoginKey: c.cached}, nil
+}
+
+func (c *rotatingCredentials) RefreshCredentials(ctx context.Context, zone string) error {
+	c.refreshes++
+	c.cached = ""
+	return nil
+}
+
+func TestFake_CredentialsRefresh(t *testing.T) {
+	server := directadmintest.NewServer("admin", "old-key")
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	creds := &rotatingCredentials{current: "old-key"}
+	provider, err := New(server.URL, "", "", WithAllowInsecureHTTP(), WithLogger(NopLogger()), WithCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent int
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	ctx := context.Background()
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatal(err)
+	}
+
+	server.LoginKey, creds.current = "new-key", "new-key"
+	sent = 0
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatalf("expected the request to succeed with refreshed credentials, got %v", err)
+	}
+	if creds.refreshes != 1 || sent != 2 {
+		t.Errorf("expected one refresh and one retry, got %d refreshes and %d requests", creds.refreshes, sent)
+	}
+
+	// Credentials that are still rejected after refreshing are retried once
+	server.LoginKey = "newer-key"
+	sent = 0
+	if _, err := provider.GetRecords(ctx, fakeZone); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected a single retry, got %d requests", sent)
+	}
+}
@@ -0,0 +1,257 @@
+package daapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Client calls the DirectAdmin API with a login key. Its zero value isn't
+// usable, BaseURL, User and LoginKey have to be set.
+type Client struct {
+	// BaseURL is the panel's URL, e.g. https://da.example.com:2222. A path
+	// prefix, for panels behind a reverse proxy, is kept.
+	BaseURL *url.URL
+
+	// User and LoginKey authenticate the requests. DirectAdmin's login-as
+	// is used with a User of the form `reseller|user`.
+	User     string
+	LoginKey string
+
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// UserAgent is sent as the User-Agent header if set
+	UserAgent string
+
+	// Headers are added to every request. A Host header sets the request's
+	// host instead.
+	Headers map[string]string
+
+	// MaxResponseBytes limits how much of a response is read, larger
+	// responses fail with ErrResponseTooLarge. Zero or negative reads
+	// responses of any size.
+	MaxResponseBytes int64
+}
+
+// ErrResponseTooLarge is returned for responses larger than
+// Client.MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// CommandURL returns the url of a DirectAdmin command, keeping any path
+// prefix of base so panels served below a reverse proxy path such as
+// https://host/da/ work.
+func CommandURL(base *url.URL, command string) *url.URL {
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + "/" + command
+	if len(base.RawPath) > 0 {
+		u.RawPath = strings.TrimSuffix(base.RawPath, "/") + "/" + command
+	}
+
+	return &u
+}
+
+// Do sends the request and reads the full response. It only fails if the
+// request couldn't be made; use CheckResponse and DecodeResult, or
+// Execute, to check what DirectAdmin answered. The OpenTelemetry trace
+// context of ctx is propagated in the request headers.
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	if c.BaseURL == nil {
+		return nil, errors.New("daapi: no base url")
+	}
+
+	reqURL := CommandURL(c.BaseURL, req.Command)
+	reqURL.RawQuery = req.Params.Encode()
+
+	method := req.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.UserAgent) > 0 {
+		httpReq.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	for key, value := range c.Headers {
+		if strings.EqualFold(key, "Host") {
+			httpReq.Host = value
+			continue
+		}
+		httpReq.Header.Set(key, value)
+	}
+
+	httpReq.SetBasicAuth(c.User, c.LoginKey)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if c.MaxResponseBytes > 0 {
+		// One more byte than allowed tells a response of exactly the
+		// limit from a larger one
+		reader = io.LimitReader(resp.Body, c.MaxResponseBytes+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if c.MaxResponseBytes > 0 && int64(len(body)) > c.MaxResponseBytes {
+		return nil, fmt.Errorf("%w: %v exceeded %d bytes", ErrResponseTooLarge, req.Command, c.MaxResponseBytes)
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
+
+// Execute sends a command that changes something and returns the *Error
+// DirectAdmin reports if it failed.
+func (c *Client) Execute(ctx context.Context, req *Request) error {
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = DecodeResult(req.Command, resp)
+	return err
+}
+
+// List sends a listing command, such as CMD_API_SHOW_DOMAINS, and returns
+// the listed items.
+func (c *Client) List(ctx context.Context, command string, params url.Values) ([]string, error) {
+	query := make(url.Values)
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("json", "yes")
+
+	resp, err := c.Do(ctx, &Request{Command: command, Params: query})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckResponse(command, resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api response error, status code: %v", resp.StatusCode)
+	}
+
+	return ParseList(command, resp.Body)
+}
+
+// Result is the body DirectAdmin answers commands that change something
+// with. Most commands send the error message in Error, others set Error to
+// 1 and send the message as Text and Details.
+type Result struct {
+	Error   string `json:"error,omitempty"`
+	Success string `json:"success,omitempty"`
+	Result  string `json:"result,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// UnmarshalJSON accepts the error flag as a number or boolean as well.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	type result Result
+	var aux struct {
+		result
+		Error json.RawMessage `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*r = Result(aux.result)
+
+	var s string
+	switch {
+	case len(aux.Error) == 0 || string(aux.Error) == "null" || string(aux.Error) == "false":
+	case string(aux.Error) == "true":
+		r.Error = "1"
+	case json.Unmarshal(aux.Error, &s) == nil:
+		r.Error = s
+	default:
+		r.Error = string(aux.Error)
+	}
+
+	return nil
+}
+
+// Failed reports whether DirectAdmin reported an error.
+func (r *Result) Failed() bool {
+	return len(r.Error) > 0 && r.Error != "0"
+}
+
+// Err returns the *Error for a failed result, or nil.
+func (r *Result) Err(command string) error {
+	if !r.Failed() {
+		return nil
+	}
+
+	message, details := r.Error, r.Result
+	if message == "1" {
+		message = r.Text
+		if len(message) == 0 {
+			message = "unknown error"
+		}
+	}
+	if len(details) == 0 {
+		details = r.Details
+	}
+
+	return NewError(command, message, details)
+}
+
+// DecodeResult checks the response to a command that changes something and
+// decodes its result. The *Error DirectAdmin reported is returned as the
+// error.
+func DecodeResult(command string, resp *Response) (*Result, error) {
+	if err := CheckResponse(command, resp); err != nil {
+		return nil, err
+	}
+
+	result, err := ParseResult(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %v response: %w", command, err)
+	}
+
+	if err := result.Err(command); err != nil {
+		return result, err
+	}
+
+	if err := CheckDemo(command, result); err != nil {
+		return result, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("api response error, status code: %v", resp.StatusCode)
+	}
+
+	return result, nil
+}
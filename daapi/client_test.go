@@ -0,0 +1,97 @@
+package daapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	base, err := url.Parse(server.URL + "/da/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Client{BaseURL: base, User: "admin", LoginKey: "key", UserAgent: "test"}
+}
+
+func TestClient_Execute(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		user, key, _ := r.BasicAuth()
+		if user != "admin" || key != "key" || r.URL.Path != "/da/CMD_API_DNS_CONTROL" || r.UserAgent() != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("domain") == "example.com" {
+			_, _ = w.Write([]byte(`{"success":"Records Reset","result":""}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"error":"Cannot View That Domain","result":"You do not own that domain"}`))
+	})
+
+	req := func(domain string) *Request {
+		return &Request{Command: "CMD_API_DNS_CONTROL", Params: url.Values{"action": {"reset"}, "domain": {domain}}}
+	}
+
+	if err := client.Execute(context.Background(), req("example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.Execute(context.Background(), req("example.org"))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrDomainNotFound) || apiErr.Command != "CMD_API_DNS_CONTROL" {
+		t.Errorf("expected a domain not found *Error, got %v", err)
+	}
+
+	client.LoginKey = "wrong"
+	if err := client.Execute(context.Background(), req("example.com")); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("json") != "yes" {
+			_, _ = w.Write([]byte("list[]=example.com&list[]=example.net"))
+			return
+		}
+		_, _ = w.Write([]byte(`["example.com","example.net"]`))
+	})
+
+	domains, err := client.List(context.Background(), "CMD_API_SHOW_DOMAINS", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com", "example.net"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("expected %v, got %v", want, domains)
+	}
+}
+
+func TestClient_MaxResponseBytes(t *testing.T) {
+	body := `{"success":"Records Reset","result":""}`
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	})
+	req := &Request{Command: "CMD_API_DNS_CONTROL"}
+
+	client.MaxResponseBytes = int64(len(body))
+	if _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatalf("expected a response of exactly the limit to be read, got %v", err)
+	}
+
+	client.MaxResponseBytes = int64(len(body)) - 1
+	if _, err := client.Do(context.Background(), req); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+// Package daapi is a low-level client for the DirectAdmin API. It executes
+// commands such as CMD_API_DNS_CONTROL with login key authentication and
+// turns DirectAdmin's error responses into typed errors.
+//
+// The directadmin package's Provider is built on it. Use daapi directly to
+// call DirectAdmin features the provider doesn't wrap:
+//
+//	client := &daapi.Client{BaseURL: base, User: "admin", LoginKey: key}
+//	err := client.Execute(ctx, &daapi.Request{
+//		Command: "CMD_API_DNS_CONTROL",
+//		Params:  url.Values{"domain": {"example.com"}, "action": {"reset"}, "json": {"yes"}},
+//	})
+package daapi
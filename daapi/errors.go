@@ -0,0 +1,337 @@
+package daapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors DirectAdmin API failures are classified into. Use errors.Is to
+// check for them; the returned *Error carries DirectAdmin's own message.
+var (
+	ErrDomainNotFound   = errors.New("domain not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidTTL       = errors.New("invalid ttl")
+	ErrDuplicateRecord  = errors.New("duplicate record")
+	ErrAuthFailed       = errors.New("authentication failed")
+	ErrRateLimited      = errors.New("rate limited")
+
+	// ErrIPBlacklisted means DirectAdmin's brute force protection blocked
+	// the client's IP address. Requests won't succeed, and retrying only
+	// prolongs the block, until the IP is removed from the blacklist or
+	// whitelisted in the panel.
+	ErrIPBlacklisted = errors.New("client ip blacklisted")
+
+	// ErrDomainSuspended means the domain, or the user owning it, is
+	// suspended in DirectAdmin. Requests for it fail until an administrator
+	// unsuspends it, so retrying doesn't help.
+	ErrDomainSuspended = errors.New("domain suspended")
+
+	// ErrQuotaExceeded means the server's limit on the records of a zone
+	// or a user was reached. Retrying doesn't help until records are
+	// removed or the limit is raised.
+	ErrQuotaExceeded = errors.New("record quota exceeded")
+
+	// ErrZoneLocked means another process, e.g. a panel user or a cron
+	// job, holds DirectAdmin's lock on the zone. The change succeeds once
+	// the lock is released, usually within seconds.
+	ErrZoneLocked = errors.New("zone locked")
+
+	// ErrServerMaintenance means DirectAdmin is updating itself or is in
+	// maintenance mode. Requests fail until it's done, which usually takes
+	// minutes, so defer the work and retry later rather than treating the
+	// configuration as broken.
+	ErrServerMaintenance = errors.New("server in maintenance")
+
+	// ErrDemoMode means the server is a DirectAdmin demo installation,
+	// which reports changes as successful without saving them.
+	ErrDemoMode = errors.New("server in demo mode")
+)
+
+var blacklistPatterns = [][]string{
+	{"blacklist"},
+	{"too many", "failed login"},
+	{"brute", "force"},
+}
+
+var maintenancePatterns = [][]string{
+	{"maintenance"},
+	{"directadmin", "being updated"},
+	{"directadmin", "being upgraded"},
+	{"directadmin", "updating"},
+	{"update in progress"},
+}
+
+// loginPagePatterns match DirectAdmin's login page, which it serves with
+// status 200 in place of an API response when it doesn't accept the
+// credentials.
+var loginPagePatterns = [][]string{
+	{"cmd_login"},
+	{"login"},
+	{"password"},
+}
+
+var demoPatterns = [][]string{
+	{"demo mode"},
+	{"demo server"},
+	{"demo", "not saved"},
+	{"demo", "disabled"},
+	{"demo", "not allowed"},
+}
+
+const demoHint = "the server is a DirectAdmin demo, which doesn't save changes, use a real installation"
+
+const maintenanceHint = "DirectAdmin is updating itself or in maintenance mode, retry in a few minutes"
+
+const blacklistHint = "DirectAdmin's brute force protection blocked this host, " +
+	"remove it from the IP blacklist and whitelist it under Brute Force Monitor"
+
+const authFailedHint = "check User and LoginKey, and that the login key allows " +
+	"CMD_API_SHOW_DOMAINS and CMD_API_DNS_CONTROL (plus CMD_API_DOMAIN for zone management)"
+
+// Error is returned when DirectAdmin reports that a command failed.
+type Error struct {
+	// Command is the DirectAdmin command that failed
+	Command string
+
+	// Message and Details are the error and result text DirectAdmin returned
+	Message string
+	Details string
+
+	// Hint suggests how to fix the problem, if the error was recognized
+	Hint string
+
+	kind error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("api response error: %v", e.Message)
+	if len(e.Details) > 0 {
+		msg += ": " + e.Details
+	}
+	if len(e.Hint) > 0 {
+		msg += " (" + e.Hint + ")"
+	}
+
+	return msg
+}
+
+// Unwrap returns the error class, e.g. ErrPermissionDenied, if DirectAdmin's
+// message was recognized.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+type errorClass struct {
+	kind     error
+	hint     string
+	patterns [][]string
+}
+
+// errorClasses maps known DirectAdmin error texts to error classes. Each
+// pattern is a list of substrings that all have to appear in the lowercased
+// error text.
+var errorClasses = []errorClass{
+	{
+		kind:     ErrIPBlacklisted,
+		hint:     blacklistHint,
+		patterns: blacklistPatterns,
+	},
+	{
+		kind:     ErrServerMaintenance,
+		hint:     maintenanceHint,
+		patterns: maintenancePatterns,
+	},
+	{
+		// Checked before ErrPermissionDenied, demos refuse some commands
+		// as not allowed
+		kind:     ErrDemoMode,
+		hint:     demoHint,
+		patterns: demoPatterns,
+	},
+	{
+		// Checked before ErrDomainNotFound, DirectAdmin may refuse to show
+		// a suspended domain as if it didn't exist
+		kind: ErrDomainSuspended,
+		hint: "the domain or its user is suspended, unsuspend it in DirectAdmin",
+		patterns: [][]string{
+			{"suspended"},
+		},
+	},
+	{
+		kind: ErrZoneLocked,
+		hint: "another process is changing the zone, retry shortly",
+		patterns: [][]string{
+			{"cannot currently modify"},
+			{"zone", "locked"},
+			{"currently", "locked"},
+		},
+	},
+	{
+		kind: ErrDomainNotFound,
+		hint: "check that the zone exists in DirectAdmin and is owned by the configured user",
+		patterns: [][]string{
+			{"cannot view that domain"},
+			{"you do not own that domain"},
+			{"domain", "does not exist"},
+			{"unable to find", "domain"},
+		},
+	},
+	{
+		kind: ErrPermissionDenied,
+		hint: "allow CMD_API_SHOW_DOMAINS and CMD_API_DNS_CONTROL for the login key",
+		patterns: [][]string{
+			{"you cannot execute that command"},
+			{"not allowed to execute"},
+			{"command", "not allowed"},
+		},
+	},
+	{
+		kind: ErrInvalidTTL,
+		hint: "use a TTL DirectAdmin accepts, or enable TTL overrides for the zone",
+		patterns: [][]string{
+			{"ttl", "invalid"},
+			{"ttl", "must be"},
+			{"ttl", "out of range"},
+			{"ttl", "not allowed"},
+		},
+	},
+	{
+		kind: ErrDuplicateRecord,
+		hint: "the record already exists, use SetRecords to change it",
+		patterns: [][]string{
+			{"already exists"},
+			{"duplicate"},
+		},
+	},
+	{
+		kind: ErrQuotaExceeded,
+		hint: "remove records or ask the server administrator to raise the limit",
+		patterns: [][]string{
+			{"quota"},
+			{"too many records"},
+			{"maximum number of records"},
+			{"record", "limit", "reached"},
+			{"record", "exceed", "limit"},
+		},
+	},
+}
+
+// NewError builds an Error from DirectAdmin's error and result text,
+// classifying it if the text is recognized.
+func NewError(command, message, result string) *Error {
+	apiErr := &Error{
+		Command: command,
+		Message: message,
+		Details: strings.Split(result, "\n")[0],
+	}
+
+	text := strings.ToLower(message + " " + result)
+	for _, class := range errorClasses {
+		if matchesAny(text, class.patterns) {
+			apiErr.kind = class.kind
+			apiErr.Hint = class.hint
+			break
+		}
+	}
+
+	return apiErr
+}
+
+// CheckDemo returns an ErrDemoMode *Error if DirectAdmin reported a command
+// as successful but said it's a demo server, which doesn't save changes.
+func CheckDemo(command string, result *Result) error {
+	text := strings.ToLower(strings.Join([]string{result.Success, result.Result, result.Text, result.Details}, " "))
+	if result.Failed() || !matchesAny(text, demoPatterns) {
+		return nil
+	}
+
+	message := result.Success
+	if len(message) == 0 {
+		message = result.Text
+	}
+
+	return &Error{
+		Command: command,
+		Message: fmt.Sprintf("DirectAdmin didn't save the change: %v", message),
+		Details: strings.Split(strings.TrimSpace(result.Result+"\n"+result.Details), "\n")[0],
+		Hint:    demoHint,
+		kind:    ErrDemoMode,
+	}
+}
+
+func matchesAny(text string, patterns [][]string) bool {
+	for _, pattern := range patterns {
+		matched := true
+		for _, part := range pattern {
+			if !strings.Contains(text, part) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckResponse detects responses that aren't API responses at all. When
+// the login key is wrong, expired or lacks the permission for a command,
+// DirectAdmin may answer with its HTML login page instead of JSON, and once
+// the client's IP is blacklisted, with a page saying so. While it updates
+// itself, it answers with a maintenance page.
+func CheckResponse(command string, resp *Response) error {
+	if matchesAny(strings.ToLower(string(resp.Body)), blacklistPatterns) && (resp.StatusCode != http.StatusOK || isHTML(resp)) {
+		return &Error{
+			Command: command,
+			Message: fmt.Sprintf("DirectAdmin blocked the client ip (status code %d)", resp.StatusCode),
+			Hint:    blacklistHint,
+			kind:    ErrIPBlacklisted,
+		}
+	}
+
+	if matchesAny(strings.ToLower(string(resp.Body)), maintenancePatterns) && (resp.StatusCode != http.StatusOK || isHTML(resp)) {
+		return &Error{
+			Command: command,
+			Message: fmt.Sprintf("DirectAdmin is in maintenance (status code %d)", resp.StatusCode),
+			Hint:    maintenanceHint,
+			kind:    ErrServerMaintenance,
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &Error{
+			Command: command,
+			Message: "DirectAdmin kept rejecting the request with status code 429",
+			Hint:    "reduce the request rate or raise MaxRetries",
+			kind:    ErrRateLimited,
+		}
+	}
+
+	// Other HTML pages, such as a reverse proxy's 502 page, fail with their
+	// status code
+	loginPage := resp.StatusCode == http.StatusOK && isHTML(resp) && matchesAny(strings.ToLower(string(resp.Body)), loginPagePatterns)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || loginPage {
+		return &Error{
+			Command: command,
+			Message: fmt.Sprintf("DirectAdmin rejected the credentials (status code %d)", resp.StatusCode),
+			Hint:    authFailedHint,
+			kind:    ErrAuthFailed,
+		}
+	}
+
+	return nil
+}
+
+func isHTML(resp *Response) bool {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return true
+	}
+
+	body := bytes.TrimSpace(resp.Body)
+	return len(body) > 0 && body[0] == '<'
+}
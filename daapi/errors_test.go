@@ -0,0 +1,170 @@
+package daapi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewError(t *testing.T) {
+	var tests = []struct {
+		message string
+		result  string
+		want    error
+	}{
+		{
+			message: "Cannot View That Domain",
+			result:  "You do not own that domain",
+			want:    ErrDomainNotFound,
+		},
+		{
+			message: "You cannot execute that command",
+			result:  "The request you've made cannot be executed because it does not exist in your authority level",
+			want:    ErrPermissionDenied,
+		},
+		{
+			message: "Error with one or more records",
+			result:  "TTL value is invalid\nDetails",
+			want:    ErrInvalidTTL,
+		},
+		{
+			message: "Unable to add record",
+			result:  "Record already exists",
+			want:    ErrDuplicateRecord,
+		},
+		{
+			message: "Your IP is blacklisted",
+			result:  "",
+			want:    ErrIPBlacklisted,
+		},
+		{
+			message: "Cannot View That Domain",
+			result:  "This domain is suspended",
+			want:    ErrDomainSuspended,
+		},
+		{
+			message: "Your account is suspended",
+			result:  "",
+			want:    ErrDomainSuspended,
+		},
+		{
+			message: "Cannot Add Record",
+			result:  "You have reached the maximum number of records for this zone",
+			want:    ErrQuotaExceeded,
+		},
+		{
+			message: "Unable to add record",
+			result:  "DNS record limit reached",
+			want:    ErrQuotaExceeded,
+		},
+		{
+			message: "Unable to modify the zone",
+			result:  "You cannot currently modify this zone, please try again later",
+			want:    ErrZoneLocked,
+		},
+		{
+			message: "Unable to process the request",
+			result:  "DirectAdmin is currently being updated",
+			want:    ErrServerMaintenance,
+		},
+		{
+			message: "Cannot Add Record",
+			result:  "This command is not allowed in demo mode",
+			want:    ErrDemoMode,
+		},
+		{
+			message: "Something unexpected",
+			result:  "",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := NewError("CMD_API_DNS_CONTROL", tt.message, tt.result)
+
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("expected %v to be classified as %v", err, tt.want)
+			}
+
+			if tt.want == nil && errors.Unwrap(err) != nil {
+				t.Errorf("expected %v to be unclassified, got %v", err, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	var tests = []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{
+			name:   "maintenance page",
+			status: http.StatusServiceUnavailable,
+			body:   "<html><body>DirectAdmin is down for maintenance</body></html>",
+			want:   ErrServerMaintenance,
+		},
+		{
+			name:   "update page",
+			status: http.StatusOK,
+			body:   "<html><body>DirectAdmin is updating, please wait</body></html>",
+			want:   ErrServerMaintenance,
+		},
+		{
+			name:   "login page",
+			status: http.StatusOK,
+			body:   "<html><body>Login</body></html>",
+			want:   ErrAuthFailed,
+		},
+		{
+			name:   "proxy 502 page",
+			status: http.StatusBadGateway,
+			body:   "<html><head><title>502 Bad Gateway</title></head><body><center><h1>502 Bad Gateway</h1></center><hr><center>nginx</center></body></html>",
+			want:   nil,
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			body:   "<html><body>Forbidden</body></html>",
+			want:   ErrAuthFailed,
+		},
+		{
+			name:   "zone mentioning maintenance",
+			status: http.StatusOK,
+			body:   `{"records":[{"type":"TXT","name":"maintenance","value":"\"on\""}]}`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckResponse("CMD_API_DNS_CONTROL", &Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       []byte(tt.body),
+			})
+
+			if tt.want == nil && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckDemo(t *testing.T) {
+	demo := &Result{Success: "Record Added", Result: "Demo mode: changes are not saved"}
+	if err := CheckDemo("CMD_API_DNS_CONTROL", demo); !errors.Is(err, ErrDemoMode) {
+		t.Errorf("expected ErrDemoMode, got %v", err)
+	}
+
+	real := &Result{Success: "Record Added"}
+	if err := CheckDemo("CMD_API_DNS_CONTROL", real); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
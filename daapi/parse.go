@@ -0,0 +1,77 @@
+package daapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParseList understands the shapes DirectAdmin's listing commands, such as
+// CMD_API_SHOW_DOMAINS and CMD_API_SHOW_USERS, answer in: a JSON array, a
+// JSON object keyed by index, an error object, or the legacy url-encoded
+// list[]=... format.
+func ParseList(command string, body []byte) ([]string, error) {
+	var list []string
+	if err := json.Unmarshal(body, &list); err == nil {
+		return list, nil
+	}
+
+	var errData Result
+	if err := json.Unmarshal(body, &errData); err == nil && errData.Failed() {
+		return nil, errData.Err(command)
+	}
+
+	var indexed map[string]string
+	if err := json.Unmarshal(body, &indexed); err == nil {
+		keys := make([]string, 0, len(indexed))
+		for k := range indexed {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		list = make([]string, 0, len(indexed))
+		for _, k := range keys {
+			list = append(list, indexed[k])
+		}
+		return list, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %v response: %v", command, err)
+	}
+	if result := resultFromValues(values); result.Failed() {
+		return nil, result.Err(command)
+	}
+
+	return values["list[]"], nil
+}
+
+// ParseResult decodes the result of a command, sent as JSON or in the
+// legacy url-encoded `error=1&text=...&details=...` format.
+func ParseResult(body []byte) (*Result, error) {
+	var result Result
+	jsonErr := json.Unmarshal(body, &result)
+	if jsonErr == nil {
+		return &result, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimSpace(string(body)))
+	if err != nil || (len(values.Get("error")) == 0 && len(values.Get("text")) == 0) {
+		return nil, jsonErr
+	}
+
+	return resultFromValues(values), nil
+}
+
+func resultFromValues(values url.Values) *Result {
+	return &Result{
+		Error:   values.Get("error"),
+		Success: values.Get("success"),
+		Result:  values.Get("result"),
+		Text:    values.Get("text"),
+		Details: values.Get("details"),
+	}
+}
@@ -0,0 +1,85 @@
+package daapi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseResult(t *testing.T) {
+	var tests = []struct {
+		body    string
+		failed  bool
+		message string
+		details string
+	}{
+		{body: `{"success":"Record Added","result":""}`},
+		{body: `{"error":"Cannot Add Record","result":"Record already exists"}`, failed: true, message: "Cannot Add Record", details: "Record already exists"},
+		{body: `{"error":1,"text":"Cannot View That Domain","details":"You do not own that domain"}`, failed: true, message: "Cannot View That Domain", details: "You do not own that domain"},
+		{body: `{"error":"1","text":"Unable to add record"}`, failed: true, message: "Unable to add record"},
+		{body: `{"error":0,"text":"Record added"}`},
+		{body: `{"error":true}`, failed: true, message: "unknown error"},
+		{body: `error=1&text=Cannot%20Add%20Record&details=Record%20already%20exists`, failed: true, message: "Cannot Add Record", details: "Record already exists"},
+		{body: `error=0&text=Record%20added&details=`},
+	}
+
+	for _, tt := range tests {
+		result, err := ParseResult([]byte(tt.body))
+		if err != nil {
+			t.Errorf("%v: %v", tt.body, err)
+			continue
+		}
+		if result.Failed() != tt.failed {
+			t.Errorf("%v: expected failed to be %v", tt.body, tt.failed)
+			continue
+		}
+
+		err = result.Err("CMD_API_DNS_CONTROL")
+		var apiErr *Error
+		switch {
+		case !tt.failed && err != nil:
+			t.Errorf("%v: expected no error, got %v", tt.body, err)
+		case tt.failed && !errors.As(err, &apiErr):
+			t.Errorf("%v: expected an *Error, got %v", tt.body, err)
+		case tt.failed && (apiErr.Message != tt.message || apiErr.Details != tt.details):
+			t.Errorf("%v: expected %q: %q, got %q: %q", tt.body, tt.message, tt.details, apiErr.Message, apiErr.Details)
+		}
+	}
+
+	if _, err := ParseResult([]byte(`<html>`)); err == nil {
+		t.Error("expected an error for a body that isn't a result")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	var tests = []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr error
+	}{
+		{name: "array", body: `["a.com","b.com"]`, want: []string{"a.com", "b.com"}},
+		{name: "indexed", body: `{"0":"a.com","1":"b.com"}`, want: []string{"a.com", "b.com"}},
+		{name: "urlencoded", body: "list[]=a.com&list[]=b.com", want: []string{"a.com", "b.com"}},
+		{name: "empty", body: `[]`, want: []string{}},
+		{name: "error", body: `{"error":"You cannot execute that command","result":""}`, wantErr: ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseList("CMD_API_SHOW_DOMAINS", []byte(tt.body))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
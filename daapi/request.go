@@ -0,0 +1,37 @@
+package daapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Request describes a single call to the DirectAdmin API.
+type Request struct {
+	// Command is the DirectAdmin command, e.g. `CMD_API_DNS_CONTROL`
+	Command string
+
+	// Method is the HTTP method used for the call, GET if empty
+	Method string
+
+	// Params are the query parameters sent with the command. Middleware may
+	// modify them before passing the request on.
+	Params url.Values
+
+	// Zone is the zone the request operates on, if any
+	Zone string
+}
+
+// Response is the raw response DirectAdmin returned for a Request.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Handler executes a Request.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Middleware wraps the Handler that executes API requests, allowing it to
+// inspect or change requests and responses, e.g. for auditing or metrics.
+type Middleware func(next Handler) Handler
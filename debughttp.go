@@ -0,0 +1,172 @@
+package directadmin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// debugHTTPExchanges is how many exchanges HTTPExchanges keeps
+	debugHTTPExchanges = 50
+
+	// maxDebugBody is how much of a body is recorded
+	maxDebugBody = 64 << 10
+)
+
+// sensitiveHeaders are replaced in recorded exchanges.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HTTPExchange is a request sent to DirectAdmin and its response, recorded
+// with DebugHTTP. Credentials are redacted and bodies are cut off after
+// 64 KiB.
+type HTTPExchange struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header,omitempty"`
+	RequestBody   string      `json:"request_body,omitempty"`
+
+	StatusCode     int         `json:"status_code,omitempty"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+
+	// Err is the error of a request that got no response
+	Err string `json:"error,omitempty"`
+}
+
+// exchangeLog is a ring buffer of the latest exchanges.
+type exchangeLog struct {
+	mutex     sync.Mutex
+	exchanges []HTTPExchange
+	next      int
+}
+
+func (l *exchangeLog) add(exchange HTTPExchange) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.exchanges) < debugHTTPExchanges {
+		l.exchanges = append(l.exchanges, exchange)
+		return
+	}
+
+	l.exchanges[l.next] = exchange
+	l.next = (l.next + 1) % debugHTTPExchanges
+}
+
+// HTTPExchanges returns the latest requests sent to DirectAdmin and their
+// responses, oldest first, if DebugHTTP is set. Attach them to bug reports
+// about DirectAdmin's behavior.
+func (p *Provider) HTTPExchanges() []HTTPExchange {
+	p.exchanges.mutex.Lock()
+	defer p.exchanges.mutex.Unlock()
+
+	exchanges := make([]HTTPExchange, 0, len(p.exchanges.exchanges))
+	exchanges = append(exchanges, p.exchanges.exchanges[p.exchanges.next:]...)
+	return append(exchanges, p.exchanges.exchanges[:p.exchanges.next]...)
+}
+
+// debugTransport records the exchanges with DirectAdmin for DebugHTTP.
+type debugTransport struct {
+	p    *Provider
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *req.URL
+	u.User = nil
+
+	exchange := HTTPExchange{
+		Time:          time.Now(),
+		Method:        req.Method,
+		URL:           t.p.scrubSecrets(u.String()),
+		RequestHeader: t.redactHeader(req.Header),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = t.redactBody(body, req.Header.Get("Content-Type"))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	exchange.Duration = time.Since(exchange.Time)
+	if err != nil {
+		exchange.Err = t.p.scrubSecrets(err.Error())
+		t.record(exchange)
+		return nil, err
+	}
+
+	// Only the recorded part of the body is read ahead, so MaxResponseBytes
+	// still applies to the rest
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDebugBody))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	exchange.StatusCode = resp.StatusCode
+	exchange.ResponseHeader = t.redactHeader(resp.Header)
+	exchange.ResponseBody = t.p.scrubSecrets(string(body))
+	t.record(exchange)
+
+	return resp, nil
+}
+
+func (t *debugTransport) record(exchange HTTPExchange) {
+	t.p.exchanges.add(exchange)
+	if t.p.OnHTTPExchange != nil {
+		t.p.OnHTTPExchange(exchange)
+	}
+}
+
+func (t *debugTransport) redactHeader(header http.Header) http.Header {
+	redactedHeader := make(http.Header, len(header))
+	for key, values := range header {
+		for _, value := range values {
+			redactedHeader.Add(key, t.p.scrubSecrets(value))
+		}
+	}
+	for _, key := range sensitiveHeaders {
+		if len(redactedHeader.Values(key)) > 0 {
+			redactedHeader.Set(key, redacted)
+		}
+	}
+
+	return redactedHeader
+}
+
+// redactBody scrubs a request body, replacing the password fields of forms
+// too.
+func (t *debugTransport) redactBody(body []byte, contentType string) string {
+	if len(body) > maxDebugBody {
+		body = body[:maxDebugBody]
+	}
+
+	if contentType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for _, key := range []string{"passwd", "passwd2", "password"} {
+				if form.Has(key) {
+					form.Set(key, redacted)
+				}
+			}
+			body = []byte(form.Encode())
+		}
+	}
+
+	return t.p.scrubSecrets(string(body))
+}
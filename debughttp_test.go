@@ -0,0 +1,67 @@
+package directadmin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestFake_DebugHTTP(t *testing.T) {
+	const loginKey = "s3cr3t-login-key"
+	server := directadmintest.NewServer("admin", loginKey)
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	var seen []HTTPExchange
+	provider, err := New(server.URL, "admin", loginKey, WithAllowInsecureHTTP(), WithDebugHTTP(),
+		WithLogger(NopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.OnHTTPExchange = func(exchange HTTPExchange) {
+		seen = append(seen, exchange)
+	}
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+
+	exchanges := provider.HTTPExchanges()
+	if len(exchanges) == 0 || len(exchanges) != len(seen) {
+		t.Fatalf("expected the exchanges to be recorded and reported, got %d and %d", len(exchanges), len(seen))
+	}
+
+	last := exchanges[len(exchanges)-1]
+	if last.Method != http.MethodGet || !strings.Contains(last.URL, "action=add") || last.StatusCode != http.StatusOK {
+		t.Errorf("unexpected exchange %+v", last)
+	}
+	if !strings.Contains(last.ResponseBody, "Record Added") {
+		t.Errorf("expected the response body to be recorded, got %q", last.ResponseBody)
+	}
+	if got := last.RequestHeader.Get("Authorization"); got != redacted {
+		t.Errorf("expected the authorization header to be redacted, got %q", got)
+	}
+
+	for _, exchange := range exchanges {
+		if strings.Contains(exchange.URL+exchange.RequestBody+exchange.ResponseBody, loginKey) {
+			t.Errorf("expected the login key to be redacted from %+v", exchange)
+		}
+	}
+}
+
+func TestExchangeLog(t *testing.T) {
+	var p Provider
+	for i := 0; i < debugHTTPExchanges+5; i++ {
+		p.exchanges.add(HTTPExchange{StatusCode: i})
+	}
+
+	exchanges := p.HTTPExchanges()
+	if len(exchanges) != debugHTTPExchanges || exchanges[0].StatusCode != 5 || exchanges[len(exchanges)-1].StatusCode != debugHTTPExchanges+4 {
+		t.Errorf("expected the latest %d exchanges in order, got %d from %d", debugHTTPExchanges, len(exchanges), exchanges[0].StatusCode)
+	}
+}
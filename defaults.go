@@ -0,0 +1,74 @@
+package directadmin
+
+import (
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultRecordHosts are the hosts DirectAdmin's default zone template
+// points at the server's IP address when it creates a domain.
+var defaultRecordHosts = map[string]bool{
+	"ftp":     true,
+	"imap":    true,
+	"mail":    true,
+	"pop":     true,
+	"smtp":    true,
+	"webmail": true,
+	"www":     true,
+}
+
+// withoutDefaultRecords returns the records of the zone except those
+// DirectAdmin's default template created: the template's hosts pointing at
+// the address of the zone's apex, `localhost`, and the MX record for `mail`
+// with priority 10. Records the template would create with other values are
+// kept, as they were changed since.
+func withoutDefaultRecords(zone string, records []libdns.Record) []libdns.Record {
+	apex := apexAddresses(zone, records)
+
+	filtered := make([]libdns.Record, 0, len(records))
+	for _, rec := range records {
+		if !isDefaultRecord(zone, rec, apex) {
+			filtered = append(filtered, rec)
+		}
+	}
+
+	return filtered
+}
+
+// apexAddresses returns the addresses of the zone's apex, which the default
+// records point at.
+func apexAddresses(zone string, records []libdns.Record) map[string]bool {
+	apex := make(map[string]bool)
+	for _, rec := range records {
+		if isApexAddress(zone, rec) {
+			apex[rec.Value] = true
+		}
+	}
+
+	return apex
+}
+
+func isApexAddress(zone string, rec libdns.Record) bool {
+	return (rec.Type == "A" || rec.Type == "AAAA") && relativeName(rec.Name, zone) == "@"
+}
+
+func isDefaultRecord(zone string, rec libdns.Record, apex map[string]bool) bool {
+	name := relativeName(rec.Name, zone)
+
+	switch rec.Type {
+	case "A", "AAAA":
+		if name == "localhost" {
+			return rec.Value == "127.0.0.1" || rec.Value == "::1"
+		}
+		return defaultRecordHosts[name] && apex[rec.Value]
+	case "CNAME":
+		target := strings.ToLower(strings.TrimSuffix(rec.Value, "."))
+		return name == "www" && (target == "@" || target == strings.ToLower(zone))
+	case "MX":
+		target, err := mxTarget(rec.Value, zone, true)
+		return err == nil && name == "@" && rec.Priority == 10 && strings.EqualFold(target, "mail."+zone+".")
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package directadmin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestFake_HideDefaultRecords(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.HideDefaultRecords = true
+	provider.SortRecords = true
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: fakeZone + ".", Value: "192.0.2.1", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "ftp", Value: "192.0.2.1", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "mail", Value: "192.0.2.1", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "localhost", Value: "127.0.0.1", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "pop", Value: "198.51.100.7", TTL: 3600},
+		directadmintest.Record{Type: "MX", Name: fakeZone + ".", Value: "10 mail", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "api", Value: "192.0.2.1", TTL: 3600},
+	)
+
+	records, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, rec := range records {
+		got = append(got, rec.Name+" "+rec.Type+" "+rec.Value)
+	}
+	want := []string{"@ A 192.0.2.1", "@ NS ns1.example.net.", "api A 192.0.2.1", "pop A 198.51.100.7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// SyncZone doesn't delete the hidden records
+	desired := make([]libdns.Record, 0, len(records))
+	for _, rec := range records {
+		if rec.Type != "NS" {
+			rec.ID = ""
+			desired = append(desired, rec)
+		}
+	}
+	plan, err := provider.SyncZone(ctx, fakeZone, desired, SyncOptions{IgnoreTypes: []string{"NS"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected no changes, got %+v", plan)
+	}
+}
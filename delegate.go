@@ -0,0 +1,147 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// DelegateSubzone delegates child, a subdomain of parentZone such as
+// `lab.example.com` or just `lab`, to the nameservers by adding NS records
+// for it to parentZone. Nameservers inside the child zone can't be
+// resolved without glue, so glueIPs has to give their addresses; the A and
+// AAAA glue records are added with the NS records. Everything is validated
+// before anything is written, and records the zone already has are kept.
+// It returns the records that were added.
+func (p *Provider) DelegateSubzone(ctx context.Context, parentZone, child string, nameservers []string, glueIPs map[string][]net.IP) (_ []libdns.Record, err error) {
+	parentZone = strings.TrimSuffix(parentZone, ".")
+
+	ctx, end := p.startOperation(ctx, "DelegateSubzone", parentZone)
+	defer end(&err)
+
+	records, err := delegationRecords(parentZone, child, nameservers, glueIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := p.GetRecords(ctx, parentZone)
+	if err != nil {
+		return nil, err
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, rec := range existing {
+		have[delegationKey(parentZone, rec)] = true
+	}
+
+	var missing []libdns.Record
+	for _, rec := range records {
+		if !have[delegationKey(parentZone, rec)] {
+			missing = append(missing, rec)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	return p.AppendRecords(ctx, parentZone, missing)
+}
+
+// delegationRecords validates a delegation and returns its NS and glue
+// records.
+func delegationRecords(parentZone, child string, nameservers []string, glueIPs map[string][]net.IP) ([]libdns.Record, error) {
+	parent := strings.ToLower(parentZone)
+	childZone := strings.ToLower(strings.TrimSuffix(child, "."))
+	if !strings.HasSuffix(child, ".") && !strings.HasSuffix(childZone, "."+parent) {
+		childZone += "." + parent
+	}
+	if !strings.HasSuffix(childZone, "."+parent) || !validHostname(childZone) {
+		return nil, fmt.Errorf("%v is not a subdomain of %v", child, parentZone)
+	}
+	if len(nameservers) == 0 {
+		return nil, errors.New("at least one nameserver is required")
+	}
+
+	name := strings.TrimSuffix(childZone, "."+parent)
+
+	var records []libdns.Record
+	var errs []string
+	needGlue := map[string]bool{}
+	for _, ns := range nameservers {
+		host := strings.ToLower(strings.TrimSuffix(ns, "."))
+		if !validHostname(host) {
+			errs = append(errs, fmt.Sprintf("invalid nameserver %q", ns))
+			continue
+		}
+		if host == childZone || strings.HasSuffix(host, "."+childZone) {
+			needGlue[host] = true
+		}
+		records = append(records, libdns.Record{Type: "NS", Name: name, Value: host + "."})
+	}
+
+	for ns, ips := range glueIPs {
+		host := strings.ToLower(strings.TrimSuffix(ns, "."))
+		delete(needGlue, host)
+
+		glueName := relativeName(host, parentZone)
+		if glueName == host {
+			errs = append(errs, fmt.Sprintf("glue for %v, which is outside %v, can't be added", ns, parentZone))
+			continue
+		}
+		if len(ips) == 0 {
+			errs = append(errs, fmt.Sprintf("no glue addresses for %v", ns))
+		}
+
+		for _, ip := range ips {
+			recordType := "AAAA"
+			if ip.To4() != nil {
+				recordType = "A"
+			}
+			records = append(records, libdns.Record{Type: recordType, Name: glueName, Value: ip.String()})
+		}
+	}
+
+	for host := range needGlue {
+		errs = append(errs, fmt.Sprintf("nameserver %v is inside %v and needs glue addresses", host, childZone))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid delegation of %v: %v", childZone, strings.Join(errs, "; "))
+	}
+
+	return records, nil
+}
+
+// delegationKey identifies a delegation record by type, name and value.
+func delegationKey(zone string, rec libdns.Record) string {
+	value := rec.Value
+	if rec.Type == "NS" {
+		value = strings.ToLower(strings.TrimSuffix(value, "."))
+	}
+
+	return rec.Type + "\x00" + relativeName(rec.Name, zone) + "\x00" + value
+}
+
+// validHostname reports whether name is a syntactically valid host name.
+func validHostname(name string) bool {
+	if len(name) == 0 || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package directadmin
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestDelegationRecords(t *testing.T) {
+	var tests = []struct {
+		child       string
+		nameservers []string
+		glue        map[string][]net.IP
+		want        []string
+		err         string
+	}{
+		{
+			child:       "lab",
+			nameservers: []string{"ns1.example.net", "ns2.example.net."},
+			want:        []string{"NS lab ns1.example.net.", "NS lab ns2.example.net."},
+		},
+		{
+			child:       "lab.example.com.",
+			nameservers: []string{"ns1.lab.example.com"},
+			glue:        map[string][]net.IP{"ns1.lab.example.com": {net.ParseIP("192.0.2.53"), net.ParseIP("2001:db8::53")}},
+			want:        []string{"NS lab ns1.lab.example.com.", "A ns1.lab 192.0.2.53", "AAAA ns1.lab 2001:db8::53"},
+		},
+		{child: "lab", nameservers: []string{"ns1.lab.example.com"}, err: "needs glue"},
+		{child: "lab", nameservers: []string{"ns1.example.net"}, glue: map[string][]net.IP{"ns1.example.net": {net.ParseIP("192.0.2.53")}}, err: "outside"},
+		{child: "lab.example.org.", nameservers: []string{"ns1.example.net"}, err: "not a subdomain"},
+		{child: "lab", err: "at least one nameserver"},
+		{child: "lab", nameservers: []string{"ns1..example.net"}, err: "invalid nameserver"},
+	}
+
+	for _, tt := range tests {
+		records, err := delegationRecords("example.com", tt.child, tt.nameservers, tt.glue)
+		if len(tt.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%v: expected an error containing %q, got %v", tt.child, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", tt.child, err)
+		}
+
+		var got []string
+		for _, rec := range records {
+			got = append(got, rec.Type+" "+rec.Name+" "+rec.Value)
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%v: expected %v, got %v", tt.child, tt.want, got)
+		}
+	}
+}
+
+func TestFake_DelegateSubzone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	glue := map[string][]net.IP{"ns1.lab.example.com": {net.ParseIP("192.0.2.53")}}
+
+	added, err := provider.DelegateSubzone(ctx, fakeZone, "lab", []string{"ns1.lab.example.com", "ns2.example.net"}, glue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 3 || len(server.Records(fakeZone)) != 5 {
+		t.Fatalf("expected 2 NS and a glue record, zone has %v", server.Records(fakeZone))
+	}
+
+	// Delegating again only adds what's missing
+	added, err = provider.DelegateSubzone(ctx, fakeZone, "lab", []string{"ns1.lab.example.com", "ns2.example.net", "ns3.example.net"}, glue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0] != (libdns.Record{Type: "NS", Name: "lab", Value: "ns3.example.net.", ID: added[0].ID}) {
+		t.Errorf("expected only the new nameserver to be added, got %v", added)
+	}
+}
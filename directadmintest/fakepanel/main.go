@@ -0,0 +1,57 @@
+// Command fakepanel serves the directadmintest fake DirectAdmin panel on a
+// fixed address, for tests that can't start it in process, such as the
+// container based integration tests.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func main() {
+	listen := flag.String("listen", ":2222", "address to listen on")
+	user := flag.String("user", "admin", "user to accept")
+	loginKey := flag.String("login-key", os.Getenv("FAKEPANEL_LOGIN_KEY"), "login key to accept, defaults to $FAKEPANEL_LOGIN_KEY")
+	zones := flag.String("zones", "", "comma separated zones to create on start")
+	plainHTTP := flag.Bool("http", false, "serve plain http instead of https with a self-signed certificate")
+	flag.Parse()
+
+	if len(*loginKey) == 0 {
+		log.Fatal("a login key is required")
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := directadmintest.NewUnstartedServer(*user, *loginKey)
+	server.Listener.Close()
+	server.Listener = listener
+
+	for _, zone := range strings.Split(*zones, ",") {
+		if zone = strings.TrimSpace(zone); len(zone) > 0 {
+			server.AddZone(zone)
+		}
+	}
+
+	if *plainHTTP {
+		server.Start()
+	} else {
+		server.StartTLS()
+	}
+	defer server.Close()
+
+	log.Printf("fake DirectAdmin panel listening on %v", listener.Addr())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+}
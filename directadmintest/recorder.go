@@ -0,0 +1,177 @@
+package directadmintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder records or replays API interactions.
+type Mode int
+
+const (
+	// ModeReplay answers requests from a fixture without network access
+	ModeReplay Mode = iota
+
+	// ModeRecord passes requests on and saves the interactions to a fixture
+	ModeRecord
+)
+
+// Interaction is a request and the response DirectAdmin sent for it, as
+// stored in a fixture. Credentials are never stored.
+type Interaction struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Query       string `json:"query"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records API interactions to a JSON
+// fixture, or replays them from one, so tests written against a real panel
+// can run deterministically without it. Set it as the provider's Transport.
+//
+// Replayed requests have to arrive in the recorded order.
+type Recorder struct {
+	path         string
+	mode         Mode
+	next         http.RoundTripper
+	replacements *strings.Replacer
+
+	mu           sync.Mutex
+	interactions []Interaction
+	pos          int
+}
+
+// NewRecorder creates a Recorder for the fixture at path. In ModeRecord,
+// requests are sent through next, or http.DefaultTransport if nil, and the
+// replacements, given as pairs of old and new strings, are applied to
+// everything stored, e.g. to replace the real zone and server names with
+// example ones. In ModeReplay the fixture has to exist.
+func NewRecorder(path string, mode Mode, next http.RoundTripper, replacements ...string) (*Recorder, error) {
+	if len(replacements)%2 != 0 {
+		return nil, errors.New("replacements must be pairs of old and new strings")
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path:         path,
+		mode:         mode,
+		next:         next,
+		replacements: strings.NewReplacer(replacements...),
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %v: %v", path, err)
+		}
+	}
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       r.replacements.Replace(req.URL.Query().Encode()),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        r.replacements.Replace(string(body)),
+	})
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	query := req.URL.Query().Encode()
+	if r.pos >= len(r.interactions) {
+		return nil, fmt.Errorf("fixture %v has no interaction left for %v %v?%v", r.path, req.Method, req.URL.Path, query)
+	}
+
+	want := r.interactions[r.pos]
+	if want.Method != req.Method || want.Path != req.URL.Path || want.Query != query {
+		return nil, fmt.Errorf("fixture %v expected %v %v?%v, got %v %v?%v", r.path, want.Method, want.Path, want.Query, req.Method, req.URL.Path, query)
+	}
+	r.pos++
+
+	header := make(http.Header)
+	if len(want.ContentType) > 0 {
+		header.Set("Content-Type", want.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", want.StatusCode, http.StatusText(want.StatusCode)),
+		StatusCode:    want.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(want.Body)),
+		ContentLength: int64(len(want.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the recorded interactions to the fixture. It does nothing in
+// ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,485 @@
+// Package directadmintest provides a fake DirectAdmin panel for tests.
+//
+// The fake implements the parts of the DirectAdmin API the directadmin
+// provider uses: CMD_API_SHOW_DOMAINS, CMD_API_DNS_CONTROL (reading a zone
+// and the add, edit, select and reset actions) and CMD_API_DOMAIN (creating and
+// deleting domains). It answers in DirectAdmin's JSON format, including its
+// error responses, so code using the provider can be tested without a panel.
+package directadmintest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultTTL is the TTL the fake reports for records added without one.
+const DefaultTTL = 3600
+
+// Record is a record as DirectAdmin stores it. Names are relative to the
+// zone, MX values hold the priority followed by the target.
+type Record struct {
+	Type  string
+	Name  string
+	Value string
+	TTL   int
+}
+
+// combined is DirectAdmin's identifier for the record.
+func (r Record) combined() string {
+	return fmt.Sprintf("name=%s&value=%s", r.Name, r.Value)
+}
+
+// Server is a fake DirectAdmin panel. Create it with NewServer and point the
+// provider's ServerURL at URL.
+type Server struct {
+	*httptest.Server
+
+	// User and LoginKey are the only credentials the fake accepts
+	User     string
+	LoginKey string
+
+	// FullMXRecords enables full MX records for every zone, so MX targets
+	// may point outside the zone. Set it before making requests.
+	FullMXRecords bool
+
+	mu         sync.Mutex
+	zones      map[string][]Record
+	subdomains map[string][]string
+	denied     map[string]bool
+	failures   map[string][]string
+	requests   map[string]int
+}
+
+// NewServer starts a fake panel accepting the given credentials. Close it
+// when done.
+func NewServer(user, loginKey string) *Server {
+	s := newServer(user, loginKey)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// NewTLSServer starts a fake panel serving https with a self-signed
+// certificate, which the provider accepts with InsecureRequests set.
+func NewTLSServer(user, loginKey string) *Server {
+	s := newServer(user, loginKey)
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// NewUnstartedServer returns a fake panel that isn't listening yet, so its
+// Listener can be replaced before calling Start or StartTLS, e.g. to serve
+// on a fixed address.
+func NewUnstartedServer(user, loginKey string) *Server {
+	s := newServer(user, loginKey)
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+func newServer(user, loginKey string) *Server {
+	return &Server{
+		User:       user,
+		LoginKey:   loginKey,
+		zones:      make(map[string][]Record),
+		subdomains: make(map[string][]string),
+		denied:     make(map[string]bool),
+		failures:   make(map[string][]string),
+		requests:   make(map[string]int),
+	}
+}
+
+// AddZone creates the zone with the given records, replacing it if it
+// exists.
+func (s *Server) AddZone(zone string, records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.zones[normalizeZone(zone)] = append([]Record(nil), records...)
+}
+
+// Records returns a copy of the zone's records, or nil if the zone doesn't
+// exist.
+func (s *Server) Records(zone string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, ok := s.zones[normalizeZone(zone)]
+	if !ok {
+		return nil
+	}
+
+	return append([]Record{}, records...)
+}
+
+// Subdomains returns the subdomains created in the domain.
+func (s *Server) Subdomains(domain string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.subdomains[normalizeZone(domain)]...)
+}
+
+// Deny makes the fake refuse the command, as DirectAdmin does when the login
+// key doesn't allow it.
+func (s *Server) Deny(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.denied[command] = true
+}
+
+// FailNext makes the next request for the command fail with the error
+// message. Calling it repeatedly queues several failures.
+func (s *Server) FailNext(command, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[command] = append(s.failures[command], message)
+}
+
+// RequestCount returns how many authenticated requests for the command the
+// fake has received.
+func (s *Server) RequestCount(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[command]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	user, key, ok := r.BasicAuth()
+	if !ok || user != s.User || key != s.LoginKey {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("<html><body>Please login</body></html>"))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "Unable to parse request", err.Error())
+		return
+	}
+
+	command := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[command]++
+
+	if s.denied[command] {
+		writeError(w, "You cannot execute that command", "The request was made with a login key that does not allow "+command)
+		return
+	}
+
+	if failures := s.failures[command]; len(failures) > 0 {
+		s.failures[command] = failures[1:]
+		writeError(w, failures[0], "")
+		return
+	}
+
+	switch command {
+	case "CMD_API_SHOW_DOMAINS":
+		s.showDomains(w)
+	case "CMD_API_DNS_CONTROL":
+		s.dnsControl(w, r.Form)
+	case "CMD_API_DOMAIN":
+		s.domain(w, r.Form)
+	case "CMD_API_SUBDOMAINS":
+		s.subdomain(w, r.Form)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) showDomains(w http.ResponseWriter) {
+	domains := make([]string, 0, len(s.zones))
+	for zone := range s.zones {
+		domains = append(domains, zone)
+	}
+	sort.Strings(domains)
+
+	writeJSON(w, domains)
+}
+
+func (s *Server) dnsControl(w http.ResponseWriter, form url.Values) {
+	zone := normalizeZone(form.Get("domain"))
+	records, ok := s.zones[zone]
+	if !ok {
+		writeError(w, "Cannot View That Domain", "You do not own that domain")
+		return
+	}
+
+	switch form.Get("action") {
+	case "":
+		s.writeZone(w, records)
+	case "add":
+		rec, msg := recordFromForm(form, s.FullMXRecords)
+		if len(msg) > 0 {
+			writeError(w, "Cannot Add Record", msg)
+			return
+		}
+
+		// Like DirectAdmin, adding a record that exists succeeds without
+		// adding it a second time
+		if i := indexOf(records, rec.combined()); i >= 0 {
+			records[i] = rec
+		} else {
+			s.zones[zone] = append(records, rec)
+		}
+		writeSuccess(w, "Record Added")
+	case "edit":
+		rec, msg := recordFromForm(form, s.FullMXRecords)
+		if len(msg) > 0 {
+			writeError(w, "Cannot Edit Record", msg)
+			return
+		}
+
+		id := form.Get(strings.ToLower(rec.Type) + "recs0")
+		i := indexOf(records, id)
+		switch {
+		case len(id) == 0:
+			s.zones[zone] = append(records, rec)
+		case i < 0:
+			writeError(w, "Cannot Edit Record", "Unable to find the record "+id)
+			return
+		default:
+			records[i] = rec
+		}
+		writeSuccess(w, "Record Edited")
+	case "select":
+		var remaining []Record
+		selected := selectedIDs(form)
+		for _, rec := range records {
+			if !selected[rec.combined()] {
+				remaining = append(remaining, rec)
+			}
+		}
+
+		s.zones[zone] = append([]Record{}, remaining...)
+		writeSuccess(w, "Records Deleted")
+	case "reset":
+		s.zones[zone] = templateRecords(zone)
+		writeSuccess(w, "Zone Reset")
+	default:
+		writeError(w, "Unknown action", form.Get("action"))
+	}
+}
+
+func (s *Server) writeZone(w http.ResponseWriter, records []Record) {
+	type daRecord struct {
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Value    string `json:"value"`
+		Combined string `json:"combined"`
+		TTL      string `json:"ttl,omitempty"`
+	}
+
+	resp := struct {
+		Records       []daRecord `json:"records"`
+		DNSTTL        string     `json:"dns_ttl"`
+		DefaultTTL    string     `json:"default_ttl"`
+		FullMXRecords string     `json:"full_mx_records"`
+	}{
+		Records:       make([]daRecord, 0, len(records)),
+		DNSTTL:        "yes",
+		DefaultTTL:    strconv.Itoa(DefaultTTL),
+		FullMXRecords: "no",
+	}
+	if s.FullMXRecords {
+		resp.FullMXRecords = "yes"
+	}
+
+	for _, rec := range records {
+		resp.Records = append(resp.Records, daRecord{
+			Type:     rec.Type,
+			Name:     rec.Name,
+			Value:    rec.Value,
+			Combined: rec.combined(),
+			TTL:      strconv.Itoa(rec.TTL),
+		})
+	}
+
+	writeJSON(w, resp)
+}
+
+func (s *Server) domain(w http.ResponseWriter, form url.Values) {
+	switch form.Get("action") {
+	case "create":
+		zone := normalizeZone(form.Get("domain"))
+		if len(zone) == 0 {
+			writeError(w, "Cannot Create Domain", "No domain given")
+			return
+		}
+		if _, ok := s.zones[zone]; ok {
+			writeError(w, "Cannot Create Domain", "That domain already exists")
+			return
+		}
+
+		// DirectAdmin fills a new zone from its template
+		s.zones[zone] = templateRecords(zone)
+		writeSuccess(w, "Domain Created")
+	case "select":
+		if form.Get("delete") != "yes" || form.Get("confirmed") != "yes" {
+			writeError(w, "Cannot Delete Domain", "Deletion not confirmed")
+			return
+		}
+
+		zone := normalizeZone(form.Get("select0"))
+		if _, ok := s.zones[zone]; !ok {
+			writeError(w, "Cannot Delete Domain", "You do not own that domain")
+			return
+		}
+
+		delete(s.zones, zone)
+		writeSuccess(w, "Domain Deleted")
+	default:
+		writeError(w, "Unknown action", form.Get("action"))
+	}
+}
+
+func (s *Server) subdomain(w http.ResponseWriter, form url.Values) {
+	zone := normalizeZone(form.Get("domain"))
+	if _, ok := s.zones[zone]; !ok {
+		writeError(w, "Cannot View That Domain", "You do not own that domain")
+		return
+	}
+
+	if len(form.Get("action")) == 0 {
+		writeJSON(w, append([]string{}, s.subdomains[zone]...))
+		return
+	}
+	if form.Get("action") != "create" {
+		writeError(w, "Unknown action", form.Get("action"))
+		return
+	}
+
+	subdomain := strings.ToLower(form.Get("subdomain"))
+	for _, existing := range s.subdomains[zone] {
+		if existing == subdomain {
+			writeError(w, "Cannot Create Subdomain", "That subdomain already exists")
+			return
+		}
+	}
+
+	// DirectAdmin points the subdomain at the domain's IP
+	s.subdomains[zone] = append(s.subdomains[zone], subdomain)
+	s.zones[zone] = append(s.zones[zone], Record{Type: "A", Name: subdomain, Value: "192.0.2.1", TTL: DefaultTTL})
+	writeSuccess(w, "Subdomain Created")
+}
+
+// templateRecords returns the records DirectAdmin creates a zone with.
+func templateRecords(zone string) []Record {
+	return []Record{
+		{Type: "SOA", Name: zone + ".", Value: fmt.Sprintf("ns1.%[1]s. hostmaster.%[1]s. 2024010101 3600 3600 1209600 86400", zone), TTL: DefaultTTL},
+		{Type: "NS", Name: zone + ".", Value: "ns1." + zone + ".", TTL: DefaultTTL},
+		{Type: "A", Name: zone + ".", Value: "192.0.2.1", TTL: DefaultTTL},
+	}
+}
+
+// recordFromForm reads the record of an add or edit action, returning a
+// message describing why DirectAdmin would reject it, if it would.
+func recordFromForm(form url.Values, fullMX bool) (Record, string) {
+	rec := Record{
+		Type:  strings.ToUpper(form.Get("type")),
+		Name:  form.Get("name"),
+		Value: form.Get("value"),
+		TTL:   DefaultTTL,
+	}
+
+	if len(rec.Name) == 0 {
+		return rec, "The name is required"
+	}
+	if len(rec.Value) == 0 {
+		return rec, "The value is required"
+	}
+
+	if ttl := form.Get("ttl"); len(ttl) > 0 {
+		n, err := strconv.Atoi(ttl)
+		if err != nil || n < 0 {
+			return rec, "The TTL value is invalid"
+		}
+		if n > 0 {
+			rec.TTL = n
+		}
+	}
+
+	switch rec.Type {
+	case "A":
+		if ip := net.ParseIP(rec.Value); ip == nil || ip.To4() == nil {
+			return rec, rec.Value + " is not a valid IPv4 address"
+		}
+	case "AAAA":
+		if ip := net.ParseIP(rec.Value); ip == nil || ip.To4() != nil {
+			return rec, rec.Value + " is not a valid IPv6 address"
+		}
+	case "MX":
+		// The provider's MX values carry the priority, DirectAdmin's
+		// full MX mode passes the target separately
+		if target := form.Get("mx_value"); len(target) > 0 {
+			if strings.HasSuffix(target, ".") && !fullMX {
+				return rec, "MX target " + target + " must be in the zone unless full MX records are enabled"
+			}
+			rec.Value += " " + target
+		}
+	case "CNAME", "NS", "PTR", "TXT", "SRV", "CAA", "TLSA", "DS", "URI", "SPF", "HTTPS", "SVCB":
+	default:
+		return rec, "Unsupported record type " + rec.Type
+	}
+
+	return rec, ""
+}
+
+// selectedIDs collects the record identifiers of a select action, passed as
+// <type>recs<n> parameters.
+func selectedIDs(form url.Values) map[string]bool {
+	selected := make(map[string]bool)
+	for key, values := range form {
+		if i := strings.Index(key, "recs"); i > 0 {
+			if _, err := strconv.Atoi(key[i+len("recs"):]); err == nil {
+				for _, v := range values {
+					selected[v] = true
+				}
+			}
+		}
+	}
+
+	return selected
+}
+
+func indexOf(records []Record, combined string) int {
+	for i, rec := range records {
+		if rec.combined() == combined {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+func writeSuccess(w http.ResponseWriter, message string) {
+	writeJSON(w, map[string]string{"success": message, "result": ""})
+}
+
+func writeError(w http.ResponseWriter, message, result string) {
+	writeJSON(w, map[string]string{"error": message, "result": result})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,209 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// DirectAdminDKIMSelector is the selector DirectAdmin publishes the DKIM
+// keys it generates for a domain under.
+const DirectAdminDKIMSelector = "x"
+
+// ErrNoDKIMKey is returned when the zone publishes no DKIM key for the
+// selector.
+var ErrNoDKIMKey = errors.New("no DKIM key found")
+
+// DKIMKey is a DKIM public key as published in a selector._domainkey TXT
+// record.
+type DKIMKey struct {
+	Selector string `json:"selector"`
+
+	// KeyType is the k= tag, rsa or ed25519
+	KeyType string `json:"key_type"`
+
+	// PublicKey is the base64 p= tag
+	PublicKey string `json:"public_key"`
+
+	TTL time.Duration `json:"ttl"`
+}
+
+// DKIMRecord returns the selector._domainkey TXT record publishing the
+// public key, split into strings of at most 255 characters as long RSA keys
+// require. publicKey may be PEM encoded or base64 DER, as printed by
+// `openssl rsa -pubout`; RSA and Ed25519 keys are supported.
+func DKIMRecord(selector, publicKey string) (libdns.Record, error) {
+	if len(selector) == 0 || strings.ContainsAny(selector, " ;") {
+		return libdns.Record{}, fmt.Errorf("invalid DKIM selector %q", selector)
+	}
+
+	keyType, p, err := dkimPublicKey(publicKey)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	return libdns.Record{
+		Type:  "TXT",
+		Name:  selector + "._domainkey",
+		Value: quoteTXT(fmt.Sprintf("v=DKIM1; k=%s; p=%s", keyType, p)),
+	}, nil
+}
+
+// dkimPublicKey returns the DKIM key type and p= tag of a PEM or base64
+// encoded public key.
+func dkimPublicKey(publicKey string) (string, string, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(publicKey)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(publicKey), ""))
+		if err != nil {
+			return "", "", fmt.Errorf("DKIM public key is neither PEM nor base64: %v", err)
+		}
+		der = decoded
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse DKIM public key: %v", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return "rsa", base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		// RFC 8463 publishes the bare key instead of the DER structure
+		return "ed25519", base64.StdEncoding.EncodeToString(key), nil
+	default:
+		return "", "", fmt.Errorf("unsupported DKIM key type %T", key)
+	}
+}
+
+// PublishDKIM publishes the public key under selector, replacing the key
+// the selector published before. To rotate keys without failing the
+// signatures of mail in transit, publish the new key under a new selector,
+// switch the mail server to it and remove the old selector with RemoveDKIM
+// once its mail has been delivered.
+func (p *Provider) PublishDKIM(ctx context.Context, zone, selector, publicKey string, ttl time.Duration) (_ libdns.Record, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "PublishDKIM", zone)
+	defer end(&err)
+
+	record, err := DKIMRecord(selector, publicKey)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+	record.TTL = ttl
+
+	set, err := p.SetRecords(ctx, zone, []libdns.Record{record})
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	return set[0], nil
+}
+
+// RemoveDKIM deletes the key published under selector.
+func (p *Provider) RemoveDKIM(ctx context.Context, zone, selector string) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "RemoveDKIM", zone)
+	defer end(&err)
+
+	records, err := p.dkimRecords(ctx, zone, selector)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w for selector %v", ErrNoDKIMKey, selector)
+	}
+
+	_, err = p.DeleteRecords(ctx, zone, records)
+	return err
+}
+
+// GetDKIMKey returns the key the zone publishes under selector. Use
+// DirectAdminDKIMSelector to read the key DirectAdmin generated when DKIM
+// was enabled for the domain. It returns ErrNoDKIMKey when there is none.
+func (p *Provider) GetDKIMKey(ctx context.Context, zone, selector string) (_ DKIMKey, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "GetDKIMKey", zone)
+	defer end(&err)
+
+	records, err := p.dkimRecords(ctx, zone, selector)
+	if err != nil {
+		return DKIMKey{}, err
+	}
+
+	for _, rec := range records {
+		key, ok := parseDKIM(unquoteTXT(rec.Value))
+		if !ok {
+			continue
+		}
+		key.Selector = selector
+		key.TTL = rec.TTL
+
+		return key, nil
+	}
+
+	return DKIMKey{}, fmt.Errorf("%w for selector %v", ErrNoDKIMKey, selector)
+}
+
+// dkimRecords returns the TXT records of the selector.
+func (p *Provider) dkimRecords(ctx context.Context, zone, selector string) ([]libdns.Record, error) {
+	records, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	name := selector + "._domainkey"
+
+	var matching []libdns.Record
+	for _, rec := range records {
+		if rec.Type == "TXT" && strings.EqualFold(relativeName(rec.Name, zone), name) {
+			matching = append(matching, rec)
+		}
+	}
+
+	return matching, nil
+}
+
+// parseDKIM parses the tags of a DKIM key record, e.g.
+// "v=DKIM1; k=rsa; p=MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA...".
+func parseDKIM(value string) (DKIMKey, bool) {
+	key := DKIMKey{KeyType: "rsa"}
+	found := false
+
+	for _, tag := range strings.Split(value, ";") {
+		name, tagValue, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok {
+			continue
+		}
+
+		tagValue = strings.Join(strings.Fields(tagValue), "")
+		switch strings.TrimSpace(name) {
+		case "v":
+			if tagValue != "DKIM1" {
+				return DKIMKey{}, false
+			}
+		case "k":
+			key.KeyType = tagValue
+		case "p":
+			key.PublicKey = tagValue
+			found = true
+		}
+	}
+
+	return key, found
+}
@@ -0,0 +1,126 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDKIMRecord(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	record, err := DKIMRecord("mail2024", rsaPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Type != "TXT" || record.Name != "mail2024._domainkey" {
+		t.Errorf("unexpected record %v %v", record.Type, record.Name)
+	}
+
+	strs := zoneFileFields(record.Value)
+	if len(strs) < 2 {
+		t.Errorf("expected a 2048 bit key to be split, got %v", record.Value)
+	}
+	for _, s := range strs {
+		if len(unquoteZoneString(s)) > maxTXTString {
+			t.Errorf("string longer than %d characters: %v", maxTXTString, s)
+		}
+	}
+
+	key, ok := parseDKIM(unquoteTXT(record.Value))
+	if !ok || key.KeyType != "rsa" || key.PublicKey != base64.StdEncoding.EncodeToString(der) {
+		t.Errorf("expected the record to round trip, got %+v", key)
+	}
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err = DKIMRecord("ed", base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := quoteTXT("v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(edKey)); record.Value != want {
+		t.Errorf("expected %v, got %v", want, record.Value)
+	}
+
+	if _, err := DKIMRecord("x", "not a key"); err == nil {
+		t.Error("expected an invalid key to fail")
+	}
+	if _, err := DKIMRecord("bad selector", rsaPEM); err == nil {
+		t.Error("expected an invalid selector to fail")
+	}
+}
+
+func TestFake_DKIM(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(der)
+
+	if _, err := provider.GetDKIMKey(ctx, fakeZone, "sel1"); !errors.Is(err, ErrNoDKIMKey) {
+		t.Fatalf("expected ErrNoDKIMKey, got %v", err)
+	}
+
+	if _, err := provider.PublishDKIM(ctx, fakeZone, "sel1", publicKey, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// Publishing again replaces the key
+	if _, err := provider.PublishDKIM(ctx, fakeZone, "sel1", publicKey, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	for _, rec := range server.Records(fakeZone) {
+		if strings.HasPrefix(rec.Name, "sel1._domainkey") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected one DKIM record, got %d", count)
+	}
+
+	key, err := provider.GetDKIMKey(ctx, fakeZone, "sel1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyType != "ed25519" || key.PublicKey != base64.StdEncoding.EncodeToString(edKey) || key.TTL != time.Hour {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	if err := provider.RemoveDKIM(ctx, fakeZone, "sel1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.GetDKIMKey(ctx, fakeZone, "sel1"); !errors.Is(err, ErrNoDKIMKey) {
+		t.Errorf("expected the key to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNSSEC actions understood by CMD_API_DNS_CONTROL
+const (
+	dnssecGenerateKeys = "generate_keys"
+	dnssecSignZone     = "sign_zone"
+	dnssecUnsignZone   = "unsign_zone"
+)
+
+// DNSSECKey is a DNSKEY published in a signed zone.
+type DNSSECKey struct {
+	Flags     uint16        `json:"flags"`
+	Protocol  uint8         `json:"protocol"`
+	Algorithm uint8         `json:"algorithm"`
+	PublicKey string        `json:"public_key"`
+	KeyTag    uint16        `json:"key_tag"`
+	TTL       time.Duration `json:"ttl"`
+}
+
+// IsKSK reports whether the key has the Secure Entry Point flag set, which
+// marks it as a key signing key.
+func (k DNSSECKey) IsKSK() bool {
+	return k.Flags&1 == 1
+}
+
+// GenerateDNSSECKeys asks DirectAdmin to generate a new set of signing keys
+// for the zone. The zone is not signed until SignZone or EnableDNSSEC is
+// called.
+func (p *Provider) GenerateDNSSECKeys(ctx context.Context, zone string) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "GenerateDNSSECKeys", zone)
+	defer end(&err)
+
+	return p.dnssecAction(ctx, zone, dnssecGenerateKeys)
+}
+
+// SignZone signs the zone with its existing DNSSEC keys.
+func (p *Provider) SignZone(ctx context.Context, zone string) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "SignZone", zone)
+	defer end(&err)
+
+	return p.dnssecAction(ctx, zone, dnssecSignZone)
+}
+
+// EnableDNSSEC generates keys for the zone when it has none yet and signs it.
+func (p *Provider) EnableDNSSEC(ctx context.Context, zone string) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "EnableDNSSEC", zone)
+	defer end(&err)
+
+	keys, err := p.ListDNSSECKeys(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		err = p.dnssecAction(ctx, zone, dnssecGenerateKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.dnssecAction(ctx, zone, dnssecSignZone)
+}
+
+// DisableDNSSEC stops signing the zone.
+func (p *Provider) DisableDNSSEC(ctx context.Context, zone string) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "DisableDNSSEC", zone)
+	defer end(&err)
+
+	return p.dnssecAction(ctx, zone, dnssecUnsignZone)
+}
+
+// ListDNSSECKeys returns the DNSKEY records published in the zone.
+func (p *Provider) ListDNSSECKeys(ctx context.Context, zone string) (_ []DNSSECKey, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "ListDNSSECKeys", zone)
+	defer end(&err)
+
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return respData.dnssecKeys(zone)
+}
+
+func (z daZone) dnssecKeys(zone string) ([]DNSSECKey, error) {
+	var keys []DNSSECKey
+	for _, rec := range z.Records {
+		if rec.Type != "DNSKEY" {
+			continue
+		}
+
+		key, err := parseDNSKEY(rec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DNSKEY for %v: %v", zone, err)
+		}
+
+		if len(rec.TTL) > 0 {
+			ttl, err := strconv.Atoi(rec.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse TTL for %v: %v", rec.Name, err)
+			}
+			key.TTL = time.Duration(ttl) * time.Second
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (p *Provider) dnssecAction(ctx context.Context, zone, action string) error {
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	queryString := make(url.Values)
+	queryString.Set("action", "dnssec")
+	queryString.Set("json", "yes")
+	queryString.Set("domain", zone)
+	queryString.Set("value", action)
+
+	return p.executeRequest(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	})
+}
+
+// parseDNSKEY parses the presentation format of DNSKEY record data, e.g.
+// "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0d...".
+func parseDNSKEY(value string) (DNSSECKey, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return DNSSECKey{}, fmt.Errorf("expected at least 4 fields, got %d", len(fields))
+	}
+
+	flags, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return DNSSECKey{}, fmt.Errorf("invalid flags: %v", err)
+	}
+	protocol, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil {
+		return DNSSECKey{}, fmt.Errorf("invalid protocol: %v", err)
+	}
+	algorithm, err := strconv.ParseUint(fields[2], 10, 8)
+	if err != nil {
+		return DNSSECKey{}, fmt.Errorf("invalid algorithm: %v", err)
+	}
+
+	key := DNSSECKey{
+		Flags:     uint16(flags),
+		Protocol:  uint8(protocol),
+		Algorithm: uint8(algorithm),
+		PublicKey: strings.Join(fields[3:], ""),
+	}
+
+	rdata, err := key.rdata()
+	if err != nil {
+		return DNSSECKey{}, err
+	}
+	key.KeyTag = keyTag(rdata)
+
+	return key, nil
+}
+
+// rdata returns the wire format of the DNSKEY record data.
+func (k DNSSECKey) rdata() ([]byte, error) {
+	pub, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+
+	rdata := make([]byte, 4, 4+len(pub))
+	rdata[0] = byte(k.Flags >> 8)
+	rdata[1] = byte(k.Flags)
+	rdata[2] = k.Protocol
+	rdata[3] = k.Algorithm
+
+	return append(rdata, pub...), nil
+}
+
+// keyTag computes the key tag of a DNSKEY as described in RFC 4034 Appendix B.
+func keyTag(rdata []byte) uint16 {
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 1 {
+			ac += uint32(b)
+		} else {
+			ac += uint32(b) << 8
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+
+	return uint16(ac & 0xFFFF)
+}
+
+// ErrDNSSECDisabled is returned when a DNSSEC operation requires a signed zone.
+var ErrDNSSECDisabled = errors.New("dnssec is not enabled for this zone")
+
+// DS digest types, see https://www.iana.org/assignments/ds-rr-types
+const (
+	DigestSHA256 uint8 = 2
+	DigestSHA384 uint8 = 4
+)
+
+// DSRecord is a delegation signer record that has to be published at the
+// registrar for the zone's chain of trust to be complete.
+type DSRecord struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digest_type"`
+	Digest     string `json:"digest"`
+}
+
+// String returns the DS record data in presentation format, which is what
+// most registrars expect to be pasted into their forms.
+func (ds DSRecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, ds.Digest)
+}
+
+// GetDSRecords returns the SHA-256 and SHA-384 DS records for every key
+// signing key of the zone. It returns ErrDNSSECDisabled when the zone is not
+// signed.
+func (p *Provider) GetDSRecords(ctx context.Context, zone string) (_ []DSRecord, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "GetDSRecords", zone)
+	defer end(&err)
+
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	if !daBool(respData.Dnssec) {
+		return nil, ErrDNSSECDisabled
+	}
+
+	keys, err := respData.dnssecKeys(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DSRecord
+	for _, key := range keys {
+		if !key.IsKSK() {
+			continue
+		}
+
+		for _, digestType := range []uint8{DigestSHA256, DigestSHA384} {
+			ds, err := key.ds(zone, digestType)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, ds)
+		}
+	}
+
+	return records, nil
+}
+
+// ds computes the DS record for the key as described in RFC 4034 Section 5.1.4.
+func (k DNSSECKey) ds(zone string, digestType uint8) (DSRecord, error) {
+	rdata, err := k.rdata()
+	if err != nil {
+		return DSRecord{}, err
+	}
+
+	var h hash.Hash
+	switch digestType {
+	case DigestSHA256:
+		h = sha256.New()
+	case DigestSHA384:
+		h = sha512.New384()
+	default:
+		return DSRecord{}, fmt.Errorf("unsupported digest type %d", digestType)
+	}
+
+	h.Write(canonicalName(zone))
+	h.Write(rdata)
+
+	return DSRecord{
+		KeyTag:     k.KeyTag,
+		Algorithm:  k.Algorithm,
+		DigestType: digestType,
+		Digest:     strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+	}, nil
+}
+
+// canonicalName returns the lowercase wire format of a domain name.
+func canonicalName(name string) []byte {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	var wire []byte
+	if len(name) > 0 {
+		for _, label := range strings.Split(name, ".") {
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+
+	return append(wire, 0)
+}
@@ -0,0 +1,96 @@
+package directadmin
+
+import (
+	"strings"
+	"testing"
+)
+
+// The example key from RFC 4509 Section 2.3
+const rfc4509Key = "256 3 5 AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw=="
+
+func TestParseDNSKEY(t *testing.T) {
+	var tests = []struct {
+		name    string
+		value   string
+		flags   uint16
+		alg     uint8
+		keyTag  uint16
+		wantErr bool
+	}{
+		{name: "rfc 4509", value: rfc4509Key, flags: 256, alg: 5, keyTag: 60485},
+		{name: "split key", value: rfc4509Key[:60] + " " + rfc4509Key[60:], flags: 256, alg: 5, keyTag: 60485},
+		{name: "missing key", value: "257 3 13", wantErr: true},
+		{name: "invalid flags", value: "KSK 3 13 AAAA", wantErr: true},
+		{name: "invalid key", value: "257 3 13 not-base64!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseDNSKEY(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Flags != tt.flags || key.Protocol != 3 || key.Algorithm != tt.alg {
+				t.Errorf("expected %d 3 %d, got %d %d %d", tt.flags, tt.alg, key.Flags, key.Protocol, key.Algorithm)
+			}
+			if key.KeyTag != tt.keyTag {
+				t.Errorf("expected key tag %d, got %d", tt.keyTag, key.KeyTag)
+			}
+		})
+	}
+}
+
+func TestDNSSECKey_DS(t *testing.T) {
+	key, err := parseDNSKEY(rfc4509Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name       string
+		zone       string
+		digestType uint8
+		want       string
+		wantErr    bool
+	}{
+		{name: "rfc 4509", zone: "dskey.example.com", digestType: DigestSHA256,
+			want: "60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"},
+		{name: "fqdn", zone: "dskey.example.com.", digestType: DigestSHA256,
+			want: "60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"},
+		{name: "case insensitive", zone: "DSKEY.Example.COM", digestType: DigestSHA256,
+			want: "60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"},
+		{name: "sha-1", zone: "dskey.example.com", digestType: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, err := key.ds(tt.zone, tt.digestType)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", ds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ds.String() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, ds)
+			}
+		})
+	}
+
+	ds, err := key.ds("dskey.example.com", DigestSHA384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ds.String(), "60485 5 4 ") || len(ds.Digest) != 96 {
+		t.Errorf("expected a SHA-384 digest, got %v", ds)
+	}
+}
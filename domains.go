@@ -0,0 +1,60 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+// listDomains returns the domains owned by the configured user, as reported
+// by CMD_API_SHOW_DOMAINS.
+func (p *Provider) listDomains(ctx context.Context) ([]string, error) {
+	body, err := p.showList(ctx, "CMD_API_SHOW_DOMAINS", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := daapi.ParseList("CMD_API_SHOW_DOMAINS", body)
+	if err != nil {
+		p.logger(ctx).Errorw("failed to decode domain list", "error", err)
+		return nil, err
+	}
+
+	return domains, nil
+}
+
+// showList makes a read-only request for a listing command and returns the
+// body of a successful response.
+func (p *Provider) showList(ctx context.Context, command string, params url.Values) ([]byte, error) {
+	callerSkipDepth := 4
+
+	queryString := make(url.Values)
+	for key, values := range params {
+		queryString[key] = values
+	}
+	queryString.Set("json", "yes")
+
+	resp, err := p.doRequest(ctx, &APIRequest{
+		Command: command,
+		Method:  http.MethodGet,
+		Params:  queryString,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := daapi.CheckResponse(command, resp); err != nil {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "error", err)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		p.logger(ctx).Errorw("api response error", "caller", p.caller(callerSkipDepth), "status_code", resp.StatusCode)
+		return nil, fmt.Errorf("api response error, status code: %v", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
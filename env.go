@@ -0,0 +1,71 @@
+package directadmin
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by NewFromEnv
+const (
+	EnvServerURL        = "DIRECTADMIN_SERVER_URL"
+	EnvUser             = "DIRECTADMIN_USER"
+	EnvLoginKey         = "DIRECTADMIN_LOGIN_KEY"
+	EnvLoginKeyFile     = "DIRECTADMIN_LOGIN_KEY_FILE"
+	EnvInsecureRequests = "DIRECTADMIN_INSECURE_REQUESTS"
+	EnvOperationTimeout = "DIRECTADMIN_OPERATION_TIMEOUT"
+	EnvDryRun           = "DIRECTADMIN_DRY_RUN"
+)
+
+// NewFromEnv creates a Provider from the DIRECTADMIN_SERVER_URL,
+// DIRECTADMIN_USER and DIRECTADMIN_LOGIN_KEY (or DIRECTADMIN_LOGIN_KEY_FILE)
+// environment variables.
+// DIRECTADMIN_INSECURE_REQUESTS and DIRECTADMIN_DRY_RUN take a boolean and
+// DIRECTADMIN_OPERATION_TIMEOUT a duration such as "45s". The options are
+// applied after the environment, so they take precedence.
+func NewFromEnv(opts ...Option) (*Provider, error) {
+	for _, key := range []string{EnvServerURL, EnvUser} {
+		if len(os.Getenv(key)) == 0 {
+			return nil, fmt.Errorf("%v is required", key)
+		}
+	}
+
+	var envOpts []Option
+
+	if path := os.Getenv(EnvLoginKeyFile); len(path) > 0 {
+		envOpts = append(envOpts, WithLoginKeyFile(path))
+	} else if len(os.Getenv(EnvLoginKey)) == 0 {
+		return nil, fmt.Errorf("%v or %v is required", EnvLoginKey, EnvLoginKeyFile)
+	}
+
+	if v, ok := os.LookupEnv(EnvInsecureRequests); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", EnvInsecureRequests, err)
+		}
+		if insecure {
+			envOpts = append(envOpts, WithInsecureRequests())
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvDryRun); ok {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", EnvDryRun, err)
+		}
+		if dryRun {
+			envOpts = append(envOpts, WithDryRun())
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvOperationTimeout); ok {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", EnvOperationTimeout, err)
+		}
+		envOpts = append(envOpts, WithOperationTimeout(timeout))
+	}
+
+	return New(os.Getenv(EnvServerURL), os.Getenv(EnvUser), os.Getenv(EnvLoginKey), append(envOpts, opts...)...)
+}
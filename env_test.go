@@ -0,0 +1,93 @@
+package directadmin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	server := directadmintest.NewServer("admin", "key")
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	t.Setenv(EnvServerURL, server.URL)
+	t.Setenv(EnvUser, "admin")
+	t.Setenv(EnvLoginKey, "key")
+	t.Setenv(EnvOperationTimeout, "45s")
+	t.Setenv(EnvDryRun, "true")
+
+	provider, err := NewFromEnv(WithAllowInsecureHTTP(), WithLogger(NopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.User != "admin" || provider.LoginKey != "key" || provider.OperationTimeout != 45*time.Second || !provider.DryRun {
+		t.Errorf("expected the environment to be applied, got %+v", provider)
+	}
+	if _, err := provider.GetRecords(context.Background(), fakeZone); err != nil {
+		t.Errorf("expected the provider to reach the panel, got %v", err)
+	}
+
+	// Options take precedence over the environment
+	provider, err = NewFromEnv(WithAllowInsecureHTTP(), WithOperationTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.OperationTimeout != time.Minute {
+		t.Errorf("expected the option to override the environment, got %v", provider.OperationTimeout)
+	}
+}
+
+func TestNewFromEnv_LoginKeyFile(t *testing.T) {
+	server := directadmintest.NewServer("admin", "file-key")
+	t.Cleanup(server.Close)
+	server.AddZone(fakeZone)
+
+	path := filepath.Join(t.TempDir(), "login-key")
+	if err := os.WriteFile(path, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvServerURL, server.URL)
+	t.Setenv(EnvUser, "admin")
+	t.Setenv(EnvLoginKeyFile, path)
+
+	provider, err := NewFromEnv(WithAllowInsecureHTTP(), WithLogger(NopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.GetRecords(context.Background(), fakeZone); err != nil {
+		t.Errorf("expected the key from the file to be used, got %v", err)
+	}
+}
+
+func TestNewFromEnv_Errors(t *testing.T) {
+	var tests = []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "missing user", env: map[string]string{EnvServerURL: "https://da.example.com", EnvLoginKey: "key"}},
+		{name: "missing key", env: map[string]string{EnvServerURL: "https://da.example.com", EnvUser: "admin"}},
+		{name: "invalid bool", env: map[string]string{EnvServerURL: "https://da.example.com", EnvUser: "admin", EnvLoginKey: "key", EnvDryRun: "maybe"}},
+		{name: "invalid duration", env: map[string]string{EnvServerURL: "https://da.example.com", EnvUser: "admin", EnvLoginKey: "key", EnvOperationTimeout: "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{EnvServerURL, EnvUser, EnvLoginKey, EnvLoginKeyFile, EnvInsecureRequests, EnvOperationTimeout, EnvDryRun} {
+				t.Setenv(key, tt.env[key])
+				if _, ok := tt.env[key]; !ok {
+					os.Unsetenv(key)
+				}
+			}
+
+			if _, err := NewFromEnv(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package directadmin
+
+import (
+	"fmt"
+
+	"github.com/libdns/directadmin/daapi"
+	"github.com/libdns/libdns"
+)
+
+// Errors DirectAdmin API failures are classified into. Use errors.Is to
+// check for them; the returned *APIError carries DirectAdmin's own message.
+var (
+	ErrDomainNotFound   = daapi.ErrDomainNotFound
+	ErrPermissionDenied = daapi.ErrPermissionDenied
+	ErrInvalidTTL       = daapi.ErrInvalidTTL
+	ErrDuplicateRecord  = daapi.ErrDuplicateRecord
+	ErrAuthFailed       = daapi.ErrAuthFailed
+	ErrRateLimited      = daapi.ErrRateLimited
+
+	// ErrIPBlacklisted means DirectAdmin's brute force protection blocked
+	// the client's IP address. Requests won't succeed, and retrying only
+	// prolongs the block, until the IP is removed from the blacklist or
+	// whitelisted in the panel.
+	ErrIPBlacklisted = daapi.ErrIPBlacklisted
+
+	// ErrDomainSuspended means the domain, or the user owning it, is
+	// suspended in DirectAdmin. Alert instead of retrying, requests fail
+	// until it's unsuspended.
+	ErrDomainSuspended = daapi.ErrDomainSuspended
+
+	// ErrQuotaExceeded means the server's limit on the records of a zone
+	// or a user was reached. Unlike transient failures, it persists until
+	// records are removed or the limit is raised.
+	ErrQuotaExceeded = daapi.ErrQuotaExceeded
+
+	// ErrZoneLocked means another process holds DirectAdmin's lock on the
+	// zone. Changes are retried for ZoneLockTimeout before it's returned.
+	ErrZoneLocked = daapi.ErrZoneLocked
+
+	// ErrServerMaintenance means DirectAdmin is updating itself or in
+	// maintenance mode. Defer and retry in a few minutes instead of
+	// treating it as a configuration error.
+	ErrServerMaintenance = daapi.ErrServerMaintenance
+
+	// ErrDemoMode means the server is a DirectAdmin demo installation,
+	// which reports changes as successful without saving them.
+	ErrDemoMode = daapi.ErrDemoMode
+
+	// ErrResponseTooLarge is returned for responses larger than
+	// MaxResponseBytes.
+	ErrResponseTooLarge = daapi.ErrResponseTooLarge
+)
+
+// APIError is returned when DirectAdmin reports that a command failed.
+type APIError = daapi.Error
+
+// BatchError is returned by AppendRecords, SetRecords and DeleteRecords when
+// a record of the batch fails. Applied lists the records that were changed
+// before the failure. Unless RolledBack is set they are still in effect and
+// are also returned by the method itself.
+type BatchError struct {
+	Applied []libdns.Record
+	Failed  libdns.Record
+	Err     error
+
+	// RolledBack is set when RollbackOnFailure restored the zone to its
+	// state before the batch. RollbackErr is set when that failed.
+	RolledBack  bool
+	RollbackErr error
+}
+
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("%v record %v failed after %d of the batch were applied", e.Failed.Type, e.Failed.Name, len(e.Applied))
+	switch {
+	case e.RolledBack:
+		msg += " and rolled back"
+	case e.RollbackErr != nil:
+		msg += fmt.Sprintf(" (rollback failed: %v)", e.RollbackErr)
+	}
+
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Atomic reports whether the batch failed before anything was changed, or
+// was rolled back, in which case the zone is as it was before the call.
+func (e *BatchError) Atomic() bool {
+	return len(e.Applied) == 0 || e.RolledBack
+}
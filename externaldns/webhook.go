@@ -0,0 +1,323 @@
+// Package externaldns serves the external-dns webhook provider API on top of
+// the directadmin provider, so external-dns can manage DirectAdmin zones.
+//
+// external-dns runs the webhook as a sidecar and talks to it on
+// localhost:8888; cmd/externaldns-webhook is a ready to use binary.
+package externaldns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/libdns"
+)
+
+// MediaType is the content type of the webhook API's requests and responses.
+const MediaType = "application/external.dns.webhook+json;version=1"
+
+// SupportedTypes are the record types the webhook reports and changes.
+var SupportedTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// Endpoint is external-dns's representation of the records of one name and
+// type.
+type Endpoint struct {
+	DNSName          string             `json:"dnsName"`
+	Targets          []string           `json:"targets"`
+	RecordType       string             `json:"recordType"`
+	SetIdentifier    string             `json:"setIdentifier,omitempty"`
+	RecordTTL        int64              `json:"recordTTL,omitempty"`
+	Labels           map[string]string  `json:"labels,omitempty"`
+	ProviderSpecific []ProviderSpecific `json:"providerSpecific,omitempty"`
+}
+
+// ProviderSpecific is an endpoint property only some providers understand.
+// The webhook ignores them.
+type ProviderSpecific struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Changes is the set of changes external-dns asks the webhook to apply.
+type Changes struct {
+	Create    []*Endpoint `json:"create"`
+	UpdateOld []*Endpoint `json:"updateOld"`
+	UpdateNew []*Endpoint `json:"updateNew"`
+	Delete    []*Endpoint `json:"delete"`
+}
+
+// DomainFilter tells external-dns which domains the webhook manages.
+type DomainFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Webhook is an http.Handler serving the webhook API.
+type Webhook struct {
+	Provider *directadmin.Provider
+
+	// Zones limits the webhook to these zones. If empty, all zones of the
+	// DirectAdmin user are managed.
+	Zones []string
+
+	// Timeout bounds the API calls for one request, 1 minute if 0
+	Timeout time.Duration
+}
+
+// ServeHTTP dispatches to the webhook API's endpoints.
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	timeout := wh.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		err = wh.negotiate(ctx, w)
+	case r.URL.Path == "/records" && r.Method == http.MethodGet:
+		err = wh.records(ctx, w)
+	case r.URL.Path == "/records" && r.Method == http.MethodPost:
+		err = wh.applyChanges(ctx, w, r)
+	case r.URL.Path == "/adjustendpoints" && r.Method == http.MethodPost:
+		err = wh.adjustEndpoints(w, r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (wh *Webhook) negotiate(ctx context.Context, w http.ResponseWriter) error {
+	zones, err := wh.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, DomainFilter{Include: zones})
+}
+
+func (wh *Webhook) records(ctx context.Context, w http.ResponseWriter) error {
+	zones, err := wh.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoints := []*Endpoint{}
+	for _, zone := range zones {
+		records, err := wh.Provider.GetRecords(ctx, zone)
+		if err != nil {
+			return fmt.Errorf("failed to get the records of %v: %w", zone, err)
+		}
+		endpoints = append(endpoints, toEndpoints(zone, records)...)
+	}
+
+	return writeJSON(w, endpoints)
+}
+
+func (wh *Webhook) applyChanges(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var changes Changes
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		http.Error(w, "invalid changes: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	zones, err := wh.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Deletes go first so an update replacing a CNAME by an A record, or
+	// the other way around, doesn't conflict with the old record
+	for _, step := range []struct {
+		endpoints []*Endpoint
+		apply     func(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error)
+	}{
+		{changes.Delete, wh.Provider.DeleteRecords},
+		{changes.UpdateOld, wh.Provider.DeleteRecords},
+		{changes.Create, wh.Provider.AppendRecords},
+		{changes.UpdateNew, wh.Provider.AppendRecords},
+	} {
+		for _, ep := range step.endpoints {
+			zone := zoneFor(ep.DNSName, zones)
+			if len(zone) == 0 {
+				return fmt.Errorf("%v is not in a managed zone", ep.DNSName)
+			}
+
+			records, err := toRecords(zone, ep)
+			if err != nil {
+				return err
+			}
+
+			if _, err := step.apply(ctx, zone, records); err != nil {
+				return fmt.Errorf("failed to change %v %v: %w", ep.RecordType, ep.DNSName, err)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// adjustEndpoints normalizes names and drops endpoints of record types the
+// webhook doesn't support, so external-dns doesn't keep planning changes
+// for them.
+func (wh *Webhook) adjustEndpoints(w http.ResponseWriter, r *http.Request) error {
+	var endpoints []*Endpoint
+	if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+		http.Error(w, "invalid endpoints: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	adjusted := []*Endpoint{}
+	for _, ep := range endpoints {
+		if !supported(ep.RecordType) {
+			continue
+		}
+		ep.DNSName = strings.ToLower(strings.TrimSuffix(ep.DNSName, "."))
+		adjusted = append(adjusted, ep)
+	}
+
+	return writeJSON(w, adjusted)
+}
+
+// zones returns the managed zones without trailing dots.
+func (wh *Webhook) zones(ctx context.Context) ([]string, error) {
+	if len(wh.Zones) > 0 {
+		zones := make([]string, 0, len(wh.Zones))
+		for _, zone := range wh.Zones {
+			zones = append(zones, strings.TrimSuffix(zone, "."))
+		}
+		return zones, nil
+	}
+
+	list, err := wh.Provider.ListZones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %w", err)
+	}
+
+	zones := make([]string, 0, len(list))
+	for _, zone := range list {
+		zones = append(zones, strings.TrimSuffix(zone.Name, "."))
+	}
+
+	return zones, nil
+}
+
+// zoneFor returns the longest zone containing name, or "" if none does.
+func zoneFor(name string, zones []string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	best := ""
+	for _, zone := range zones {
+		zone = strings.ToLower(zone)
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+			best = zone
+		}
+	}
+
+	return best
+}
+
+func supported(recordType string) bool {
+	for _, t := range SupportedTypes {
+		if t == recordType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toEndpoints groups the records of a zone by name and type.
+func toEndpoints(zone string, records []libdns.Record) []*Endpoint {
+	byKey := map[string]*Endpoint{}
+	var keys []string
+
+	for _, rec := range records {
+		if !supported(rec.Type) {
+			continue
+		}
+
+		name := strings.TrimSuffix(libdns.AbsoluteName(rec.Name, zone+"."), ".")
+
+		target := rec.Value
+		if rec.Type == "MX" {
+			target = fmt.Sprintf("%d %s", rec.Priority, rec.Value)
+		}
+
+		key := name + " " + rec.Type
+		ep, ok := byKey[key]
+		if !ok {
+			ep = &Endpoint{DNSName: name, RecordType: rec.Type, RecordTTL: int64(rec.TTL.Seconds())}
+			byKey[key] = ep
+			keys = append(keys, key)
+		}
+		ep.Targets = append(ep.Targets, target)
+	}
+
+	sort.Strings(keys)
+	endpoints := make([]*Endpoint, 0, len(keys))
+	for _, key := range keys {
+		endpoints = append(endpoints, byKey[key])
+	}
+
+	return endpoints
+}
+
+// toRecords converts an endpoint to one record per target.
+func toRecords(zone string, ep *Endpoint) ([]libdns.Record, error) {
+	if !supported(ep.RecordType) {
+		return nil, fmt.Errorf("%v: unsupported record type %v", ep.DNSName, ep.RecordType)
+	}
+
+	name := libdns.RelativeName(strings.ToLower(ep.DNSName), zone)
+	if len(name) == 0 {
+		name = "@"
+	}
+
+	records := make([]libdns.Record, 0, len(ep.Targets))
+	for _, target := range ep.Targets {
+		rec := libdns.Record{
+			Type:  ep.RecordType,
+			Name:  name,
+			Value: target,
+			TTL:   time.Duration(ep.RecordTTL) * time.Second,
+		}
+
+		if ep.RecordType == "MX" {
+			fields := strings.Fields(target)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%v: invalid MX target %q", ep.DNSName, target)
+			}
+			priority, err := strconv.ParseUint(fields[0], 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid MX target %q", ep.DNSName, target)
+			}
+			rec.Priority = uint(priority)
+			rec.Value = fields[1]
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", MediaType)
+	return json.NewEncoder(w).Encode(v)
+}
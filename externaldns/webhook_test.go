@@ -0,0 +1,92 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestWebhook(t *testing.T) {
+	server := directadmintest.NewTLSServer("admin", "key")
+	defer server.Close()
+	server.AddZone("example.com", directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300})
+
+	provider, err := directadmin.New(server.URL, "admin", "key", directadmin.WithInsecureRequests())
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook := &Webhook{Provider: provider}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		webhook.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do(http.MethodGet, "/", "")
+	if got, want := w.Body.String(), `{"include":["example.com"]}`+"\n"; got != want {
+		t.Errorf("expected domain filter %q, got %q", want, got)
+	}
+	if got := w.Header().Get("Content-Type"); got != MediaType {
+		t.Errorf("expected content type %q, got %q", MediaType, got)
+	}
+
+	w = do(http.MethodPost, "/records", `{
+		"Create": [{"dnsName": "api.example.com", "recordType": "A", "targets": ["192.0.2.2", "192.0.2.3"], "recordTTL": 60}],
+		"UpdateOld": [{"dnsName": "www.example.com", "recordType": "A", "targets": ["192.0.2.1"], "recordTTL": 300}],
+		"UpdateNew": [{"dnsName": "www.example.com", "recordType": "A", "targets": ["192.0.2.4"], "recordTTL": 300}]
+	}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %v", http.StatusNoContent, w.Code, w.Body)
+	}
+
+	w = do(http.MethodGet, "/records", "")
+	var endpoints []Endpoint
+	if err := json.Unmarshal(w.Body.Bytes(), &endpoints); err != nil {
+		t.Fatal(err)
+	}
+	want := []Endpoint{
+		{DNSName: "api.example.com", RecordType: "A", Targets: []string{"192.0.2.2", "192.0.2.3"}, RecordTTL: 60},
+		{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.4"}, RecordTTL: 300},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("expected %+v, got %+v", want, endpoints)
+	}
+
+	w = do(http.MethodPost, "/records", `{"Create": [{"dnsName": "www.example.org", "recordType": "A", "targets": ["192.0.2.1"]}]}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d for a name outside the zones, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	w = do(http.MethodPost, "/adjustendpoints", `[{"dnsName": "WWW.example.com.", "recordType": "A", "targets": ["192.0.2.1"]}, {"dnsName": "example.com", "recordType": "SRV", "targets": ["0 0 443 www"]}]`)
+	if got, want := w.Body.String(), `[{"dnsName":"www.example.com","targets":["192.0.2.1"],"recordType":"A"}]`+"\n"; got != want {
+		t.Errorf("expected adjusted endpoints %q, got %q", want, got)
+	}
+}
+
+func TestZoneFor(t *testing.T) {
+	var tests = []struct {
+		name string
+		want string
+	}{
+		{name: "example.com", want: "example.com"},
+		{name: "www.example.com.", want: "example.com"},
+		{name: "www.sub.example.com", want: "sub.example.com"},
+		{name: "badexample.com", want: ""},
+		{name: "example.org", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zoneFor(tt.name, []string{"example.com", "sub.example.com"}); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,737 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+const fakeZone = "example.com"
+
+func newFakeProvider(t testing.TB) (*Provider, *directadmintest.Server) {
+	t.Helper()
+
+	server := directadmintest.NewServer("admin", "key")
+	t.Cleanup(server.Close)
+
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+	)
+
+	provider := &Provider{
+		ServerURL:         server.URL,
+		User:              "admin",
+		LoginKey:          "key",
+		AllowInsecureHTTP: true,
+		Logger:            NewStdLogger(log.New(io.Discard, "", 0), false),
+	}
+
+	return provider, server
+}
+
+func TestFake_RecordLifecycle(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	records, err := provider.GetRecords(ctx, fakeZone+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	added, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: 60 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || len(server.Records(fakeZone)) != 3 {
+		t.Fatalf("expected the record to be added, zone has %v", server.Records(fakeZone))
+	}
+
+	_, err = provider.SetRecords(ctx, fakeZone, []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.DeleteRecords(ctx, fakeZone, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []directadmintest.Record{
+		{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300},
+	}
+	got := server.Records(fakeZone)
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], got[i])
+		}
+	}
+}
+
+func TestFake_Errors(t *testing.T) {
+	var tests = []struct {
+		name    string
+		setup   func(server *directadmintest.Server, provider *Provider)
+		records []libdns.Record
+		zone    string
+		wantErr error
+	}{
+		{
+			name:    "unknown zone",
+			zone:    "example.org",
+			records: []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}},
+			wantErr: ErrDomainNotFound,
+		},
+		{
+			name: "duplicate",
+			setup: func(server *directadmintest.Server, _ *Provider) {
+				server.FailNext("CMD_API_DNS_CONTROL", "Record already exists")
+			},
+			records: []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}},
+			wantErr: ErrDuplicateRecord,
+		},
+		{
+			name: "wrong key",
+			setup: func(_ *directadmintest.Server, provider *Provider) {
+				provider.LoginKey = "wrong"
+			},
+			records: []libdns.Record{{Type: "A", Name: "api", Value: "192.0.2.1"}},
+			wantErr: ErrAuthFailed,
+		},
+		{
+			name: "denied",
+			setup: func(server *directadmintest.Server, _ *Provider) {
+				server.Deny("CMD_API_DNS_CONTROL")
+			},
+			records: []libdns.Record{{Type: "A", Name: "api", Value: "192.0.2.1"}},
+			wantErr: ErrPermissionDenied,
+		},
+		{
+			name: "suspended",
+			setup: func(server *directadmintest.Server, _ *Provider) {
+				server.FailNext("CMD_API_DNS_CONTROL", "This domain is suspended")
+			},
+			records: []libdns.Record{{Type: "A", Name: "api", Value: "192.0.2.1"}},
+			wantErr: ErrDomainSuspended,
+		},
+		{
+			name: "quota",
+			setup: func(server *directadmintest.Server, _ *Provider) {
+				server.FailNext("CMD_API_DNS_CONTROL", "You have reached the maximum number of records")
+			},
+			records: []libdns.Record{{Type: "A", Name: "api", Value: "192.0.2.1"}},
+			wantErr: ErrQuotaExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, server := newFakeProvider(t)
+			if tt.setup != nil {
+				tt.setup(server, provider)
+			}
+
+			zone := tt.zone
+			if len(zone) == 0 {
+				zone = fakeZone
+			}
+
+			_, err := provider.AppendRecords(context.Background(), zone, tt.records)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFake_CheckCredentials(t *testing.T) {
+	provider, server := newFakeProvider(t)
+
+	if err := provider.CheckCredentials(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Deny("CMD_API_DNS_CONTROL")
+
+	err := provider.CheckCredentials(context.Background())
+
+	var permErr *PermissionError
+	if !errors.As(err, &permErr) {
+		t.Fatalf("expected a PermissionError, got %v", err)
+	}
+	if len(permErr.Missing) != 1 || permErr.Missing[0] != "CMD_API_DNS_CONTROL" {
+		t.Errorf("expected CMD_API_DNS_CONTROL to be missing, got %v", permErr.Missing)
+	}
+}
+
+func TestFake_DryRun(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	var buf bytes.Buffer
+	provider.Logger = NewStdLogger(log.New(&buf, "", 0), false)
+	provider.DryRun = true
+
+	var changes int
+	provider.OnChange = func(ChangeEvent) {
+		changes++
+	}
+
+	added, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}})
+	if err != nil || len(added) != 1 {
+		t.Fatalf("expected the record to be reported as added, got %v and %v", added, err)
+	}
+	set, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.2"}})
+	if err != nil || len(set) != 1 {
+		t.Fatalf("expected the record to be reported as set, got %v and %v", set, err)
+	}
+	deleted, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}})
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("expected the record to be reported as deleted, got %v and %v", deleted, err)
+	}
+
+	if !reflect.DeepEqual(server.Records(fakeZone), []directadmintest.Record{
+		{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+	}) {
+		t.Errorf("expected the zone to be unchanged, got %v", server.Records(fakeZone))
+	}
+	if changes != 0 {
+		t.Errorf("expected no change notifications, got %d", changes)
+	}
+	if n := strings.Count(buf.String(), "dry run"); n < 3 {
+		t.Errorf("expected every skipped call to be logged, got\n%s", buf.String())
+	}
+}
+
+// failAction makes the nth request with the action fail with the message.
+func failAction(server *directadmintest.Server, action string, n int, message string) http.RoundTripper {
+	var seen int
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("action") == action {
+			if seen++; seen == n {
+				server.FailNext("CMD_API_DNS_CONTROL", message)
+			}
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+}
+
+func TestFake_BatchErrors(t *testing.T) {
+	ctx := context.Background()
+	records := []libdns.Record{
+		{Type: "TXT", Name: "first", Value: "token"},
+		{Type: "TXT", Name: "second", Value: "token"},
+		{Type: "TXT", Name: "third", Value: "token"},
+	}
+
+	t.Run("append", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.Transport = failAction(server, "add", 2, "Cannot Add Record")
+
+		added, err := provider.AppendRecords(ctx, fakeZone, records)
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("expected a BatchError, got %v", err)
+		}
+		if len(added) != 1 || added[0].Name != "first" || !reflect.DeepEqual(batchErr.Applied, added) {
+			t.Errorf("expected the first record to be reported as added, got %v and %v", added, batchErr.Applied)
+		}
+		if batchErr.Failed.Name != "second" || batchErr.Atomic() {
+			t.Errorf("expected the second record to fail a partial batch, got %+v", batchErr)
+		}
+		if got := len(server.Records(fakeZone)); got != 3 {
+			t.Errorf("expected only the first record to be added, zone has %d records", got)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.Transport = failAction(server, "edit", 1, "Cannot Edit Record")
+
+		set, err := provider.SetRecords(ctx, fakeZone, records[:2])
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) || !batchErr.Atomic() || len(set) != 0 {
+			t.Errorf("expected an atomic BatchError for a failed first record, got %v, %v", set, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.Transport = failAction(server, "select", 3, "Cannot Delete Record")
+		if _, err := provider.AppendRecords(ctx, fakeZone, records); err != nil {
+			t.Fatal(err)
+		}
+
+		deleted, err := provider.DeleteRecords(ctx, fakeZone, records)
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) || batchErr.Failed.Name != "third" {
+			t.Fatalf("expected the third record to fail, got %v", err)
+		}
+		if len(deleted) != 2 || len(batchErr.Applied) != 2 {
+			t.Errorf("expected two records to be reported as deleted, got %v", deleted)
+		}
+	})
+}
+
+func TestFake_RollbackAfterCancel(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	provider.RollbackOnFailure = true
+
+	// The caller gives up once the first record was added
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if req.URL.Query().Get("action") == "add" {
+			cancel()
+		}
+		return resp, err
+	})
+
+	_, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{
+		{Type: "TXT", Name: "first", Value: "token"},
+		{Type: "TXT", Name: "second", Value: "token"},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a BatchError for the canceled context, got %v", err)
+	}
+	if !batchErr.RolledBack {
+		t.Errorf("expected the batch to be rolled back, got %v", batchErr)
+	}
+	if records := server.Records(fakeZone); len(records) != 2 {
+		t.Errorf("expected the zone to be restored, got %v", records)
+	}
+}
+
+func TestFake_RollbackOnFailure(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	provider.RollbackOnFailure = true
+	provider.Transport = failAction(server, "edit", 2, "Cannot Edit Record")
+
+	set, err := provider.SetRecords(context.Background(), fakeZone, []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300 * time.Second},
+		{Type: "TXT", Name: "new", Value: "token"},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !batchErr.RolledBack || !batchErr.Atomic() {
+		t.Fatalf("expected a rolled back BatchError, got %v", err)
+	}
+	if set != nil {
+		t.Errorf("expected no records to be reported as set, got %v", set)
+	}
+
+	want := []directadmintest.Record{
+		{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+	}
+	if got := server.Records(fakeZone); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the zone to be restored to %v, got %v", want, got)
+	}
+}
+
+func TestFake_SkipDuplicates(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	provider.SkipDuplicates = true
+
+	var adds int
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("action") == "add" {
+			adds++
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	added, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1"},
+		{Type: "TXT", Name: "new", Value: "token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if adds != 1 {
+		t.Errorf("expected only the new record to be added, got %d add requests", adds)
+	}
+	if len(added) != 2 || added[0].ID != "name=www&value=192.0.2.1" || added[0].TTL != 300*time.Second {
+		t.Errorf("expected the existing record in place of the duplicate, got %v", added)
+	}
+	if got := len(server.Records(fakeZone)); got != 3 {
+		t.Errorf("expected 3 records, got %d", got)
+	}
+}
+
+func TestFake_UpsertOnConflict(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	rec := libdns.Record{Type: "A", Name: "www", Value: "192.0.2.9", TTL: 300 * time.Second}
+
+	server.FailNext("CMD_API_DNS_CONTROL", "Cannot Add Record: a record with that name already exists")
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec}); !errors.Is(err, ErrDuplicateRecord) {
+		t.Fatalf("expected ErrDuplicateRecord without UpsertOnConflict, got %v", err)
+	}
+
+	provider.UpsertOnConflict = true
+	server.FailNext("CMD_API_DNS_CONTROL", "Cannot Add Record: a record with that name already exists")
+	added, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0].Value != rec.Value {
+		t.Errorf("expected the record to be returned, got %v", added)
+	}
+
+	want := []directadmintest.Record{
+		{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		{Type: "A", Name: "www", Value: "192.0.2.9", TTL: 300},
+	}
+	if got := server.Records(fakeZone); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the existing record to be replaced, got %v", got)
+	}
+}
+
+// hangingTransport blocks every request until its context ends.
+var hangingTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+})
+
+func TestFake_OperationTimeout(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+	provider.Transport = hangingTransport
+	provider.OperationTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := provider.AppendRecords(context.TODO(), fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the operation to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the operation to stop after OperationTimeout, took %v", elapsed)
+	}
+
+	var tests = []struct {
+		name    string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+		want    time.Duration
+	}{
+		{name: "default", want: defaultOperationTimeout},
+		{name: "configured", timeout: time.Minute, want: time.Minute},
+		{name: "disabled", timeout: -1},
+		{name: "caller's deadline", timeout: time.Minute, want: time.Hour, ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Hour)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			p := &Provider{OperationTimeout: tt.timeout}
+			ctx, cancelOp := p.withDefaultDeadline(ctx)
+			defer cancelOp()
+
+			deadline, ok := ctx.Deadline()
+			if ok != (tt.want > 0) {
+				t.Fatalf("expected a deadline: %v, got %v", tt.want > 0, ok)
+			}
+			if remaining := time.Until(deadline); ok && (remaining > tt.want || remaining < tt.want-time.Second) {
+				t.Errorf("expected a deadline in %v, got %v", tt.want, remaining)
+			}
+		})
+	}
+}
+
+func TestFake_CancelBetweenRecords(t *testing.T) {
+	provider, server := newFakeProvider(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var adds int
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if req.URL.Query().Get("action") == "add" {
+			adds++
+			cancel()
+		}
+		return resp, err
+	})
+
+	added, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{
+		{Type: "TXT", Name: "first", Value: "token"},
+		{Type: "TXT", Name: "second", Value: "token"},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, context.Canceled) || batchErr.Failed.Name != "second" {
+		t.Fatalf("expected the batch to stop at the second record, got %v", err)
+	}
+	if adds != 1 || len(added) != 1 || len(server.Records(fakeZone)) != 3 {
+		t.Errorf("expected only the first record to be added, got %d requests and %v", adds, added)
+	}
+}
+
+func TestFake_CancelDuringRetry(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": {"5"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := provider.GetRecords(ctx, fakeZone); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the retry wait to end with the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancellation to stop the retries promptly, took %v", elapsed)
+	}
+}
+
+func TestFake_MXRecords(t *testing.T) {
+	ctx := context.Background()
+
+	for _, fullMX := range []bool{false, true} {
+		provider, server := newFakeProvider(t)
+		server.FullMXRecords = fullMX
+
+		_, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{
+			{Type: "MX", Name: "@", Value: "mail.example.com", Priority: 10},
+		})
+		if err != nil {
+			t.Fatalf("full MX %v: %v", fullMX, err)
+		}
+
+		_, err = provider.AppendRecords(ctx, fakeZone, []libdns.Record{
+			{Type: "MX", Name: "@", Value: "mx.example.net", Priority: 20},
+		})
+		if fullMX && err != nil {
+			t.Fatalf("full MX %v: %v", fullMX, err)
+		}
+		if !fullMX && !errors.Is(err, ErrFullMXRequired) {
+			t.Fatalf("expected ErrFullMXRequired, got %v", err)
+		}
+
+		records, err := provider.GetRecords(ctx, fakeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, rec := range records {
+			if rec.Type == "MX" {
+				got = append(got, fmt.Sprintf("%d %s", rec.Priority, rec.Value))
+			}
+		}
+		want := []string{"10 mail.example.com"}
+		if fullMX {
+			want = append(want, "20 mx.example.net")
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("full MX %v: expected MX records %v, got %v", fullMX, want, got)
+		}
+	}
+}
+
+func TestFake_SortRecords(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.SortRecords = true
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "TXT", Name: "www", Value: "b", TTL: 300},
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300},
+		directadmintest.Record{Type: "TXT", Name: "www", Value: "a", TTL: 300},
+		directadmintest.Record{Type: "A", Name: "api", Value: "192.0.2.3", TTL: 300},
+		directadmintest.Record{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+	)
+
+	records, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, rec := range records {
+		got = append(got, rec.Name+" "+rec.Type+" "+rec.Value)
+	}
+	want := []string{"@ NS ns1.example.net.", "api A 192.0.2.3", "www A 192.0.2.2", "www TXT a", "www TXT b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFake_OnChange(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newFakeProvider(t)
+
+	var events []ChangeEvent
+	provider.OnChange = func(event ChangeEvent) {
+		events = append(events, event)
+	}
+
+	txt := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: 60 * time.Second}
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{txt}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300 * time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{txt}); err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []ChangeKind
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+		if event.Zone != fakeZone || event.User != "admin" || event.Time.IsZero() {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+	if want := []ChangeKind{RecordAdded, RecordModified, RecordDeleted}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("expected events %v, got %v", want, kinds)
+	}
+
+	if got := events[0].After; got.Value != "token" || got.Name != "_acme-challenge" {
+		t.Errorf("unexpected added record %+v", got)
+	}
+	if before, after := events[1].Before, events[1].After; before.Value != "192.0.2.1" || after.Value != "192.0.2.2" {
+		t.Errorf("unexpected modification from %+v to %+v", before, after)
+	}
+	if got := events[2].Before; got.Value != "token" {
+		t.Errorf("unexpected deleted record %+v", got)
+	}
+}
+
+func TestFake_OnChangeInternalRecords(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newFakeProvider(t)
+	provider.LeaseLock = true
+	provider.OwnerID = "test"
+
+	var events []ChangeEvent
+	provider.OnChange = func(event ChangeEvent) {
+		events = append(events, event)
+	}
+
+	txt := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: 60 * time.Second}
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{txt}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{txt}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Taking and releasing the lease and the ownership marker aren't
+	// reported
+	if len(events) != 2 || events[0].After.Name != "_acme-challenge" || events[1].Before.Name != "_acme-challenge" {
+		t.Errorf("expected only the TXT record's events, got %+v", events)
+	}
+}
+
+func TestFake_DefaultTTL(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.DefaultTTL = 10 * time.Minute
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "default", Value: "a"},
+		{Type: "TXT", Name: "explicit", Value: "b", TTL: time.Minute},
+	}
+	created, err := provider.AppendRecords(ctx, fakeZone, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created[0].TTL != 10*time.Minute {
+		t.Errorf("expected the default TTL to be returned, got %v", created[0].TTL)
+	}
+
+	ttls := map[string]int{}
+	for _, rec := range server.Records(fakeZone) {
+		ttls[rec.Name] = rec.TTL
+	}
+	if ttls["default"] != 600 || ttls["explicit"] != 60 {
+		t.Errorf("expected TTLs 600 and 60, got %v and %v", ttls["default"], ttls["explicit"])
+	}
+
+	// Records without a TTL match the zone once it has the default
+	plan, err := provider.SyncZone(ctx, fakeZone, append(records, libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 5 * time.Minute}), SyncOptions{Merge: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected no changes, got %+v", plan)
+	}
+}
+
+func TestFake_SetRecordsTTLOnly(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "TXT", Name: "www", Value: "a", TTL: 300},
+		directadmintest.Record{Type: "TXT", Name: "www", Value: "b", TTL: 300},
+	)
+
+	updated, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "www", Value: "b", TTL: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || updated[0].TTL != time.Hour {
+		t.Errorf("expected the record with the new TTL, got %v", updated)
+	}
+
+	got := map[string]int{}
+	for _, rec := range server.Records(fakeZone) {
+		got[rec.Value] = rec.TTL
+	}
+	if !reflect.DeepEqual(got, map[string]int{"a": 300, "b": 3600}) {
+		t.Errorf("expected only the TTL of b to change, got %v", got)
+	}
+
+	// Setting the record as it is only reads the zone
+	requests := server.RequestCount("CMD_API_DNS_CONTROL")
+	unchanged, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "www", Value: "a", TTL: 5 * time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := server.RequestCount("CMD_API_DNS_CONTROL") - requests; n != 1 {
+		t.Errorf("expected only the zone to be read, got %d requests", n)
+	}
+	if len(unchanged) != 1 || len(unchanged[0].ID) == 0 {
+		t.Errorf("expected the existing record, got %v", unchanged)
+	}
+}
@@ -0,0 +1,81 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ambiguousRetries is how often a change that failed without telling
+// whether DirectAdmin applied it is checked and sent again.
+const ambiguousRetries = 2
+
+// ambiguousFailure reports whether err, from sending a change, leaves open
+// whether DirectAdmin received and applied it, e.g. a timeout or a
+// connection dropped while waiting for the response. Changes that failed to
+// connect or were canceled by the caller aren't ambiguous.
+func ambiguousFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+
+	// The request isn't sent before the TLS handshake completed
+	if strings.Contains(err.Error(), "TLS handshake timeout") {
+		return false
+	}
+
+	var opErr *net.OpError
+	return !errors.As(err, &opErr) || opErr.Op != "dial"
+}
+
+// executeChange makes a request changing the zone like executeRequest.
+// When it fails ambiguously, the zone is read before the change is sent
+// again: if landed reports the change as applied, it isn't repeated, so a
+// lost response doesn't lead to duplicate records.
+func (p *Provider) executeChange(ctx context.Context, req *APIRequest, landed func(current []libdns.Record) bool) error {
+	err := p.executeRequest(ctx, req)
+	for attempt := 0; attempt < ambiguousRetries && ambiguousFailure(ctx, err); attempt++ {
+		current, readErr := p.getZoneRecords(ctx, req.Zone)
+		if readErr != nil {
+			p.logger(ctx).Warnw("failed to check whether the change was applied", "zone", req.Zone, "error", readErr)
+			return err
+		}
+
+		if landed(current) {
+			p.logger(ctx).Infow("change was applied despite the error", "command", req.Command, "action", req.Params.Get("action"), "zone", req.Zone, "error", err)
+			return nil
+		}
+
+		p.logger(ctx).Warnw("change wasn't applied, retrying", "command", req.Command, "action", req.Params.Get("action"), "zone", req.Zone, "attempt", attempt+1, "error", err)
+		err = p.executeRequest(ctx, req)
+	}
+
+	return err
+}
+
+// hasRecord reports whether the records include rec, identified by its ID
+// if it has one, or else by its name, type, value and priority.
+func hasRecord(zone string, records []libdns.Record, rec libdns.Record) bool {
+	key := verifyKey(zone, rec)
+	for _, cur := range records {
+		if (len(rec.ID) > 0 && cur.ID == rec.ID && cur.Type == rec.Type) || verifyKey(zone, cur) == key {
+			return true
+		}
+	}
+
+	return false
+}
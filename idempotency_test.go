@@ -0,0 +1,92 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+// lossyTransport fails the first add request, either after DirectAdmin
+// applied it or before sending it.
+type lossyTransport struct {
+	next   http.RoundTripper
+	landed bool
+	adds   int
+}
+
+func (t *lossyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("action") != "add" {
+		return t.next.RoundTrip(req)
+	}
+
+	t.adds++
+	if t.adds > 1 {
+		return t.next.RoundTrip(req)
+	}
+
+	if t.landed {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		_ = resp.Body.Close()
+	}
+
+	return nil, io.ErrUnexpectedEOF
+}
+
+func TestFake_AmbiguousFailure(t *testing.T) {
+	for _, landed := range []bool{true, false} {
+		provider, server := newFakeProvider(t)
+		transport := &lossyTransport{next: http.DefaultTransport, landed: landed}
+		provider.Transport = transport
+
+		rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+		if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{rec}); err != nil {
+			t.Fatalf("landed=%v: %v", landed, err)
+		}
+
+		want := 2
+		if landed {
+			want = 1
+		}
+		if transport.adds != want {
+			t.Errorf("landed=%v: expected %d add requests, got %d", landed, want, transport.adds)
+		}
+
+		count := 0
+		for _, r := range server.Records(fakeZone) {
+			if r.Type == "TXT" && r.Name == "_acme-challenge" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("landed=%v: expected the record once, got %d", landed, count)
+		}
+	}
+}
+
+func TestAmbiguousFailure(t *testing.T) {
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	for _, test := range []struct {
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{ctx, nil, false},
+		{ctx, errors.New("record exists"), false},
+		{ctx, io.ErrUnexpectedEOF, true},
+		{canceled, io.ErrUnexpectedEOF, false},
+	} {
+		if got := ambiguousFailure(test.ctx, test.err); got != test.want {
+			t.Errorf("ambiguousFailure(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
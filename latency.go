@@ -0,0 +1,95 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultSlowCallThreshold is how long an API call takes before it's logged
+// at info level when SlowCallThreshold is unset.
+const defaultSlowCallThreshold = 2 * time.Second
+
+// APICall describes a finished call to the DirectAdmin API. Retried calls
+// are reported once per attempt.
+type APICall struct {
+	// Command and Action are the DirectAdmin command and its action, if
+	// any, e.g. `CMD_API_DNS_CONTROL` and `add`
+	Command string
+	Action  string
+
+	// Zone is the zone the call was made for, if any
+	Zone string
+
+	// Duration is how long the call took, including reading the response
+	Duration time.Duration
+
+	// StatusCode is the HTTP status of the response, 0 if there was none
+	StatusCode int
+
+	// Outcome is `ok` for a 200 response, `status_<code>` for other
+	// statuses and `error` for calls that failed without a response
+	Outcome string
+
+	// Err is the error of a call that failed without a response
+	Err error
+}
+
+// slowCallThreshold returns the duration from which API calls are logged at
+// info level, zero if they never are.
+func (p *Provider) slowCallThreshold() time.Duration {
+	switch {
+	case p.SlowCallThreshold < 0:
+		return 0
+	case p.SlowCallThreshold == 0:
+		return defaultSlowCallThreshold
+	default:
+		return p.SlowCallThreshold
+	}
+}
+
+// latencyMiddleware logs the duration and outcome of every API call, at
+// debug level or at info level if it took longer than SlowCallThreshold,
+// and reports it to OnAPICall.
+func (p *Provider) latencyMiddleware(next Handler) Handler {
+	return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+
+		call := APICall{
+			Command:  req.Command,
+			Action:   req.Params.Get("action"),
+			Zone:     req.Zone,
+			Duration: time.Since(start),
+			Outcome:  "error",
+			Err:      err,
+		}
+		if err == nil {
+			call.StatusCode = resp.StatusCode
+			call.Outcome = "ok"
+			if resp.StatusCode != http.StatusOK {
+				call.Outcome = fmt.Sprintf("status_%d", resp.StatusCode)
+			}
+		}
+
+		keysAndValues := []interface{}{
+			"command", call.Command,
+			"action", call.Action,
+			"zone", call.Zone,
+			"duration", call.Duration,
+			"outcome", call.Outcome,
+		}
+		if threshold := p.slowCallThreshold(); threshold > 0 && call.Duration >= threshold {
+			p.logger(ctx).Infow("slow api call", keysAndValues...)
+		} else {
+			p.logger(ctx).Debugw("api call", keysAndValues...)
+		}
+
+		if p.OnAPICall != nil {
+			p.OnAPICall(call)
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,47 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_OnAPICall(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newFakeProvider(t)
+
+	var mutex sync.Mutex
+	var calls []APICall
+	provider.OnAPICall = func(call APICall) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, call)
+	}
+
+	var buf bytes.Buffer
+	provider.Logger = NewStdLogger(log.New(&buf, "", 0), false)
+	provider.SlowCallThreshold = time.Nanosecond
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) == 0 {
+		t.Fatal("expected the API calls to be reported")
+	}
+	last := calls[len(calls)-1]
+	if last.Command != "CMD_API_DNS_CONTROL" || last.Action != "add" || last.Zone != fakeZone || last.Outcome != "ok" || last.Duration <= 0 {
+		t.Errorf("unexpected call %+v", last)
+	}
+
+	if !strings.Contains(buf.String(), "INFO slow api call") || !strings.Contains(buf.String(), "command=CMD_API_DNS_CONTROL action=add zone=example.com") {
+		t.Errorf("expected the call to be logged as slow, got %q", buf.String())
+	}
+}
@@ -0,0 +1,234 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// LeaseRecordName is the name of the TXT record holding a zone's lease, see
+// Provider.LeaseLock.
+const LeaseRecordName = "_libdns-lease"
+
+// defaultLeaseTTL is how long a lease is valid when LeaseTTL is unset.
+const defaultLeaseTTL = time.Minute
+
+// leaseRetryInterval is how long to wait, plus up to as much jitter, before
+// trying again to take a lease held by someone else.
+var leaseRetryInterval = time.Second
+
+// ErrLeaseHeld is returned when the lease on a zone couldn't be taken
+// before the context was done, because another instance held it.
+var ErrLeaseHeld = errors.New("zone lease held by another instance")
+
+// lease is a lease record read from the zone.
+type lease struct {
+	record  libdns.Record
+	token   string
+	expires time.Time
+}
+
+// leaseKey marks the zones a context holds the lease on.
+type leaseKey struct{ zone string }
+
+// withLease wraps a function changing a zone so it runs while holding the
+// zone's lease, if LeaseLock is set. Nested calls with a context already
+// holding the lease don't take it again.
+func (p *Provider) withLease(fn func(context.Context, string, []libdns.Record) ([]libdns.Record, error)) func(context.Context, string, []libdns.Record) ([]libdns.Record, error) {
+	return func(ctx context.Context, zone string, records []libdns.Record) (_ []libdns.Record, err error) {
+		if !p.LeaseLock || p.DryRun || ctx.Value(leaseKey{zone}) != nil {
+			return fn(ctx, zone, records)
+		}
+
+		token, err := p.acquireLease(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		defer p.releaseLease(ctx, zone, token)
+
+		return fn(context.WithValue(ctx, leaseKey{zone}, token), zone, records)
+	}
+}
+
+// leased runs fn while holding the zone's lease, like withLease, for
+// changes that don't take a list of records.
+func (p *Provider) leased(ctx context.Context, zone string, fn func(context.Context) error) error {
+	_, err := p.withLease(func(ctx context.Context, zone string, _ []libdns.Record) ([]libdns.Record, error) {
+		return nil, fn(ctx)
+	})(ctx, zone, nil)
+
+	return err
+}
+
+// acquireLease adds a lease record to the zone once no other unexpired
+// lease is left, and returns its token. Instances adding their lease at
+// the same time all see each other's records and back off.
+func (p *Provider) acquireLease(ctx context.Context, zone string) (string, error) {
+	ctx, cancel := p.withDefaultDeadline(ctx)
+	defer cancel()
+
+	token, err := leaseToken()
+	if err != nil {
+		return "", err
+	}
+
+	ttl := p.LeaseTTL
+	if ttl <= 0 {
+		ttl = defaultLeaseTTL
+	}
+
+	var holder *lease
+	heldErr := func() error {
+		return fmt.Errorf("%w: %v until %v: %v", ErrLeaseHeld, zone, holder.expires.Format(time.RFC3339), ctx.Err())
+	}
+
+	for {
+		others, expired, err := p.leases(ctx, zone, token)
+		if err != nil && holder != nil && ctx.Err() != nil {
+			// The wait ran out while checking the lease again
+			return "", heldErr()
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read the lease of %v: %w", zone, err)
+		}
+
+		for _, l := range expired {
+			p.logger(ctx).Infow("removing expired lease", "zone", zone, "token", l.token, "expired", l.expires)
+			if _, err := p.deleteZoneRecord(ctx, zone, l.record); err != nil {
+				p.logger(ctx).Warnw("failed to remove expired lease", "zone", zone, "error", err)
+			}
+		}
+
+		if len(others) == 0 {
+			value := fmt.Sprintf("token=%v expires=%d", token, time.Now().Add(ttl).Unix())
+			record := libdns.Record{Type: "TXT", Name: LeaseRecordName, Value: value, TTL: ttl}
+			if _, err := p.appendZoneRecord(ctx, zone, record); err != nil {
+				return "", fmt.Errorf("failed to take the lease of %v: %w", zone, err)
+			}
+
+			others, _, err = p.leases(ctx, zone, token)
+			if err != nil {
+				p.releaseLease(ctx, zone, token)
+				return "", fmt.Errorf("failed to read the lease of %v: %w", zone, err)
+			}
+			if len(others) == 0 {
+				p.logger(ctx).Debugw("lease taken", "zone", zone, "token", token)
+				return token, nil
+			}
+
+			// Someone else added a lease at the same time
+			p.releaseLease(ctx, zone, token)
+		}
+
+		holder = &others[0]
+		p.logger(ctx).Debugw("zone lease held, waiting", "zone", zone, "holder", holder.token, "expires", holder.expires)
+
+		wait := leaseRetryInterval + time.Duration(jitter.Int63n(int64(leaseRetryInterval)+1))
+		select {
+		case <-ctx.Done():
+			return "", heldErr()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// releaseLease removes the lease record with the token from the zone. It
+// is attempted even when ctx is done, an unreleased lease blocks the zone
+// until it expires.
+func (p *Provider) releaseLease(ctx context.Context, zone, token string) {
+	releaseCtx, cancel := p.detached(ctx, zone)
+	defer cancel()
+
+	records, err := p.getZoneRecords(releaseCtx, zone)
+	if err == nil {
+		for _, record := range records {
+			if l, ok := parseLease(record); ok && l.token == token {
+				_, err = p.deleteZoneRecord(releaseCtx, zone, record)
+			}
+		}
+	}
+	if err != nil {
+		p.logger(ctx).Warnw("failed to release zone lease", "zone", zone, "token", token, "error", err)
+	}
+}
+
+// leases returns the unexpired leases in the zone other than the one with
+// the token, and the expired ones.
+func (p *Provider) leases(ctx context.Context, zone, token string) (others, expired []lease, err error) {
+	records, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		l, ok := parseLease(record)
+		switch {
+		case !ok || l.token == token:
+		case now.After(l.expires):
+			expired = append(expired, l)
+		default:
+			others = append(others, l)
+		}
+	}
+
+	return others, expired, nil
+}
+
+// parseLease reports whether the record is a lease and returns it. Lease
+// records that can't be parsed expire immediately.
+func parseLease(record libdns.Record) (lease, bool) {
+	if record.Type != "TXT" || record.Name != LeaseRecordName {
+		return lease{}, false
+	}
+
+	l := lease{record: record}
+	for _, field := range strings.Fields(unquoteTXT(record.Value)) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "token":
+			l.token = value
+		case "expires":
+			if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+				l.expires = time.Unix(unix, 0)
+			}
+		}
+	}
+
+	return l, true
+}
+
+// isLeaseRecord reports whether the record is a lease, which GetRecords
+// leaves out and SyncZone and RestoreZone never change.
+func isLeaseRecord(record libdns.Record) bool {
+	_, ok := parseLease(record)
+	return ok
+}
+
+// withoutLeases returns the records that aren't leases.
+func withoutLeases(records []libdns.Record) []libdns.Record {
+	filtered := records[:0:0]
+	for _, record := range records {
+		if !isLeaseRecord(record) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered
+}
+
+func leaseToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease token: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,193 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestLeaseLock(t *testing.T) {
+	interval := leaseRetryInterval
+	leaseRetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { leaseRetryInterval = interval })
+
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: time.Minute}
+	leaseRecord := func(token string, expires time.Time) directadmintest.Record {
+		return directadmintest.Record{Type: "TXT", Name: LeaseRecordName, Value: fmt.Sprintf("token=%v expires=%d", token, expires.Unix()), TTL: 60}
+	}
+	hasLease := func(server *directadmintest.Server) bool {
+		for _, r := range server.Records(fakeZone) {
+			if r.Name == LeaseRecordName {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("released", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.LeaseLock = true
+
+		if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{record}); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.Records(fakeZone)) != 3 || hasLease(server) {
+			t.Errorf("expected the record to be added and the lease released, zone has %v", server.Records(fakeZone))
+		}
+	})
+
+	t.Run("held", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.LeaseLock = true
+		server.AddZone(fakeZone, leaseRecord("other", time.Now().Add(time.Hour)))
+
+		records, err := provider.GetRecords(context.Background(), fakeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 0 {
+			t.Errorf("expected the lease to be hidden, got %v", records)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = provider.AppendRecords(ctx, fakeZone, []libdns.Record{record})
+		if !errors.Is(err, ErrLeaseHeld) {
+			t.Fatalf("expected ErrLeaseHeld, got %v", err)
+		}
+		if len(server.Records(fakeZone)) != 1 {
+			t.Errorf("expected the zone to be unchanged, got %v", server.Records(fakeZone))
+		}
+	})
+
+	t.Run("expires while re-reading", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.LeaseLock = true
+		server.AddZone(fakeZone, leaseRecord("other", time.Now().Add(time.Hour)))
+
+		// The first read sees the lease, the next one hangs until the
+		// deadline passes
+		var reads int32
+		provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/CMD_API_DNS_CONTROL" && len(req.URL.Query().Get("action")) == 0 && atomic.AddInt32(&reads, 1) > 1 {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{record})
+		if !errors.Is(err, ErrLeaseHeld) {
+			t.Fatalf("expected ErrLeaseHeld, got %v", err)
+		}
+		if atomic.LoadInt32(&reads) < 2 {
+			t.Errorf("expected the lease to be read again, got %d reads", reads)
+		}
+		if len(server.Records(fakeZone)) != 1 {
+			t.Errorf("expected the zone to be unchanged, got %v", server.Records(fakeZone))
+		}
+	})
+
+	t.Run("sync and restore", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		other := leaseRecord("other", time.Now().Add(time.Hour))
+		server.AddZone(fakeZone, directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300}, other)
+
+		snapshot, err := provider.SnapshotZone(context.Background(), fakeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshot.Records) != 1 {
+			t.Errorf("expected the lease to be left out of the snapshot, got %v", snapshot.Records)
+		}
+
+		// Without LeaseLock the other instance's lease is left alone
+		plan, err := provider.SyncZone(context.Background(), fakeZone, nil, SyncOptions{Apply: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Delete) != 1 || !reflect.DeepEqual(server.Records(fakeZone), []directadmintest.Record{other}) {
+			t.Errorf("expected only the A record to be deleted, got %+v and zone %v", plan, server.Records(fakeZone))
+		}
+		if _, err := provider.RestoreZone(context.Background(), fakeZone, snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.Records(fakeZone)) != 2 || !hasLease(server) {
+			t.Errorf("expected the A record to be restored next to the lease, zone has %v", server.Records(fakeZone))
+		}
+
+		// With it, both wait for the lease
+		provider.LeaseLock = true
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := provider.SyncZone(ctx, fakeZone, nil, SyncOptions{Apply: true}); !errors.Is(err, ErrLeaseHeld) {
+			t.Errorf("expected SyncZone to wait for the lease, got %v", err)
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := provider.RestoreZone(ctx, fakeZone, snapshot); !errors.Is(err, ErrLeaseHeld) {
+			t.Errorf("expected RestoreZone to wait for the lease, got %v", err)
+		}
+
+		// Planning doesn't need the lease
+		if _, err := provider.SyncZone(context.Background(), fakeZone, nil, SyncOptions{}); err != nil {
+			t.Errorf("expected a plan without taking the lease, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		provider, server := newFakeProvider(t)
+		provider.LeaseLock = true
+		server.AddZone(fakeZone, leaseRecord("other", time.Now().Add(-time.Minute)))
+
+		if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{record}); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.Records(fakeZone)) != 1 || hasLease(server) {
+			t.Errorf("expected the expired lease to be replaced by the record, zone has %v", server.Records(fakeZone))
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		first, server := newFakeProvider(t)
+		first.LeaseLock = true
+		second := &Provider{ServerURL: first.ServerURL, User: "admin", LoginKey: "key", AllowInsecureHTTP: true, Logger: first.Logger, LeaseLock: true}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 4)
+		for i, p := range []*Provider{first, second, first, second} {
+			wg.Add(1)
+			go func(i int, p *Provider) {
+				defer wg.Done()
+				_, err := p.AppendRecords(context.Background(), fakeZone, []libdns.Record{
+					{Type: "TXT", Name: fmt.Sprintf("instance%d", i), Value: "token", TTL: time.Minute},
+				})
+				errs <- err
+			}(i, p)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(server.Records(fakeZone)) != 6 || hasLease(server) {
+			t.Errorf("expected every record to be added and no lease left, zone has %v", server.Records(fakeZone))
+		}
+	})
+}
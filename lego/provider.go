@@ -0,0 +1,123 @@
+// Package lego adapts the directadmin provider to lego's challenge.Provider,
+// for Traefik and other tools built on github.com/go-acme/lego.
+//
+// It lives in its own module so lego's dependencies don't end up in programs
+// only using the provider.
+package lego
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/libdns/directadmin"
+	"github.com/libdns/libdns"
+)
+
+var _ challenge.ProviderTimeout = (*DNSProvider)(nil)
+
+// DNSProvider presents DNS-01 challenges as TXT records on a DirectAdmin
+// panel.
+type DNSProvider struct {
+	Provider *directadmin.Provider
+
+	// TTL of the challenge records, the zone's default if 0
+	TTL time.Duration
+
+	// PropagationTimeout and PollingInterval are returned by Timeout, lego's
+	// defaults if 0
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+
+	// FindZone returns the zone of a challenge's FQDN,
+	// dns01.FindZoneByFqdn if nil
+	FindZone func(fqdn string) (string, error)
+}
+
+// NewDNSProvider returns a DNSProvider configured from the DIRECTADMIN_*
+// environment variables, see directadmin.NewFromEnv.
+func NewDNSProvider(opts ...directadmin.Option) (*DNSProvider, error) {
+	provider, err := directadmin.NewFromEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("directadmin: %w", err)
+	}
+
+	return &DNSProvider{Provider: provider}, nil
+}
+
+// Present adds the TXT record for the challenge.
+func (d *DNSProvider) Present(domain, _, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.propagationTimeout())
+	defer cancel()
+
+	zone, rec, err := d.record(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.Provider.AppendRecords(ctx, zone, []libdns.Record{rec}); err != nil {
+		return fmt.Errorf("directadmin: %w", err)
+	}
+
+	return nil
+}
+
+// CleanUp deletes the TXT record for the challenge.
+func (d *DNSProvider) CleanUp(domain, _, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.propagationTimeout())
+	defer cancel()
+
+	zone, rec, err := d.record(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.Provider.DeleteRecords(ctx, zone, []libdns.Record{rec}); err != nil {
+		return fmt.Errorf("directadmin: %w", err)
+	}
+
+	return nil
+}
+
+// Timeout returns how long lego waits for the record to propagate and how
+// often it checks.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	interval = d.PollingInterval
+	if interval == 0 {
+		interval = dns01.DefaultPollingInterval
+	}
+
+	return d.propagationTimeout(), interval
+}
+
+func (d *DNSProvider) propagationTimeout() time.Duration {
+	if d.PropagationTimeout == 0 {
+		return dns01.DefaultPropagationTimeout
+	}
+
+	return d.PropagationTimeout
+}
+
+// record returns the zone and the TXT record for a challenge.
+func (d *DNSProvider) record(domain, keyAuth string) (string, libdns.Record, error) {
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	findZone := d.FindZone
+	if findZone == nil {
+		findZone = dns01.FindZoneByFqdn
+	}
+
+	zone, err := findZone(info.EffectiveFQDN)
+	if err != nil {
+		return "", libdns.Record{}, fmt.Errorf("directadmin: could not find the zone of %v: %w", info.EffectiveFQDN, err)
+	}
+
+	return dns01.UnFqdn(zone), libdns.Record{
+		Type:  "TXT",
+		Name:  libdns.RelativeName(info.EffectiveFQDN, dns01.ToFqdn(zone)),
+		Value: info.Value,
+		TTL:   d.TTL,
+	}, nil
+}
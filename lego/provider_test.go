@@ -0,0 +1,44 @@
+package lego
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/libdns/directadmin"
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestDNSProvider(t *testing.T) {
+	server := directadmintest.NewTLSServer("admin", "key")
+	defer server.Close()
+	server.AddZone("example.com")
+
+	provider, err := directadmin.New(server.URL, "admin", "key", directadmin.WithInsecureRequests())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+	d := &DNSProvider{
+		Provider: provider,
+		FindZone: func(string) (string, error) { return "example.com.", nil },
+	}
+
+	if err := d.Present("www.example.com", "token", "keyAuth"); err != nil {
+		t.Fatal(err)
+	}
+
+	info := dns01.GetChallengeInfo("www.example.com", "keyAuth")
+	want := []directadmintest.Record{{Type: "TXT", Name: "_acme-challenge.www", Value: info.Value, TTL: directadmintest.DefaultTTL}}
+	if got := server.Records("example.com"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if err := d.CleanUp("www.example.com", "token", "keyAuth"); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Records("example.com"); len(got) != 0 {
+		t.Fatalf("expected no records, got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package directadmin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Logger is the structured logger the provider reports problems to. Each
+// method takes a message followed by alternating keys and values.
+//
+// A *zap.SugaredLogger satisfies Logger as is; use NewStdLogger to adapt a
+// standard library *log.Logger, or NewSlogLogger to adapt a *slog.Logger.
+type Logger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewStdLogger adapts a standard library logger. Debug messages are only
+// written when debug is set.
+func NewStdLogger(l *log.Logger, debug bool) Logger {
+	return &stdLogger{l: l, debug: debug}
+}
+
+type stdLogger struct {
+	l     *log.Logger
+	debug bool
+}
+
+func (s *stdLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if s.debug {
+		s.output("DEBUG", msg, keysAndValues)
+	}
+}
+
+func (s *stdLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.output("INFO", msg, keysAndValues)
+}
+
+func (s *stdLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.output("WARN", msg, keysAndValues)
+}
+
+func (s *stdLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.output("ERROR", msg, keysAndValues)
+}
+
+func (s *stdLogger) output(level, msg string, keysAndValues []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(level)
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&sb, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&sb, " %v", keysAndValues[i])
+		}
+	}
+
+	_ = s.l.Output(3, sb.String())
+}
+
+// NopLogger returns a Logger that discards everything, e.g. to silence
+// providers without a Logger with SetFallbackLogger.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugw(string, ...interface{}) {}
+func (nopLogger) Infow(string, ...interface{})  {}
+func (nopLogger) Warnw(string, ...interface{})  {}
+func (nopLogger) Errorw(string, ...interface{}) {}
+
+var (
+	fallback atomic.Value // loggerBox
+
+	defaultLoggerOnce sync.Once
+	defaultLogger     Logger
+)
+
+// loggerBox gives the loggers stored in fallback the same concrete type.
+type loggerBox struct {
+	l Logger
+}
+
+// SetFallbackLogger sets the Logger of providers without one, which write
+// to stdout by default. A nil Logger discards their output.
+func SetFallbackLogger(l Logger) {
+	if l == nil {
+		l = NopLogger()
+	}
+	fallback.Store(loggerBox{l: l})
+}
+
+// getLogger returns the configured Logger, falling back to the one set with
+// SetFallbackLogger or else to writing to stdout as the provider always has.
+func (p *Provider) getLogger() Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+
+	if box, ok := fallback.Load().(loggerBox); ok {
+		return box.l
+	}
+
+	defaultLoggerOnce.Do(func() {
+		defaultLogger = NewStdLogger(log.New(os.Stdout, "[directadmin] ", 0), false)
+	})
+
+	return defaultLogger
+}
@@ -0,0 +1,33 @@
+//go:build go1.21
+
+package directadmin
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger adapts a *slog.Logger.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s *slogLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelDebug, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelInfo, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelWarn, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelError, msg, keysAndValues...)
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package directadmin
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	l.Debugw("hidden")
+	l.Infow("added", "zone", "example.com")
+	l.Warnw("locked", "attempt", 2)
+	l.Errorw("failed", "error", "boom")
+
+	want := "level=INFO msg=added zone=example.com\nlevel=WARN msg=locked attempt=2\nlevel=ERROR msg=failed error=boom\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
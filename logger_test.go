@@ -0,0 +1,91 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestProvider_FallbackLogger(t *testing.T) {
+	var p Provider
+	if p.getLogger() != p.getLogger() {
+		t.Error("expected the default logger to be reused")
+	}
+
+	var buf bytes.Buffer
+	SetFallbackLogger(NewStdLogger(log.New(&buf, "", 0), false))
+	t.Cleanup(func() { fallback.Store(loggerBox{l: defaultLogger}) })
+
+	p.getLogger().Warnw("zone locked", "zone", "example.com")
+	if got := buf.String(); !strings.Contains(got, "WARN zone locked zone=example.com") {
+		t.Errorf("expected the fallback logger to be used, got %q", got)
+	}
+
+	SetFallbackLogger(nil)
+	if _, ok := p.getLogger().(nopLogger); !ok {
+		t.Errorf("expected a nil fallback to discard the output, got %T", p.getLogger())
+	}
+
+	p.Logger = NewStdLogger(log.New(&buf, "", 0), true)
+	if p.getLogger() != p.Logger {
+		t.Error("expected the configured logger to take precedence")
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0), false)
+
+	l.Debugw("hidden", "zone", "example.com")
+	l.Infow("added", "zone", "example.com", "count", 2)
+	l.Warnw("odd", "dangling")
+	l.Errorw("failed", "error", "boom")
+
+	want := "INFO added zone=example.com count=2\nWARN odd dangling\nERROR failed error=boom\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	NewStdLogger(log.New(&buf, "", 0), true).Debugw("shown")
+	if buf.String() != "DEBUG shown\n" {
+		t.Errorf("expected debug messages with debug set, got %q", buf.String())
+	}
+}
+
+// recordingLogger is a Logger keeping what it was asked to log.
+type recordingLogger struct {
+	entries []string
+}
+
+func (r *recordingLogger) log(level, msg string, keysAndValues []interface{}) {
+	r.entries = append(r.entries, fmt.Sprint(append([]interface{}{level, msg}, keysAndValues...)...))
+}
+
+func (r *recordingLogger) Debugw(msg string, kv ...interface{}) { r.log("debug", msg, kv) }
+func (r *recordingLogger) Infow(msg string, kv ...interface{})  { r.log("info", msg, kv) }
+func (r *recordingLogger) Warnw(msg string, kv ...interface{})  { r.log("warn", msg, kv) }
+func (r *recordingLogger) Errorw(msg string, kv ...interface{}) { r.log("error", msg, kv) }
+
+func TestFake_CustomLogger(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	logger := &recordingLogger{}
+	provider.Logger = logger
+
+	server.FailNext("CMD_API_DNS_CONTROL", "Internal Error")
+	if _, err := provider.GetRecords(context.Background(), fakeZone); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(logger.entries) == 0 {
+		t.Fatal("expected the failure to be logged through the configured Logger")
+	}
+	for _, entry := range logger.entries {
+		if !strings.Contains(entry, "request_id") {
+			t.Errorf("expected the request ID to be added, got %q", entry)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package directadmin
+
+import (
+	"context"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+// APIRequest describes a single call to the DirectAdmin API.
+type APIRequest = daapi.Request
+
+// APIResponse is the raw response DirectAdmin returned for an APIRequest.
+type APIResponse = daapi.Response
+
+// Handler executes an APIRequest.
+type Handler = daapi.Handler
+
+// Middleware wraps the Handler that executes API requests, allowing it to
+// inspect or change requests and responses, e.g. for auditing or metrics.
+type Middleware = daapi.Middleware
+
+// Use appends middleware to the chain every API call passes through. The
+// first middleware added is the outermost. Use must not be called while the
+// provider is in use.
+func (p *Provider) Use(mw ...Middleware) {
+	p.Middleware = append(p.Middleware, mw...)
+}
+
+// BeforeRequest returns middleware that calls fn before every API call. The
+// call is aborted with fn's error if it returns one.
+func BeforeRequest(fn func(ctx context.Context, req *APIRequest) error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+			if err := fn(ctx, req); err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// AfterRequest returns middleware that calls fn with the outcome of every API
+// call.
+func AfterRequest(fn func(ctx context.Context, req *APIRequest, resp *APIResponse, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+			resp, err := next(ctx, req)
+			fn(ctx, req, resp, err)
+			return resp, err
+		}
+	}
+}
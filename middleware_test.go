@@ -0,0 +1,81 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_Middleware(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				calls = append(calls, name+" "+req.Command)
+				resp, err := next(ctx, req)
+				calls = append(calls, name+" done")
+				return resp, err
+			}
+		}
+	}
+
+	var after []string
+	provider.Use(trace("outer"), trace("inner"))
+	provider.Use(AfterRequest(func(ctx context.Context, req *APIRequest, resp *APIResponse, err error) {
+		if req.Command != "CMD_API_DNS_CONTROL" || err != nil {
+			return
+		}
+		if req.Zone != fakeZone || resp == nil {
+			t.Fatalf("expected the request and response, got %+v and %+v", req, resp)
+		}
+		after = append(after, string(resp.Body))
+	}))
+
+	if _, err := provider.GetRecords(ctx, fakeZone); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first middleware added is the outermost
+	want := []string{"outer CMD_API_DNS_CONTROL", "inner CMD_API_DNS_CONTROL", "inner done", "outer done"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+
+	// AfterRequest sees the errors DirectAdmin reports in the response
+	server.FailNext("CMD_API_DNS_CONTROL", "Internal Error")
+	if _, err := provider.GetRecords(ctx, fakeZone); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(after) != 2 || strings.Contains(after[0], "Internal Error") || !strings.Contains(after[1], "Internal Error") {
+		t.Errorf("expected a success and a failure, got %v", after)
+	}
+
+	// BeforeRequest can abort calls before they reach the panel
+	errReadOnly := errors.New("read only")
+	provider.Use(BeforeRequest(func(ctx context.Context, req *APIRequest) error {
+		if len(req.Params.Get("action")) > 0 {
+			return errReadOnly
+		}
+		return nil
+	}))
+
+	requests := server.RequestCount("CMD_API_DNS_CONTROL")
+	_, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}})
+	if !errors.Is(err, errReadOnly) {
+		t.Errorf("expected the hook's error, got %v", err)
+	}
+	// Reading the zone before the change is let through
+	if n := server.RequestCount("CMD_API_DNS_CONTROL") - requests; n > 1 {
+		t.Errorf("expected the change not to reach the panel, got %d requests", n)
+	}
+	if len(server.Records(fakeZone)) != 2 {
+		t.Errorf("expected the zone to be unchanged, got %v", server.Records(fakeZone))
+	}
+}
@@ -3,10 +3,12 @@ package directadmin
 import (
 	"errors"
 	"fmt"
-	"github.com/libdns/libdns"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/libdns/directadmin/daapi"
+	"github.com/libdns/libdns"
 )
 
 type daZone struct {
@@ -43,7 +45,7 @@ func (r daRecord) libdnsRecord(zone string) (libdns.Record, error) {
 	record := libdns.Record{
 		ID:   r.Combined,
 		Type: r.Type,
-		Name: r.Name,
+		Name: libdnsName(r.Name, zone),
 	}
 
 	switch r.Type {
@@ -56,7 +58,7 @@ func (r daRecord) libdnsRecord(zone string) (libdns.Record, error) {
 		}
 
 		record.Priority = uint(priority)
-		record.Value = fmt.Sprintf("%v.%v", splits[1], zone)
+		record.Value = mxValue(splits[1], zone)
 	case "SRV":
 		return record, ErrUnsupported
 	case "URI":
@@ -76,8 +78,171 @@ func (r daRecord) libdnsRecord(zone string) (libdns.Record, error) {
 	return record, nil
 }
 
-type daResponse struct {
-	Error   string `json:"error,omitempty"`
-	Success string `json:"success,omitempty"`
-	Result  string `json:"result,omitempty"`
+// libdnsName converts a DirectAdmin record name, which is either relative
+// or fully qualified with a trailing dot, to a name relative to the zone
+// with "@" for the apex.
+func libdnsName(name, zone string) string {
+	if !strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	fqdn := strings.TrimSuffix(name, ".")
+	suffix := "." + zone
+	switch {
+	case strings.EqualFold(fqdn, zone):
+		return "@"
+	case len(fqdn) > len(suffix) && strings.EqualFold(fqdn[len(fqdn)-len(suffix):], suffix):
+		return fqdn[:len(fqdn)-len(suffix)]
+	default:
+		return name
+	}
+}
+
+// daName converts a libdns record name to what DirectAdmin expects, which
+// refers to the apex by its fully qualified name.
+func daName(name, zone string) string {
+	if len(name) == 0 || name == "@" {
+		return zone + "."
+	}
+
+	return name
+}
+
+// ErrFullMXRequired is returned for an MX record pointing outside the zone
+// when the zone doesn't have full MX records enabled.
+var ErrFullMXRequired = errors.New("MX target outside the zone requires full MX records")
+
+// mxValue converts the target of a DirectAdmin MX record, which is relative
+// to the zone unless it ends with a dot, to a host name.
+func mxValue(target, zone string) string {
+	if strings.HasSuffix(target, ".") {
+		return strings.TrimSuffix(target, ".")
+	}
+
+	return fmt.Sprintf("%v.%v", target, zone)
+}
+
+// mxTarget converts an MX record's host name to the target DirectAdmin
+// expects: fully qualified with a trailing dot when the zone has full MX
+// records enabled, and relative to the zone otherwise, which only allows
+// hosts in the zone. Host names without a dot are relative to the zone.
+func mxTarget(value, zone string, fullMX bool) (string, error) {
+	host := strings.TrimSuffix(value, ".")
+	suffix := "." + zone
+
+	var relative string
+	switch {
+	case strings.EqualFold(host, zone):
+		// The apex can only be referred to by its full name
+		return zone + ".", nil
+	case len(host) > len(suffix) && strings.EqualFold(host[len(host)-len(suffix):], suffix):
+		relative = host[:len(host)-len(suffix)]
+	case !strings.HasSuffix(value, ".") && !strings.Contains(host, "."):
+		relative = host
+	case fullMX:
+		return host + ".", nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrFullMXRequired, value)
+	}
+
+	if fullMX {
+		return relative + suffix + ".", nil
+	}
+
+	return relative, nil
+}
+
+type daResponse = daapi.Result
+
+// ZoneInfo describes the DNS configuration DirectAdmin reports for a zone
+// alongside its records.
+type ZoneInfo struct {
+	// Zone is the domain the information was requested for
+	Zone string `json:"zone"`
+
+	// DNSSEC reports whether the zone is currently signed
+	DNSSEC bool `json:"dnssec"`
+
+	// UserDNSSECControl reports whether the user may manage DNSSEC themselves
+	UserDNSSECControl bool `json:"user_dnssec_control"`
+
+	// NS, PTR, SPF, TLSA and CAA report which of the optional record
+	// editors DirectAdmin has enabled for the zone
+	NS   bool `json:"ns"`
+	PTR  bool `json:"ptr"`
+	SPF  bool `json:"spf"`
+	TLSA bool `json:"tlsa"`
+	CAA  bool `json:"caa"`
+
+	// TTL reports whether per-record TTLs are enabled
+	TTL bool `json:"ttl"`
+
+	// AffectPointersDefault is the default for replicating changes to
+	// pointer (alias) domains
+	AffectPointersDefault bool `json:"affect_pointers_default"`
+
+	// AllowUnderscore reports whether record names may contain underscores
+	AllowUnderscore bool `json:"allow_underscore"`
+
+	// FullMXRecords reports whether MX values are stored fully qualified
+	FullMXRecords bool `json:"full_mx_records"`
+
+	// DefaultTTL is the TTL DirectAdmin applies to records without one
+	DefaultTTL time.Duration `json:"default_ttl"`
+
+	// AllowTTLOverride reports whether the zone-wide TTL may be changed
+	AllowTTLOverride bool `json:"allow_ttl_override"`
+
+	// ZoneTTL is the zone-wide TTL when TTLOverridden is set
+	TTLOverridden bool          `json:"ttl_overridden"`
+	ZoneTTL       time.Duration `json:"zone_ttl"`
+}
+
+func (z daZone) zoneInfo(zone string) (ZoneInfo, error) {
+	info := ZoneInfo{
+		Zone:                  zone,
+		DNSSEC:                daBool(z.Dnssec),
+		UserDNSSECControl:     daBool(z.UserDnssecControl),
+		NS:                    daBool(z.DNSNs),
+		PTR:                   daBool(z.DNSPtr),
+		SPF:                   daBool(z.DNSSpf),
+		TLSA:                  daBool(z.DNSTLSa),
+		CAA:                   daBool(z.DNSCaa),
+		TTL:                   daBool(z.DNSTTL),
+		AffectPointersDefault: daBool(z.DNSAffectPointersDefault),
+		AllowUnderscore:       daBool(z.AllowDNSUnderscore),
+		FullMXRecords:         daBool(z.FullMxRecords),
+		AllowTTLOverride:      daBool(z.AllowTTLOverride),
+		TTLOverridden:         daBool(z.TTLIsOverridden),
+	}
+
+	if len(z.DefaultTTL) > 0 {
+		ttl, err := strconv.Atoi(z.DefaultTTL)
+		if err != nil {
+			return info, fmt.Errorf("failed to parse default TTL for %v: %v", zone, err)
+		}
+		info.DefaultTTL = time.Duration(ttl) * time.Second
+	}
+
+	zoneTTL := z.TTLValue
+	if len(zoneTTL) == 0 {
+		zoneTTL = z.TTL
+	}
+	if len(zoneTTL) > 0 {
+		if ttl, err := strconv.Atoi(zoneTTL); err == nil {
+			info.ZoneTTL = time.Duration(ttl) * time.Second
+		}
+	}
+
+	return info, nil
+}
+
+// daBool interprets the various ways DirectAdmin spells a boolean setting.
+func daBool(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "yes", "on", "1", "true":
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,93 @@
+package directadmin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMXTarget(t *testing.T) {
+	var tests = []struct {
+		value  string
+		fullMX bool
+		target string
+		err    error
+	}{
+		{value: "mail.example.com", target: "mail"},
+		{value: "mail.example.com.", fullMX: true, target: "mail.example.com."},
+		{value: "mail", target: "mail"},
+		{value: "mail", fullMX: true, target: "mail.example.com."},
+		{value: "example.com", target: "example.com."},
+		{value: "mx.example.net", fullMX: true, target: "mx.example.net."},
+		{value: "mx.example.net", err: ErrFullMXRequired},
+		{value: "mailexample.com", err: ErrFullMXRequired},
+	}
+
+	for _, tt := range tests {
+		target, err := mxTarget(tt.value, "example.com", tt.fullMX)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v (full MX %v): expected error %v, got %v", tt.value, tt.fullMX, tt.err, err)
+			continue
+		}
+		if target != tt.target {
+			t.Errorf("%v (full MX %v): expected %q, got %q", tt.value, tt.fullMX, tt.target, target)
+		}
+	}
+}
+
+func TestMXValue(t *testing.T) {
+	if got := mxValue("mail", "example.com"); got != "mail.example.com" {
+		t.Errorf("expected relative target to be qualified, got %v", got)
+	}
+	if got := mxValue("mx.example.net.", "example.com"); got != "mx.example.net" {
+		t.Errorf("expected absolute target to be kept, got %v", got)
+	}
+}
+
+func TestDAZone_ZoneInfo(t *testing.T) {
+	z := daZone{
+		Dnssec:                   "yes",
+		UserDnssecControl:        "ON",
+		DNSNs:                    "1",
+		DNSCaa:                   "no",
+		DNSTTL:                   "yes",
+		DNSAffectPointersDefault: "yes",
+		AllowDNSUnderscore:       "true",
+		DefaultTTL:               "14400",
+		AllowTTLOverride:         "yes",
+		TTLIsOverridden:          "yes",
+		TTL:                      "600",
+	}
+
+	info, err := z.zoneInfo("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ZoneInfo{
+		Zone:                  "example.com",
+		DNSSEC:                true,
+		UserDNSSECControl:     true,
+		NS:                    true,
+		TTL:                   true,
+		AffectPointersDefault: true,
+		AllowUnderscore:       true,
+		DefaultTTL:            4 * time.Hour,
+		AllowTTLOverride:      true,
+		TTLOverridden:         true,
+		ZoneTTL:               10 * time.Minute,
+	}
+	if info != want {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+
+	// ttl_value takes precedence over ttl
+	z.TTLValue = "300"
+	if info, _ := z.zoneInfo("example.com"); info.ZoneTTL != 5*time.Minute {
+		t.Errorf("expected a zone TTL of 5m, got %v", info.ZoneTTL)
+	}
+
+	z.DefaultTTL = "an hour"
+	if _, err := z.zoneInfo("example.com"); err == nil {
+		t.Error("expected an error for an invalid default TTL")
+	}
+}
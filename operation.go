@@ -0,0 +1,162 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+const defaultOperationTimeout = 30 * time.Second
+
+// OperationError wraps errors returned by the provider's methods with the
+// ID of the request they occurred in. The same ID is included in every log
+// entry for that request.
+type OperationError struct {
+	Op        string
+	RequestID string
+	Err       error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("directadmin: %s [request %s]: %v", e.Op, e.RequestID, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// WithRequestID returns a context that makes the provider use id instead of
+// a generated request ID, e.g. to correlate its logs with the caller's own.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID of the operation ctx belongs
+// to, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok && len(id) > 0
+}
+
+// startOperation prepares ctx for one of the provider's public operations:
+// it assigns a request ID unless ctx already carries one, applies the default
+// deadline and starts a span.
+// The returned function must be deferred with a pointer to the operation's
+// error, which it records and wraps in an OperationError.
+func (p *Provider) startOperation(ctx context.Context, op, zone string, attrs ...attribute.KeyValue) (context.Context, func(err *error)) {
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		id = newRequestID()
+		ctx = WithRequestID(ctx, id)
+	}
+
+	ctx, cancel := p.withDefaultDeadline(ctx)
+
+	attrs = append(attrs, attribute.String("directadmin.request_id", id))
+	ctx, endSpan := p.startSpan(ctx, op, zone, attrs...)
+
+	return ctx, func(err *error) {
+		endSpan(err)
+		cancel()
+
+		var opErr *OperationError
+		if err != nil && *err != nil && !errors.As(*err, &opErr) {
+			*err = &OperationError{Op: op, RequestID: id, Err: *err}
+		}
+	}
+}
+
+// detached returns a context for cleaning up after an operation on zone,
+// which has to run even when ctx was canceled or expired. It keeps the
+// request ID and the zone's turn in the queue, but gets a deadline of its
+// own.
+func (p *Provider) detached(ctx context.Context, zone string) (context.Context, context.CancelFunc) {
+	detachedCtx, cancel := p.withDefaultDeadline(context.Background())
+	detachedCtx = withRequestIDOf(detachedCtx, ctx)
+
+	key := zoneLockKey{normalizeZoneKey(zone)}
+	if held := ctx.Value(key); held != nil {
+		detachedCtx = context.WithValue(detachedCtx, key, held)
+	}
+
+	return detachedCtx, cancel
+}
+
+// withRequestIDOf returns ctx carrying the request ID of opCtx, for work of
+// an operation that isn't bounded by OperationTimeout.
+func withRequestIDOf(ctx, opCtx context.Context) context.Context {
+	if id, ok := RequestIDFromContext(opCtx); ok {
+		return WithRequestID(ctx, id)
+	}
+
+	return ctx
+}
+
+// withDefaultDeadline applies OperationTimeout to contexts without a
+// deadline.
+func (p *Provider) withDefaultDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || p.OperationTimeout < 0 {
+		return ctx, func() {}
+	}
+
+	timeout := p.OperationTimeout
+	if timeout == 0 {
+		timeout = defaultOperationTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// logger returns the provider's Logger with the request ID of ctx attached.
+func (p *Provider) logger(ctx context.Context) Logger {
+	l := p.getLogger()
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return &fieldLogger{l: l, fields: []interface{}{"request_id", id}}
+	}
+
+	return l
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// fieldLogger adds fixed key/value pairs to every entry.
+type fieldLogger struct {
+	l      Logger
+	fields []interface{}
+}
+
+func (f *fieldLogger) with(keysAndValues []interface{}) []interface{} {
+	return append(append([]interface{}{}, f.fields...), keysAndValues...)
+}
+
+func (f *fieldLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	f.l.Debugw(msg, f.with(keysAndValues)...)
+}
+
+func (f *fieldLogger) Infow(msg string, keysAndValues ...interface{}) {
+	f.l.Infow(msg, f.with(keysAndValues)...)
+}
+
+func (f *fieldLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	f.l.Warnw(msg, f.with(keysAndValues)...)
+}
+
+func (f *fieldLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	f.l.Errorw(msg, f.with(keysAndValues)...)
+}
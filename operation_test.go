@@ -0,0 +1,61 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_RequestIDs(t *testing.T) {
+	provider, server := newFakeProvider(t)
+	var buf bytes.Buffer
+	provider.Logger = NewStdLogger(log.New(&buf, "", 0), true)
+
+	// A caller's ID is used for the logs and the error
+	ctx := WithRequestID(context.Background(), "caller-id")
+	server.FailNext("CMD_API_DNS_CONTROL", "Cannot Add Record")
+	_, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}})
+
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Op != "AppendRecords" || opErr.RequestID != "caller-id" {
+		t.Fatalf("expected an OperationError with the caller's request ID, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "[request caller-id]") {
+		t.Errorf("expected the request ID in the message, got %q", err.Error())
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Errorf("expected the cause to be unwrapped, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		if !strings.Contains(line, "caller-id") {
+			t.Errorf("expected every log line to carry the request ID, got %q", line)
+		}
+	}
+
+	// Without one, every operation gets its own
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		server.FailNext("CMD_API_DNS_CONTROL", "Cannot View That Domain")
+		_, err := provider.GetRecords(context.Background(), fakeZone)
+		if !errors.As(err, &opErr) || len(opErr.RequestID) == 0 {
+			t.Fatalf("expected an OperationError with a request ID, got %v", err)
+		}
+		ids[opErr.RequestID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected a new request ID per operation, got %v", ids)
+	}
+
+	// Successful calls return no error at all
+	if _, err := provider.GetRecords(context.Background(), fakeZone); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+package directadmin
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures a Provider created with New.
+type Option func(p *Provider) error
+
+// New creates a Provider for the DirectAdmin panel at serverURL, e.g.
+// `https://panel.example.com:2222` or just `panel.example.com`,
+// authenticating as user with the login key. The options are applied in
+// order and may reject invalid values.
+//
+// Creating a Provider as a struct literal keeps working; New additionally
+// normalizes and validates the configuration up front.
+func New(serverURL, user, loginKey string, opts ...Option) (*Provider, error) {
+	p := &Provider{
+		ServerURL: strings.TrimRight(strings.TrimSpace(serverURL), "/"),
+		User:      strings.TrimSpace(user),
+		LoginKey:  strings.TrimSpace(loginKey),
+	}
+
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	u, _ := p.baseURL()
+	p.ServerURL = u.String()
+
+	return p, nil
+}
+
+// WithLogger sets the Logger the provider reports to.
+func WithLogger(l Logger) Option {
+	return func(p *Provider) error {
+		p.Logger = l
+		return nil
+	}
+}
+
+// WithLoginKeyFile reads the login key from a file instead, pass an empty
+// loginKey to New when using it.
+func WithLoginKeyFile(path string) Option {
+	return func(p *Provider) error {
+		p.LoginKeyFile = path
+		return nil
+	}
+}
+
+// WithCredentials makes the provider ask cp for the credentials of every
+// request, pass an empty user and loginKey to New when using it.
+func WithCredentials(cp CredentialsProvider) Option {
+	return func(p *Provider) error {
+		p.Credentials = cp
+		return nil
+	}
+}
+
+// WithAccount adds an account managing the zones, see Provider.Accounts.
+// user and loginKey passed to New may then be empty.
+func WithAccount(user, loginKey string, zones ...string) Option {
+	return func(p *Provider) error {
+		p.Accounts = append(p.Accounts, Account{Zones: zones, User: user, LoginKey: loginKey})
+		return nil
+	}
+}
+
+// WithAffectPointers sets whether record changes are copied to the
+// domain's pointers, see Provider.AffectPointers.
+func WithAffectPointers(affect bool) Option {
+	return func(p *Provider) error {
+		p.AffectPointers = &affect
+		return nil
+	}
+}
+
+// WithOperationTimeout sets the deadline applied to operations whose context
+// has none. A negative timeout disables the default deadline.
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(p *Provider) error {
+		p.OperationTimeout = timeout
+		return nil
+	}
+}
+
+// WithInsecureRequests disables TLS certificate verification.
+func WithInsecureRequests() Option {
+	return func(p *Provider) error {
+		p.InsecureRequests = true
+		return nil
+	}
+}
+
+// WithTLSServerName sets the name the panel's certificate is verified
+// against.
+func WithTLSServerName(name string) Option {
+	return func(p *Provider) error {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			return errors.New("tls server name must not be empty")
+		}
+		p.TLSServerName = name
+		return nil
+	}
+}
+
+// WithConnectionLimits limits the idle and total connections kept to the
+// panel. Zero keeps Go's default.
+func WithConnectionLimits(maxIdle, maxPerHost int) Option {
+	return func(p *Provider) error {
+		if maxIdle < 0 || maxPerHost < 0 {
+			return errors.New("connection limits must not be negative")
+		}
+		p.MaxIdleConns = maxIdle
+		p.MaxConnsPerHost = maxPerHost
+		return nil
+	}
+}
+
+// WithConnectTimeouts sets how long connecting to the panel, the TLS
+// handshake and waiting for the response headers may take. Zero keeps the
+// default, a negative value disables the timeout.
+func WithConnectTimeouts(dial, tlsHandshake, responseHeader time.Duration) Option {
+	return func(p *Provider) error {
+		p.DialTimeout = dial
+		p.TLSHandshakeTimeout = tlsHandshake
+		p.ResponseHeaderTimeout = responseHeader
+		return nil
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections to the panel are kept.
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(p *Provider) error {
+		if timeout < 0 {
+			return errors.New("idle connection timeout must not be negative")
+		}
+		p.IdleConnTimeout = timeout
+		return nil
+	}
+}
+
+// WithoutKeepAlives opens a new connection for every request.
+func WithoutKeepAlives() Option {
+	return func(p *Provider) error {
+		p.DisableKeepAlives = true
+		return nil
+	}
+}
+
+// WithUserAgent appends a product token to the User-Agent.
+func WithUserAgent(product string) Option {
+	return func(p *Provider) error {
+		p.UserAgent = strings.TrimSpace(product)
+		return nil
+	}
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(p *Provider) error {
+		if len(strings.TrimSpace(key)) == 0 {
+			return errors.New("header name must not be empty")
+		}
+		if p.Headers == nil {
+			p.Headers = make(map[string]string)
+		}
+		p.Headers[key] = value
+		return nil
+	}
+}
+
+// WithExtraParams adds params to every DNS control request.
+func WithExtraParams(params url.Values) Option {
+	return func(p *Provider) error {
+		if p.ExtraParams == nil {
+			p.ExtraParams = make(url.Values)
+		}
+		for key, values := range params {
+			p.ExtraParams[key] = append(p.ExtraParams[key], values...)
+		}
+		return nil
+	}
+}
+
+// WithAllowInsecureHTTP permits a plain http:// server url.
+func WithAllowInsecureHTTP() Option {
+	return func(p *Provider) error {
+		p.AllowInsecureHTTP = true
+		return nil
+	}
+}
+
+// WithMaxResponseBytes limits the size of the responses read, see
+// Provider.MaxResponseBytes.
+func WithMaxResponseBytes(n int64) Option {
+	return func(p *Provider) error {
+		p.MaxResponseBytes = n
+		return nil
+	}
+}
+
+// WithMaxRetries sets how often throttled requests are retried. A negative
+// value disables retries.
+func WithMaxRetries(n int) Option {
+	return func(p *Provider) error {
+		p.MaxRetries = n
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the backoff, jitter and limits of the retries of
+// throttled requests.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Provider) error {
+		p.RetryPolicy = policy
+		return nil
+	}
+}
+
+// WithZoneLockTimeout sets how long changes rejected because the zone is
+// locked are retried. A negative value disables retrying.
+func WithZoneLockTimeout(timeout time.Duration) Option {
+	return func(p *Provider) error {
+		p.ZoneLockTimeout = timeout
+		return nil
+	}
+}
+
+// WithCircuitBreaker enables the circuit breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(p *Provider) error {
+		if threshold <= 0 {
+			return errors.New("circuit breaker threshold must be positive")
+		}
+		if cooldown < 0 {
+			return errors.New("circuit breaker cooldown must not be negative")
+		}
+		p.CircuitBreakerThreshold = threshold
+		p.CircuitBreakerCooldown = cooldown
+		return nil
+	}
+}
+
+// WithMiddleware appends middleware to the chain every API call passes
+// through.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(p *Provider) error {
+		p.Use(mw...)
+		return nil
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry TracerProvider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(p *Provider) error {
+		p.TracerProvider = tp
+		return nil
+	}
+}
+
+// WithDryRun makes the provider log changes instead of applying them.
+func WithDryRun() Option {
+	return func(p *Provider) error {
+		p.DryRun = true
+		return nil
+	}
+}
+
+// WithSkipDuplicates makes AppendRecords skip records that already exist.
+func WithSkipDuplicates() Option {
+	return func(p *Provider) error {
+		p.SkipDuplicates = true
+		return nil
+	}
+}
+
+// WithUpsertOnConflict makes AppendRecords update records DirectAdmin
+// rejects as duplicates.
+func WithUpsertOnConflict() Option {
+	return func(p *Provider) error {
+		p.UpsertOnConflict = true
+		return nil
+	}
+}
+
+// WithDefaultTTL sets the TTL of records given without one.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(p *Provider) error {
+		p.DefaultTTL = ttl
+		return nil
+	}
+}
+
+// WithoutDefaultRecords hides the records DirectAdmin's default zone
+// template created from GetRecords.
+func WithoutDefaultRecords() Option {
+	return func(p *Provider) error {
+		p.HideDefaultRecords = true
+		return nil
+	}
+}
+
+// WithOwnerID enables the ownership registry, marking the records the
+// provider creates with id and refusing to change other records.
+func WithOwnerID(id string) Option {
+	return func(p *Provider) error {
+		p.OwnerID = id
+		return nil
+	}
+}
+
+// WithProtectedRecords adds records that must never be modified or
+// deleted.
+func WithProtectedRecords(records ...ProtectedRecord) Option {
+	return func(p *Provider) error {
+		p.ProtectedRecords = append(p.ProtectedRecords, records...)
+		return nil
+	}
+}
+
+// WithForce allows deleting the apex NS records and the SOA record.
+func WithForce() Option {
+	return func(p *Provider) error {
+		p.Force = true
+		return nil
+	}
+}
+
+// WithOnAPICall sets the function called with the duration and outcome of
+// every API call.
+func WithOnAPICall(fn func(APICall)) Option {
+	return func(p *Provider) error {
+		p.OnAPICall = fn
+		return nil
+	}
+}
+
+// WithDebugHTTP records the exchanges with DirectAdmin, see DebugHTTP.
+func WithDebugHTTP() Option {
+	return func(p *Provider) error {
+		p.DebugHTTP = true
+		return nil
+	}
+}
+
+// WithVerifyWrites makes AppendRecords and SetRecords check that the
+// records they wrote are stored.
+func WithVerifyWrites() Option {
+	return func(p *Provider) error {
+		p.VerifyWrites = true
+		return nil
+	}
+}
+
+// WithSortRecords makes GetRecords return the records in a stable order.
+func WithSortRecords() Option {
+	return func(p *Provider) error {
+		p.SortRecords = true
+		return nil
+	}
+}
+
+// WithOnChange sets the function notified of every record change.
+func WithOnChange(fn func(ChangeEvent)) Option {
+	return func(p *Provider) error {
+		p.OnChange = fn
+		return nil
+	}
+}
+
+// WithoutZoneAdjustment passes record names through untouched, see
+// Provider.DisableZoneAdjustment.
+func WithoutZoneAdjustment() Option {
+	return func(p *Provider) error {
+		p.DisableZoneAdjustment = true
+		return nil
+	}
+}
+
+// WithLeaseLock makes changes take a lease on the zone first, valid for
+// ttl, see Provider.LeaseLock. Zero uses the default.
+func WithLeaseLock(ttl time.Duration) Option {
+	return func(p *Provider) error {
+		if ttl < 0 {
+			return errors.New("lease ttl must not be negative")
+		}
+		p.LeaseLock = true
+		p.LeaseTTL = ttl
+		return nil
+	}
+}
+
+// WithRollbackOnFailure makes failed batches roll back the records already
+// applied.
+func WithRollbackOnFailure() Option {
+	return func(p *Provider) error {
+		p.RollbackOnFailure = true
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package directadmin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+func TestNew(t *testing.T) {
+	var tests = []struct {
+		name          string
+		serverURL     string
+		user          string
+		loginKey      string
+		opts          []Option
+		expectSuccess bool
+		want          string
+	}{
+		{
+			name:          "valid",
+			serverURL:     " https://da.example.com:2222/ ",
+			user:          "admin",
+			loginKey:      "key",
+			opts:          []Option{WithOperationTimeout(10 * time.Second), WithSkipDuplicates()},
+			expectSuccess: true,
+			want:          "https://da.example.com:2222",
+		},
+		{
+			name:          "host only",
+			serverURL:     "da.example.com",
+			user:          "admin",
+			loginKey:      "key",
+			expectSuccess: true,
+			want:          "https://da.example.com:2222",
+		},
+		{
+			name:          "standard port",
+			serverURL:     "https://da.example.com",
+			user:          "admin",
+			loginKey:      "key",
+			expectSuccess: true,
+			want:          "https://da.example.com",
+		},
+		{
+			name:          "plain http",
+			serverURL:     "http://da.example.com:2222",
+			user:          "admin",
+			loginKey:      "key",
+			expectSuccess: false,
+		},
+		{
+			name:          "missing user",
+			serverURL:     "https://da.example.com:2222",
+			loginKey:      "key",
+			expectSuccess: false,
+		},
+		{
+			name:          "invalid option",
+			serverURL:     "https://da.example.com:2222",
+			user:          "admin",
+			loginKey:      "key",
+			opts:          []Option{WithCircuitBreaker(0, time.Second)},
+			expectSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.serverURL, tt.user, tt.loginKey, tt.opts...)
+
+			if tt.expectSuccess && err != nil {
+				t.Error(err)
+			}
+
+			if !tt.expectSuccess && err == nil {
+				t.Error("expected an error, didn't see one")
+			}
+
+			if tt.expectSuccess && p.ServerURL != tt.want {
+				t.Errorf("expected normalized server url, got %q", p.ServerURL)
+			}
+		})
+	}
+}
+
+func TestParseServerURL(t *testing.T) {
+	var tests = []struct {
+		raw       string
+		allowHTTP bool
+		want      string
+	}{
+		{raw: "da.example.com", want: "https://da.example.com:2222"},
+		{raw: "da.example.com:8443", want: "https://da.example.com:8443"},
+		{raw: "https://da.example.com", want: "https://da.example.com"},
+		{raw: "http://da.example.com", allowHTTP: true, want: "http://da.example.com"},
+		{raw: "https://[2001:db8::1]:2222", want: "https://[2001:db8::1]:2222"},
+		{raw: "[2001:db8::1]", want: "https://[2001:db8::1]:2222"},
+		{raw: "2001:db8::1", want: "https://[2001:db8::1]:2222"},
+		{raw: "http://da.example.com"},
+		{raw: "ftp://da.example.com"},
+		{raw: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			u, err := parseServerURL(tt.raw, tt.allowHTTP)
+			if len(tt.want) == 0 {
+				if err == nil {
+					t.Errorf("expected an error, got %v", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.String() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, u)
+			}
+		})
+	}
+}
+
+func TestCommandURL(t *testing.T) {
+	var tests = []struct {
+		base string
+		want string
+	}{
+		{base: "https://da.example.com:2222", want: "https://da.example.com:2222/CMD_API_DNS_CONTROL"},
+		{base: "https://da.example.com:2222/", want: "https://da.example.com:2222/CMD_API_DNS_CONTROL"},
+		{base: "https://proxy.example.com/da/", want: "https://proxy.example.com/da/CMD_API_DNS_CONTROL"},
+		{base: "https://proxy.example.com:443/panels/da", want: "https://proxy.example.com:443/panels/da/CMD_API_DNS_CONTROL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			base, err := parseServerURL(tt.base, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := daapi.CommandURL(base, "CMD_API_DNS_CONTROL").String(); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProvider_Validate(t *testing.T) {
+	provider := &Provider{
+		ServerURL:    "ftp://da.example.com",
+		LoginKey:     "key",
+		LoginKeyFile: "/run/secrets/da",
+	}
+
+	err := provider.Validate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	// scheme, user and mutually exclusive keys
+	if len(validationErr.Errs) != 3 {
+		t.Errorf("expected 3 problems, got %d: %v", len(validationErr.Errs), err)
+	}
+}
@@ -0,0 +1,240 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// OwnerMarkerPrefix is the label the TXT records marking the records an
+// owner created are named with: `_libdns-owner.www` marks records named
+// `www`, `_libdns-owner` those at the apex.
+const OwnerMarkerPrefix = "_libdns-owner"
+
+const ownerHeritage = "heritage=libdns-directadmin"
+
+// ErrNotOwned is returned, with OwnerID set, for changes to records the
+// provider didn't create. The error is a *NotOwnedError.
+var ErrNotOwned = errors.New("record not owned")
+
+// NotOwnedError is returned for a change to a record without an ownership
+// marker for the provider's OwnerID.
+type NotOwnedError struct {
+	Zone   string
+	Record libdns.Record
+}
+
+func (e *NotOwnedError) Error() string {
+	return fmt.Sprintf("%v: %v %v in %v", ErrNotOwned, e.Record.Type, e.Record.Name, e.Zone)
+}
+
+// Is reports whether target is ErrNotOwned.
+func (e *NotOwnedError) Is(target error) bool {
+	return target == ErrNotOwned
+}
+
+// ownerMarker returns the TXT record marking rec as created by the owner.
+// Its value identifies the record by a hash of its name, type, value and
+// priority, so each of several records sharing a name has its own marker.
+func (p *Provider) ownerMarker(zone string, rec libdns.Record) libdns.Record {
+	sum := sha256.Sum256([]byte(verifyKey(zone, rec)))
+
+	name := OwnerMarkerPrefix
+	if relative := relativeName(rec.Name, zone); relative != "@" {
+		// A wildcard is only allowed as the leftmost label
+		name += "." + strings.ReplaceAll(relative, "*", "_wildcard")
+	}
+
+	return libdns.Record{
+		Type:  "TXT",
+		Name:  name,
+		Value: fmt.Sprintf("%v,owner=%v,record=%v", ownerHeritage, p.OwnerID, hex.EncodeToString(sum[:8])),
+		TTL:   rec.TTL,
+	}
+}
+
+// isOwnerMarker reports whether rec marks the records of any owner.
+func isOwnerMarker(rec libdns.Record) bool {
+	return rec.Type == "TXT" && strings.HasPrefix(unquoteTXT(rec.Value), ownerHeritage+",")
+}
+
+// ownership holds the ownership markers of a zone.
+type ownership struct {
+	zone    string
+	markers map[string]libdns.Record
+}
+
+func markerKey(zone string, marker libdns.Record) string {
+	return relativeName(marker.Name, zone) + "\x00" + unquoteTXT(marker.Value)
+}
+
+// readOwnership reads the zone and returns its records, without the
+// ownership markers, and the markers.
+func (p *Provider) readOwnership(ctx context.Context, zone string) ([]libdns.Record, *ownership, error) {
+	current, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owned := &ownership{zone: zone, markers: make(map[string]libdns.Record)}
+	records := current[:0]
+	for _, rec := range current {
+		if isOwnerMarker(rec) {
+			owned.markers[markerKey(zone, rec)] = rec
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	return records, owned, nil
+}
+
+// marker returns the marker of rec in the zone, if it has one.
+func (o *ownership) marker(p *Provider, rec libdns.Record) (libdns.Record, bool) {
+	marker, ok := o.markers[markerKey(o.zone, p.ownerMarker(o.zone, rec))]
+	return marker, ok
+}
+
+// claim adds the marker for rec unless the zone has it already.
+func (p *Provider) claim(ctx context.Context, o *ownership, rec libdns.Record) error {
+	if _, ok := o.marker(p, rec); ok {
+		return nil
+	}
+
+	marker, err := p.appendZoneRecord(ctx, o.zone, p.ownerMarker(o.zone, rec))
+	if err != nil {
+		return fmt.Errorf("failed to mark %v %v as owned: %w", rec.Type, rec.Name, err)
+	}
+	o.markers[markerKey(o.zone, marker)] = marker
+
+	return nil
+}
+
+// release deletes the marker for rec, if the zone has it.
+func (p *Provider) release(ctx context.Context, o *ownership, rec libdns.Record) error {
+	marker, ok := o.marker(p, rec)
+	if !ok {
+		return nil
+	}
+
+	if _, err := p.deleteZoneRecord(ctx, o.zone, marker); err != nil {
+		return fmt.Errorf("failed to remove the ownership marker of %v %v: %w", rec.Type, rec.Name, err)
+	}
+	delete(o.markers, markerKey(o.zone, marker))
+
+	return nil
+}
+
+// replacedRecord returns the record of the zone a RecordModified or
+// RecordDeleted change of rec applies to: the record with its ID, or else
+// the one SetRecords and DeleteRecords would pick.
+func replacedRecord(zone string, current []libdns.Record, rec libdns.Record, kind ChangeKind) (libdns.Record, bool) {
+	if len(rec.ID) > 0 {
+		for _, cur := range current {
+			if cur.ID == rec.ID && cur.Type == rec.Type {
+				return cur, true
+			}
+		}
+		return libdns.Record{}, false
+	}
+
+	if kind == RecordModified {
+		return existingRecordFor(zone, current, rec)
+	}
+
+	key := verifyKey(zone, rec)
+	for _, cur := range current {
+		if verifyKey(zone, cur) == key {
+			return cur, true
+		}
+	}
+
+	return libdns.Record{}, false
+}
+
+// owned wraps a function changing records so that, with OwnerID set, it
+// refuses to change records the provider didn't create, and the ownership
+// markers are kept up to date for the records it adds, replaces or deletes.
+func (p *Provider) owned(kind ChangeKind, fn func(context.Context, string, []libdns.Record) ([]libdns.Record, error)) func(context.Context, string, []libdns.Record) ([]libdns.Record, error) {
+	return func(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+		if len(p.OwnerID) == 0 {
+			return fn(ctx, zone, records)
+		}
+
+		current, owned, err := p.readOwnership(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := make(map[string]bool, len(current))
+		for _, cur := range current {
+			existing[verifyKey(zone, cur)] = true
+		}
+
+		replaced := make([]libdns.Record, len(records))
+		for i, rec := range records {
+			var target libdns.Record
+			var ok bool
+			if kind == RecordAdded {
+				// Only an upsert replaces a record, the one with the same
+				// name and type
+				target, ok = existingRecordFor(zone, current, rec)
+				ok = ok && p.UpsertOnConflict && !existing[verifyKey(zone, rec)]
+			} else {
+				target, ok = replacedRecord(zone, current, rec, kind)
+			}
+			if !ok {
+				continue
+			}
+
+			if _, isOwned := owned.marker(p, target); !isOwned {
+				return nil, &NotOwnedError{Zone: zone, Record: target}
+			}
+			replaced[i] = target
+		}
+
+		changed, err := fn(ctx, zone, records)
+
+		for i, rec := range changed {
+			var markErr error
+			switch {
+			case kind == RecordDeleted:
+				if len(replaced[i].Type) == 0 {
+					replaced[i] = rec
+				}
+				markErr = p.release(ctx, owned, replaced[i])
+			case kind == RecordAdded && existing[verifyKey(zone, rec)] && len(replaced[i].Type) == 0:
+				// A duplicate skipped with SkipDuplicates stays whoever's it was
+			default:
+				if len(replaced[i].Type) > 0 && verifyKey(zone, replaced[i]) != verifyKey(zone, rec) {
+					markErr = p.release(ctx, owned, replaced[i])
+				}
+				if markErr == nil {
+					markErr = p.claim(ctx, owned, rec)
+				}
+			}
+			if markErr != nil && err == nil {
+				err = markErr
+			}
+		}
+
+		return changed, err
+	}
+}
+
+// filter returns the records that have a marker.
+func (o *ownership) filter(p *Provider, records []libdns.Record) []libdns.Record {
+	var filtered []libdns.Record
+	for _, rec := range records {
+		if _, ok := o.marker(p, rec); ok {
+			filtered = append(filtered, rec)
+		}
+	}
+
+	return filtered
+}
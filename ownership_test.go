@@ -0,0 +1,90 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestFake_Ownership(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.OwnerID = "cluster-a"
+
+	markers := func() int {
+		var n int
+		for _, rec := range server.Records(fakeZone) {
+			if strings.HasPrefix(rec.Name, OwnerMarkerPrefix) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Records created by the provider are marked and can be changed
+	api := libdns.Record{Type: "A", Name: "api", Value: "192.0.2.10"}
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{api}); err != nil {
+		t.Fatal(err)
+	}
+	if markers() != 1 {
+		t.Fatalf("expected a marker, got %v", server.Records(fakeZone))
+	}
+
+	records, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range records {
+		if isOwnerMarker(rec) {
+			t.Errorf("expected the markers to be hidden, got %v", rec)
+		}
+	}
+
+	api.Value = "192.0.2.11"
+	if _, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{api}); err != nil {
+		t.Fatal(err)
+	}
+	if markers() != 1 {
+		t.Errorf("expected the marker to be replaced, got %v", server.Records(fakeZone))
+	}
+
+	// Records created in the panel are refused
+	www := libdns.Record{Type: "A", Name: "www", Value: "192.0.2.2"}
+	_, err = provider.SetRecords(ctx, fakeZone, []libdns.Record{www})
+	var ownErr *NotOwnedError
+	if !errors.As(err, &ownErr) || !errors.Is(err, ErrNotOwned) || ownErr.Record.Name != "www" {
+		t.Errorf("expected a NotOwnedError for www, got %v", err)
+	}
+	www.Value = "192.0.2.1"
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{www}); !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned, got %v", err)
+	}
+
+	// Another owner's records are refused too
+	other, _ := newFakeProvider(t)
+	other.ServerURL, other.OwnerID = provider.ServerURL, "cluster-b"
+	if _, err := other.DeleteRecords(ctx, fakeZone, []libdns.Record{api}); !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned for another owner, got %v", err)
+	}
+
+	// SyncZone leaves unowned records alone
+	plan, err := provider.SyncZone(ctx, fakeZone, nil, SyncOptions{Apply: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Delete) != 1 || plan.Delete[0].Name != "api" {
+		t.Errorf("expected only api to be deleted, got %+v", plan)
+	}
+
+	want := []directadmintest.Record{
+		{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+	}
+	if got := server.Records(fakeZone); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,38 @@
+package directadmin
+
+import (
+	"context"
+	"net/url"
+)
+
+type callParamsKey struct{}
+
+// WithCallParams returns a context that adds params to the DNS control
+// requests made with it, on top of the provider's ExtraParams, e.g. to set
+// a flag for a single AppendRecords call.
+func WithCallParams(ctx context.Context, params url.Values) context.Context {
+	return context.WithValue(ctx, callParamsKey{}, params)
+}
+
+// applyExtraParams merges ExtraParams and the params of WithCallParams into
+// a CMD_API_DNS_CONTROL request. They replace parameters of the same name
+// the provider set, call params taking precedence over ExtraParams.
+func (p *Provider) applyExtraParams(ctx context.Context, req *APIRequest) {
+	if req.Command != "CMD_API_DNS_CONTROL" {
+		return
+	}
+
+	callParams, _ := ctx.Value(callParamsKey{}).(url.Values)
+	if len(p.ExtraParams) == 0 && len(callParams) == 0 {
+		return
+	}
+
+	if req.Params == nil {
+		req.Params = make(url.Values)
+	}
+	for _, extra := range []url.Values{p.ExtraParams, callParams} {
+		for key, values := range extra {
+			req.Params[key] = append([]string(nil), values...)
+		}
+	}
+}
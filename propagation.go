@@ -0,0 +1,221 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// DefaultPublicResolvers are queried by CheckPropagation when no resolvers
+// are given.
+var DefaultPublicResolvers = []string{"1.1.1.1:53", "8.8.8.8:53", "9.9.9.9:53"}
+
+// ErrUnsupportedLookup is returned for record types CheckPropagation can't
+// look up.
+var ErrUnsupportedLookup = errors.New("record type can't be looked up")
+
+// PropagationResult is what one nameserver answered for a record.
+type PropagationResult struct {
+	// Server is the address queried, with the nameserver's name for
+	// authoritative servers
+	Server        string
+	Authoritative bool
+
+	// Found reports whether the record was in the answer, Values lists
+	// everything the server answered for the name and type
+	Found  bool
+	Values []string
+
+	// Err is set when the server couldn't be queried. A name that doesn't
+	// exist is reported as not found rather than as an error.
+	Err error
+}
+
+// CheckPropagation asks each authoritative nameserver of the zone, and each
+// of the resolvers, whether it serves the record. A resolver is given as
+// host:port, as an IP address queried on port 53 or as the https:// URL of a
+// DNS-over-HTTPS endpoint such as `https://dns.google/dns-query`, and
+// DefaultPublicResolvers are asked if there are none. A record without a
+// value matches any answer. The results list the authoritative servers
+// first.
+//
+// A, AAAA, CNAME, MX, NS and TXT records can be checked.
+func CheckPropagation(ctx context.Context, zone string, rec libdns.Record, resolvers []string) ([]PropagationResult, error) {
+	if len(resolvers) == 0 {
+		resolvers = DefaultPublicResolvers
+	}
+
+	return checkPropagation(ctx, zone, rec, true, nil, resolvers)
+}
+
+// checkPropagation asks the resolvers for the record, and with
+// authoritative set the authoritative nameservers too, the zone's NS
+// records if nameservers is empty.
+func checkPropagation(ctx context.Context, zone string, rec libdns.Record, authoritative bool, nameservers, resolvers []string) ([]PropagationResult, error) {
+	zone = strings.TrimSuffix(zone, ".")
+	if _, ok := lookups[strings.ToUpper(rec.Type)]; !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedLookup, rec.Type)
+	}
+	if !authoritative {
+		nameservers = nil
+	}
+
+	// addrs holds the address to query for each result, or "" if the
+	// server's address couldn't be resolved
+	var servers []PropagationResult
+	var addrs []string
+	if authoritative && len(nameservers) == 0 {
+		nss, err := net.DefaultResolver.LookupNS(ctx, zone+".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the nameservers of %v: %w", zone, err)
+		}
+		for _, ns := range nss {
+			host := strings.TrimSuffix(ns.Host, ".")
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				servers = append(servers, PropagationResult{Server: host, Authoritative: true, Err: err})
+				addrs = append(addrs, "")
+				continue
+			}
+			for _, ip := range ips {
+				addr := net.JoinHostPort(ip, "53")
+				servers = append(servers, PropagationResult{Server: fmt.Sprintf("%v (%v)", host, addr), Authoritative: true})
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	for _, nameserver := range nameservers {
+		addr, err := resolverAddress(nameserver)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, PropagationResult{Server: nameserver, Authoritative: true})
+		addrs = append(addrs, addr)
+	}
+	for _, resolver := range resolvers {
+		addr, err := resolverAddress(resolver)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, PropagationResult{Server: resolver})
+		addrs = append(addrs, addr)
+	}
+
+	fqdn := libdns.AbsoluteName(rec.Name, zone+".")
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		if len(addrs[i]) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *PropagationResult, addr string) {
+			defer wg.Done()
+
+			result.Values, result.Err = lookup(ctx, newResolver(addr), rec.Type, fqdn)
+			for _, v := range result.Values {
+				if len(rec.Value) == 0 || valueMatches(rec.Type, rec.Value, rec.Priority, v) {
+					result.Found = true
+					break
+				}
+			}
+		}(&servers[i], addrs[i])
+	}
+	wg.Wait()
+
+	return servers, nil
+}
+
+// resolverFor returns a resolver sending every query to addr.
+func resolverFor(addr string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+var lookups = map[string]func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error){
+	"A": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		return lookupIP(ctx, r, "ip4", fqdn)
+	},
+	"AAAA": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		return lookupIP(ctx, r, "ip6", fqdn)
+	},
+	"CNAME": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		cname, err := r.LookupCNAME(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		return []string{cname}, nil
+	},
+	"MX": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		mxs, err := r.LookupMX(ctx, fqdn)
+		var values []string
+		for _, mx := range mxs {
+			values = append(values, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+		return values, err
+	},
+	"NS": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		nss, err := r.LookupNS(ctx, fqdn)
+		var values []string
+		for _, ns := range nss {
+			values = append(values, ns.Host)
+		}
+		return values, err
+	},
+	"TXT": func(ctx context.Context, r *net.Resolver, fqdn string) ([]string, error) {
+		return r.LookupTXT(ctx, fqdn)
+	},
+}
+
+func lookupIP(ctx context.Context, r *net.Resolver, network, fqdn string) ([]string, error) {
+	ips, err := r.LookupIP(ctx, network, fqdn)
+	var values []string
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	return values, err
+}
+
+// lookup queries the record type, treating a name without records as an
+// empty answer.
+func lookup(ctx context.Context, r *net.Resolver, recordType, fqdn string) ([]string, error) {
+	values, err := lookups[strings.ToUpper(recordType)](ctx, r, fqdn)
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+
+	return values, err
+}
+
+// valueMatches compares a record value with a value from a DNS answer,
+// ignoring differences in notation.
+func valueMatches(recordType, want string, priority uint, got string) bool {
+	switch strings.ToUpper(recordType) {
+	case "A", "AAAA":
+		wantIP, gotIP := net.ParseIP(want), net.ParseIP(got)
+		return wantIP != nil && wantIP.Equal(gotIP)
+	case "TXT":
+		return want == got
+	case "MX":
+		if fields := strings.Fields(want); len(fields) == 1 {
+			want = fmt.Sprintf("%d %s", priority, want)
+		}
+		fallthrough
+	default:
+		return strings.EqualFold(strings.TrimSuffix(want, "."), strings.TrimSuffix(got, "."))
+	}
+}
@@ -0,0 +1,121 @@
+package directadmin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestValueMatches(t *testing.T) {
+	var tests = []struct {
+		recordType string
+		want       string
+		priority   uint
+		got        string
+		match      bool
+	}{
+		{recordType: "A", want: "192.0.2.1", got: "192.0.2.1", match: true},
+		{recordType: "AAAA", want: "2001:db8:0::1", got: "2001:db8::1", match: true},
+		{recordType: "A", want: "192.0.2.1", got: "192.0.2.2", match: false},
+		{recordType: "CNAME", want: "www.example.com", got: "WWW.example.com.", match: true},
+		{recordType: "MX", want: "mail.example.com", priority: 10, got: "10 mail.example.com.", match: true},
+		{recordType: "MX", want: "mail.example.com", priority: 20, got: "10 mail.example.com.", match: false},
+		{recordType: "TXT", want: "token", got: "token", match: true},
+		{recordType: "TXT", want: "token", got: "Token", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType+" "+tt.want, func(t *testing.T) {
+			if got := valueMatches(tt.recordType, tt.want, tt.priority, tt.got); got != tt.match {
+				t.Errorf("expected %v, got %v", tt.match, got)
+			}
+		})
+	}
+}
+
+func TestResolverAddress(t *testing.T) {
+	var tests = []struct {
+		resolver string
+		want     string
+		valid    bool
+	}{
+		{resolver: "192.0.2.53:5353", want: "192.0.2.53:5353", valid: true},
+		{resolver: "192.0.2.53", want: "192.0.2.53:53", valid: true},
+		{resolver: "2001:db8::53", want: "[2001:db8::53]:53", valid: true},
+		{resolver: "ns1.example.net:53", want: "ns1.example.net:53", valid: true},
+		{resolver: "https://dns.example.net/dns-query", want: "https://dns.example.net/dns-query", valid: true},
+		{resolver: "ns1.example.net"},
+		{resolver: "https:///dns-query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resolver, func(t *testing.T) {
+			got, err := resolverAddress(tt.resolver)
+			if (err == nil) != tt.valid || got != tt.want {
+				t.Errorf("expected %q (valid %v), got %q, %v", tt.want, tt.valid, got, err)
+			}
+		})
+	}
+}
+
+// fakeDoHResolver answers TXT queries over DNS-over-HTTPS with the values
+// txt returns for the queried name.
+func fakeDoHResolver(t *testing.T, txt func(name string) []string) string {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := io.ReadAll(r.Body)
+		if err != nil || r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(answerTXT(query, txt))
+	}))
+	t.Cleanup(server.Close)
+
+	client := dohClient
+	dohClient = server.Client()
+	t.Cleanup(func() { dohClient = client })
+
+	return server.URL + "/dns-query"
+}
+
+func TestCheckPropagation_Resolvers(t *testing.T) {
+	txt := func(name string) []string {
+		if name == "_acme-challenge.example.com" {
+			return []string{"token"}
+		}
+		return nil
+	}
+	resolvers := []string{fakeNameserver(t, txt), fakeDoHResolver(t, txt)}
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	results, err := checkPropagation(context.Background(), "example.com", rec, false, nil, resolvers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(resolvers) {
+		t.Fatalf("expected a result per resolver, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Err != nil || !result.Found || result.Authoritative {
+			t.Errorf("expected %v to serve the record, got %+v", result.Server, result)
+		}
+	}
+
+	rec.Name = "missing"
+	results, err = checkPropagation(context.Background(), "example.com", rec, false, nil, resolvers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Found {
+			t.Errorf("expected %v not to serve the record, got %+v", result.Server, result)
+		}
+	}
+}
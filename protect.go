@@ -0,0 +1,105 @@
+package directadmin
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrProtectedRecord is returned for changes to records matching
+// ProtectedRecords. The error is a *ProtectedRecordError.
+var ErrProtectedRecord = errors.New("record is protected")
+
+// ProtectedRecord selects records that must never be modified or deleted.
+// Empty fields match anything.
+type ProtectedRecord struct {
+	// Zone limits the entry to a zone, e.g. `example.com`
+	Zone string `json:"zone,omitempty"`
+
+	// Name is the record name relative to the zone, `@` for the apex. It
+	// may be a pattern as understood by path.Match, e.g. `*` or `_dmarc*`.
+	Name string `json:"name,omitempty"`
+
+	// Type is the record type, e.g. `MX`
+	Type string `json:"type,omitempty"`
+}
+
+func (pr ProtectedRecord) matches(zone string, rec libdns.Record) bool {
+	if len(pr.Zone) > 0 && !strings.EqualFold(strings.TrimSuffix(pr.Zone, "."), zone) {
+		return false
+	}
+	if len(pr.Type) > 0 && !strings.EqualFold(pr.Type, rec.Type) {
+		return false
+	}
+	if len(pr.Name) == 0 {
+		return true
+	}
+
+	matched, err := path.Match(relativeName(pr.Name, zone), relativeName(rec.Name, zone))
+	return err == nil && matched
+}
+
+// ProtectedRecordError is returned for a change to a record matching one of
+// the ProtectedRecords.
+type ProtectedRecordError struct {
+	Zone   string
+	Record libdns.Record
+	Rule   ProtectedRecord
+}
+
+func (e *ProtectedRecordError) Error() string {
+	return fmt.Sprintf("%v: %v %v in %v", ErrProtectedRecord, e.Record.Type, e.Record.Name, e.Zone)
+}
+
+// Is reports whether target is ErrProtectedRecord.
+func (e *ProtectedRecordError) Is(target error) bool {
+	return target == ErrProtectedRecord
+}
+
+// deleteGuards are the records that aren't deleted unless Force is set, as
+// deleting them breaks the delegation of the domain.
+var deleteGuards = []ProtectedRecord{
+	{Name: "@", Type: "NS"},
+	{Type: "SOA"},
+}
+
+// apexMXGuard is added to deleteGuards with ProtectApexMX.
+var apexMXGuard = ProtectedRecord{Name: "@", Type: "MX"}
+
+// checkProtected returns a *ProtectedRecordError for the first record
+// matching ProtectedRecords.
+func (p *Provider) checkProtected(zone string, records []libdns.Record) error {
+	return checkRules(zone, records, p.ProtectedRecords)
+}
+
+// checkDeletable is checkProtected for records to delete, which also
+// refuses the apex NS records and SOA, and with ProtectApexMX the apex MX
+// records, unless Force is set.
+func (p *Provider) checkDeletable(zone string, records []libdns.Record) error {
+	if err := p.checkProtected(zone, records); err != nil || p.Force {
+		return err
+	}
+
+	guards := deleteGuards
+	if p.ProtectApexMX {
+		guards = append(guards[:len(guards):len(guards)], apexMXGuard)
+	}
+
+	return checkRules(zone, records, guards)
+}
+
+func checkRules(zone string, records []libdns.Record, rules []ProtectedRecord) error {
+	zone = strings.TrimSuffix(zone, ".")
+	for _, rec := range records {
+		for _, rule := range rules {
+			if rule.matches(zone, rec) {
+				return &ProtectedRecordError{Zone: zone, Record: rec, Rule: rule}
+			}
+		}
+	}
+
+	return nil
+}
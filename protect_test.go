@@ -0,0 +1,100 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProtectedRecord_Matches(t *testing.T) {
+	var tests = []struct {
+		rule ProtectedRecord
+		rec  libdns.Record
+		want bool
+	}{
+		{rule: ProtectedRecord{Name: "@", Type: "MX"}, rec: libdns.Record{Type: "MX", Name: "@"}, want: true},
+		{rule: ProtectedRecord{Name: "@", Type: "MX"}, rec: libdns.Record{Type: "MX", Name: "example.com."}, want: true},
+		{rule: ProtectedRecord{Name: "@", Type: "MX"}, rec: libdns.Record{Type: "MX", Name: "sub"}, want: false},
+		{rule: ProtectedRecord{Name: "@"}, rec: libdns.Record{Type: "a", Name: ""}, want: true},
+		{rule: ProtectedRecord{Type: "ns"}, rec: libdns.Record{Type: "NS", Name: "delegated"}, want: true},
+		{rule: ProtectedRecord{Name: "_dmarc*", Type: "TXT"}, rec: libdns.Record{Type: "TXT", Name: "_dmarc"}, want: true},
+		{rule: ProtectedRecord{Name: "*.example.com", Type: "A"}, rec: libdns.Record{Type: "A", Name: "www"}, want: true},
+		{rule: ProtectedRecord{Zone: "example.net", Name: "www"}, rec: libdns.Record{Type: "A", Name: "www"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.matches("example.com", tt.rec); got != tt.want {
+			t.Errorf("%+v for %v %v: expected %v, got %v", tt.rule, tt.rec.Type, tt.rec.Name, tt.want, got)
+		}
+	}
+}
+
+func TestFake_ProtectedRecords(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.ProtectedRecords = []ProtectedRecord{{Name: "www", Type: "A"}}
+
+	www := libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}
+	_, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{www})
+	var protErr *ProtectedRecordError
+	if !errors.As(err, &protErr) || !errors.Is(err, ErrProtectedRecord) || protErr.Rule.Name != "www" {
+		t.Errorf("expected a ProtectedRecordError, got %v", err)
+	}
+
+	www.Value = "192.0.2.2"
+	if _, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{www}); !errors.Is(err, ErrProtectedRecord) {
+		t.Errorf("expected ErrProtectedRecord, got %v", err)
+	}
+
+	if _, err := provider.SyncZone(ctx, fakeZone, nil, SyncOptions{Apply: true, IgnoreTypes: []string{"NS"}}); !errors.Is(err, ErrProtectedRecord) {
+		t.Errorf("expected SyncZone to refuse deleting www, got %v", err)
+	}
+
+	if got := server.Records(fakeZone); len(got) != 2 || got[1].Value != "192.0.2.1" {
+		t.Errorf("expected the zone to be unchanged, got %v", got)
+	}
+
+	// Other records can still be changed
+	api := libdns.Record{Type: "A", Name: "api", Value: "192.0.2.3"}
+	if _, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{api}); err != nil {
+		t.Errorf("expected api to be set, got %v", err)
+	}
+}
+
+func TestFake_DeleteGuards(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	ns := libdns.Record{Type: "NS", Name: "@", Value: "ns1.example.net."}
+
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{ns}); !errors.Is(err, ErrProtectedRecord) {
+		t.Errorf("expected deleting the apex NS to be refused, got %v", err)
+	}
+	if _, err := provider.SyncZone(ctx, fakeZone, nil, SyncOptions{Apply: true}); !errors.Is(err, ErrProtectedRecord) {
+		t.Errorf("expected SyncZone to refuse deleting the apex NS, got %v", err)
+	}
+
+	provider.ProtectApexMX = true
+	mx := libdns.Record{Type: "MX", Name: "@", Value: "mail", Priority: 10}
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{mx}); !errors.Is(err, ErrProtectedRecord) {
+		t.Errorf("expected deleting the apex MX to be refused, got %v", err)
+	}
+
+	// NS records delegating a subdomain aren't guarded
+	sub := libdns.Record{Type: "NS", Name: "sub", Value: "ns1.example.org."}
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{sub}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{sub}); err != nil {
+		t.Errorf("expected the delegation to be deleted, got %v", err)
+	}
+
+	provider.Force = true
+	if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{ns}); err != nil {
+		t.Fatalf("expected Force to allow deleting the apex NS, got %v", err)
+	}
+	if got := server.Records(fakeZone); len(got) != 1 {
+		t.Errorf("expected only www to be left, got %v", got)
+	}
+}
@@ -4,16 +4,27 @@ package directadmin
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libdns/libdns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Provider facilitates DNS record manipulation with DirectAdmin.
 type Provider struct {
 	// ServerURL should be the hostname (with port if necessary) of the DirectAdmin instance
-	// you are trying to use
+	// you are trying to use. A bare host defaults to https:// and port 2222, a
+	// URL with a scheme to the scheme's standard port.
+	// A path prefix, e.g. `https://proxy.example.com/da/` for a panel behind a
+	// reverse proxy, is kept for every API call.
 	ServerURL string `json:"host,omitempty"`
 
 	// User should be the DirectAdmin username that the Login Key is created under
@@ -31,40 +42,463 @@ type Provider struct {
 	// can be omitted
 	LoginKey string `json:"login_key,omitempty"`
 
+	// LoginKeyFile is the path of a file containing the login key, such as a
+	// mounted Kubernetes or Docker secret. It is read on first use and read
+	// again when DirectAdmin rejects the key, so rotated keys are picked up.
+	// Only used when LoginKey is empty.
+	LoginKeyFile string `json:"login_key_file,omitempty"`
+
+	// Credentials, if set, supplies the user and login key for every request
+	// instead of User, LoginKey and LoginKeyFile. When DirectAdmin rejects
+	// them, they are refreshed (see CredentialsRefresher) and the request is
+	// retried once, so rotated keys are picked up without a restart.
+	Credentials CredentialsProvider `json:"-"`
+
+	// Accounts configures further DirectAdmin users for the zones they
+	// own, so one provider can manage zones spread over several users. A
+	// request for a zone uses the account with the most specific matching
+	// entry, and User, LoginKey and LoginKeyFile, which become optional,
+	// for zones no account covers. Can't be combined with Credentials.
+	Accounts []Account `json:"accounts,omitempty"`
+
+	// Reseller makes the provider manage the zones of the reseller's users
+	// too: the owner of each zone is looked up and the request is made as
+	// that user with DirectAdmin's login-as, `reseller|user`. The login key
+	// additionally needs the `CMD_API_SHOW_USERS` and
+	// `CMD_API_SHOW_USER_DOMAINS` permissions.
+	Reseller bool `json:"reseller,omitempty"`
+
+	// AffectPointers controls whether record changes are also made in the
+	// zones of the domain's pointers (alias domains), like the checkbox in
+	// the panel. Unset uses the panel's DNS_AFFECT_POINTERS_DEFAULT, which
+	// GetZoneInfo reports.
+	AffectPointers *bool `json:"affect_pointers,omitempty"`
+
 	// InsecureRequests is an optional parameter used to ignore SSL related errors on the
 	// DirectAdmin host
 	InsecureRequests bool `json:"insecure_requests,omitempty"`
 
+	// TLSServerName overrides the name the panel's certificate is verified
+	// against, which is needed when ServerURL is an IP address, such as an
+	// IPv6 literal like `https://[2001:db8::1]:2222`, and the certificate
+	// was issued for the panel's host name.
+	TLSServerName string `json:"tls_server_name,omitempty"`
+
+	// MaxIdleConns limits the idle connections kept open to the panel,
+	// MaxConnsPerHost all connections to it, and IdleConnTimeout how long an
+	// idle connection is kept. Zero means Go's defaults, no limit for
+	// MaxConnsPerHost. DisableKeepAlives opens a new connection for every
+	// request. They take effect when the first request is made.
+	MaxIdleConns      int           `json:"max_idle_conns,omitempty"`
+	MaxConnsPerHost   int           `json:"max_conns_per_host,omitempty"`
+	IdleConnTimeout   time.Duration `json:"idle_conn_timeout,omitempty"`
+	DisableKeepAlives bool          `json:"disable_keep_alives,omitempty"`
+
+	// DialTimeout limits connecting to the panel and TLSHandshakeTimeout
+	// the TLS handshake, both 10s by default, and ResponseHeaderTimeout how
+	// long the panel may take to start responding once the request was
+	// sent, unlimited by default. They detect a panel that accepts
+	// connections but hangs well before OperationTimeout expires. A
+	// negative value disables the timeout. They take effect when the first
+	// request is made.
+	DialTimeout           time.Duration `json:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout   time.Duration `json:"tls_handshake_timeout,omitempty"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+
+	// Transport, if set, sends the requests instead of a transport built
+	// from the options above, which it then has to implement itself, e.g.
+	// a directadmintest.Recorder in tests.
+	Transport http.RoundTripper `json:"-"`
+
+	// UserAgent is appended to the library's own User-Agent, e.g.
+	// `caddy/2.8`, so panel operators can tell who is calling the API.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Headers are added to every request, e.g. headers a fronting proxy
+	// requires or a correlation header for DirectAdmin's logs. They can't
+	// replace the Authorization header.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// ExtraParams are added to every CMD_API_DNS_CONTROL request, replacing
+	// parameters of the same name the provider sets, to use DirectAdmin
+	// flags the provider doesn't know or to work around a server's quirks.
+	// WithCallParams adds parameters to the requests of a single call.
+	ExtraParams url.Values `json:"extra_params,omitempty"`
+
+	// AllowInsecureHTTP permits a ServerURL with the http:// scheme. The
+	// login key is then sent unencrypted, so only use it on trusted
+	// networks or behind a TLS terminating proxy on the same host.
+	AllowInsecureHTTP bool `json:"allow_insecure_http,omitempty"`
+
+	// DryRun makes every method that would change the zone log the API call
+	// it would make instead of making it. The methods still return the
+	// records as if the change had been applied.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// SkipDuplicates makes AppendRecords skip records whose name, type,
+	// value and priority match a record already in the zone, so retried
+	// ACME challenges neither fail nor pile up identical records.
+	SkipDuplicates bool `json:"skip_duplicates,omitempty"`
+
+	// UpsertOnConflict makes AppendRecords update the existing record with
+	// the same name and type when DirectAdmin rejects a record as a
+	// duplicate, which suits dynamic DNS updaters.
+	UpsertOnConflict bool `json:"upsert_on_conflict,omitempty"`
+
+	// SortRecords makes GetRecords return the records sorted by name, type
+	// and value instead of in DirectAdmin's order, which can change between
+	// calls, so diffs of the output only show actual changes.
+	SortRecords bool `json:"sort_records,omitempty"`
+
+	// HideDefaultRecords makes GetRecords leave out the records DirectAdmin's
+	// default zone template creates for a new domain, such as `ftp`, `mail`
+	// and `www` pointing at the apex's address, so sync tools don't try to
+	// delete them; SyncZone leaves them alone too. Such records whose value
+	// was changed are returned.
+	HideDefaultRecords bool `json:"hide_default_records,omitempty"`
+
+	// OwnerID enables an ownership registry like external-dns's: every
+	// record AppendRecords, SetRecords or SyncZone creates gets a TXT marker
+	// named after OwnerMarkerPrefix holding the OwnerID, and changing or
+	// deleting a record without one fails with a *NotOwnedError
+	// (ErrNotOwned). This makes automated reconciliation safe in zones that
+	// are also edited in the panel. GetRecords leaves the markers out.
+	OwnerID string `json:"owner_id,omitempty"`
+
+	// ProtectedRecords lists records that must never be modified or
+	// deleted, e.g. `{"name": "@", "type": "MX"}`. SetRecords and
+	// DeleteRecords, AppendRecords with UpsertOnConflict, and SyncZone
+	// refuse to change them with a *ProtectedRecordError
+	// (ErrProtectedRecord).
+	ProtectedRecords []ProtectedRecord `json:"protected_records,omitempty"`
+
+	// Force allows DeleteRecords and SyncZone to delete the apex NS records
+	// and the SOA record, which they otherwise refuse with a
+	// *ProtectedRecordError, since that breaks the domain's delegation.
+	// ProtectApexMX refuses deleting the apex MX records the same way.
+	Force         bool `json:"force,omitempty"`
+	ProtectApexMX bool `json:"protect_apex_mx,omitempty"`
+
+	// RollbackOnFailure makes AppendRecords and SetRecords snapshot the zone
+	// before changing more than one record, and restore the snapshot if a
+	// record fails after others were already applied. This is best-effort:
+	// changes made to the zone by others during the call are reverted too.
+	RollbackOnFailure bool `json:"rollback_on_failure,omitempty"`
+
+	// AutoCreateSubdomains makes the record methods create the subdomain in
+	// DirectAdmin when they are called for a zone that turns out to be a
+	// subdomain of one of the user's domains, e.g. `shop.example.com`.
+	// DirectAdmin keeps the records of subdomains in the parent's zone, so
+	// they are written there either way; the key needs the
+	// `CMD_API_SUBDOMAINS` permission for this. GetRecords and RecordsIter
+	// never create the subdomain, they return ErrDomainNotFound until it
+	// exists.
+	AutoCreateSubdomains bool `json:"auto_create_subdomains,omitempty"`
+
+	// DefaultTTL is the TTL of records given without one, so a TTL policy
+	// can be enforced in one place. If unset, DirectAdmin applies the
+	// zone's default TTL to them.
+	DefaultTTL time.Duration `json:"default_ttl,omitempty"`
+
+	// VerifyWrites makes AppendRecords and SetRecords read the zone back
+	// after writing and return a *NotPersistedError (ErrNotPersisted) for
+	// records DirectAdmin accepted but didn't store, which happens with
+	// some TTL and underscore settings.
+	VerifyWrites bool `json:"verify_writes,omitempty"`
+
+	// DirectAdminVersion is the version of the DirectAdmin server, e.g.
+	// `1.62`. AppendRecords and SetRecords reject records of types that
+	// version can't store with an *UnsupportedTypeError. If unset, the
+	// version the server announces in its responses is used once known.
+	DirectAdminVersion string `json:"directadmin_version,omitempty"`
+
+	// DisableZoneAdjustment passes record names through untouched when a
+	// zone's records are kept in a parent zone, see AutoCreateSubdomains.
+	// The names of the records given to and returned by the record methods
+	// are then relative to the zone DirectAdmin manages, e.g. `www.shop`
+	// for `www.shop.example.com`, instead of the requested zone, and
+	// GetRecords returns all the records of the managed zone.
+	DisableZoneAdjustment bool `json:"disable_zone_adjustment,omitempty"`
+
+	// LeaseLock makes AppendRecords, SetRecords and DeleteRecords take a
+	// lease on the zone before changing it, so several instances sharing a
+	// zone, e.g. a Caddy cluster, don't clobber each other's edits. The
+	// lease is a TXT record named LeaseRecordName that expires after
+	// LeaseTTL (default 1m), in case its holder dies; GetRecords leaves it
+	// out. Waiting for the lease ends with ErrLeaseHeld once the context
+	// is done.
+	LeaseLock bool          `json:"lease_lock,omitempty"`
+	LeaseTTL  time.Duration `json:"lease_ttl,omitempty"`
+
+	// SyncTimeout makes AppendRecords, SetRecords and DeleteRecords wait up
+	// to this long, after the panel accepted the change, until every
+	// authoritative nameserver of the zone serves it. In DirectAdmin DNS
+	// clusters the secondaries lag behind the panel, which makes ACME
+	// validations fail. If they don't catch up in time, the methods return
+	// the changed records with ErrNotSynced. The wait isn't bounded by
+	// OperationTimeout. Zero disables waiting.
+	SyncTimeout time.Duration `json:"sync_timeout,omitempty"`
+
+	// SyncNameservers lists the nameservers, as host:port, to wait for
+	// instead of those in the zone's NS records, e.g. the cluster members
+	// when they aren't all listed. SyncInterval is how often they are
+	// polled, every 2s if zero.
+	SyncNameservers []string      `json:"sync_nameservers,omitempty"`
+	SyncInterval    time.Duration `json:"sync_interval,omitempty"`
+
+	// PropagationResolvers replaces the servers polled with SyncTimeout,
+	// e.g. with resolvers that an ACME server queries, to not rely on
+	// resolvers that may have cached a missing record. Each is given as
+	// host:port, as an IP address or as the https:// URL of a
+	// DNS-over-HTTPS endpoint, and AuthoritativeNameservers includes the
+	// authoritative nameservers.
+	PropagationResolvers []string `json:"propagation_resolvers,omitempty"`
+
+	// MaxResponseBytes limits the size of the responses read from
+	// DirectAdmin, so a misbehaving endpoint can't make the provider buffer
+	// unbounded data. Larger responses fail with ErrResponseTooLarge.
+	// Defaults to 64 MiB, a negative value disables the limit.
+	MaxResponseBytes int64 `json:"max_response_bytes,omitempty"`
+
+	// OperationTimeout is the deadline applied to every operation, including
+	// retries, when the caller's context has none. Defaults to 30s, a
+	// negative value disables it.
+	OperationTimeout time.Duration `json:"operation_timeout,omitempty"`
+
+	// MaxRetries is how often a request rejected with 429 Too Many Requests
+	// or 503 Service Unavailable is retried, honoring the Retry-After header.
+	// Defaults to 3, a negative value disables retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// RetryPolicy sets the delays between the retries of throttled
+	// requests, their jitter and limits. The zero value waits 1s, doubling
+	// for every retry up to 1m, without jitter.
+	RetryPolicy RetryPolicy `json:"retry_policy,omitempty"`
+
+	// ZoneLockTimeout is how long changes DirectAdmin rejects because
+	// another process holds the zone's lock are retried, with growing
+	// delays, before ErrZoneLocked is returned. The context's error is
+	// returned instead if it ends first. Defaults to 30s, a negative value
+	// disables retrying.
+	ZoneLockTimeout time.Duration `json:"zone_lock_timeout,omitempty"`
+
+	// CircuitBreakerThreshold enables a circuit breaker that stops sending
+	// requests after this many consecutive failures, returning
+	// ErrCircuitOpen instead until CircuitBreakerCooldown (default 30s)
+	// has passed. Only transport errors, 5xx and 429 responses count as
+	// failures.
+	CircuitBreakerThreshold int           `json:"circuit_breaker_threshold,omitempty"`
+	CircuitBreakerCooldown  time.Duration `json:"circuit_breaker_cooldown,omitempty"`
+
+	// OnChange is called after every record DirectAdmin added, modified or
+	// deleted through the provider, e.g. to feed the changes into an audit
+	// log. It is called synchronously while the zone is locked, so it
+	// should return quickly, and not at all in DryRun mode. Leases and
+	// ownership markers the provider manages itself are not reported.
+	OnChange func(ChangeEvent) `json:"-"`
+
+	// OnAPICall is called after every call to the DirectAdmin API with its
+	// duration and outcome, e.g. to feed latency metrics. It is called
+	// synchronously, so it should return quickly.
+	OnAPICall func(APICall) `json:"-"`
+
+	// SlowCallThreshold is how long an API call may take before it's logged
+	// at info level instead of debug level, to spot slow DirectAdmin
+	// servers. Defaults to 2s, a negative value disables it.
+	SlowCallThreshold time.Duration `json:"slow_call_threshold,omitempty"`
+
+	// Logger receives the provider's log output. If unset, the logger set
+	// with SetFallbackLogger is used, or else warnings and errors are
+	// written to stdout.
+	Logger Logger `json:"-"`
+
 	// Debug - can set this to stdout or stderr to dump
 	// debugging information about the API interaction with
 	// powerdns.  This will dump your auth token in plain text
 	// so be careful.
 	Debug string `json:"debug,omitempty"`
 
-	mutex sync.Mutex
+	// DebugHTTP records the requests sent to DirectAdmin and their
+	// responses, with credentials redacted, for HTTPExchanges and
+	// OnHTTPExchange, so the exact exchange can be attached to bug reports.
+	// It takes effect when the first request is made and applies to a
+	// custom Transport too.
+	DebugHTTP bool `json:"debug_http,omitempty"`
+
+	// OnHTTPExchange is called with every exchange recorded with DebugHTTP.
+	OnHTTPExchange func(HTTPExchange) `json:"-"`
+
+	// Middleware wraps every API call, see Use
+	Middleware []Middleware `json:"-"`
+
+	// TracerProvider is used to create OpenTelemetry spans for every
+	// operation and API call. The global TracerProvider is used if unset.
+	TracerProvider trace.TracerProvider `json:"-"`
+
+	// serverVersion is the daVersion the server announced
+	serverVersion atomic.Value
+	// writes serializes the changes to each zone
+	writes   zoneQueue
+	breaker  circuitBreaker
+	keyCache loginKeyCache
+	// accountKeys caches the login key files of Accounts by path
+	accountKeys sync.Map
+	owners      ownerCache
+	// fullMX caches the full MX records setting of each zone read
+	fullMX sync.Map
+	// managedZones caches the zone detected for each requested zone
+	managedZones sync.Map
+	client       sharedClient
+	// exchanges keeps the latest exchanges recorded with DebugHTTP
+	exchanges exchangeLog
 }
 
 // GetRecords lists all the records in the zone.
-func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+//
+// GetRecords doesn't wait for changes to the zone in progress, so
+// concurrent calls, e.g. while validating several certificates, run in
+// parallel. A call made during a batch of changes may see part of it.
+// Reads DirectAdmin answers with an error or a truncated zone while it
+// rewrites the zone file, e.g. right after a change, are repeated a couple
+// of times before failing.
+func (p *Provider) GetRecords(ctx context.Context, zone string) (_ []libdns.Record, err error) {
 	zone = strings.TrimSuffix(zone, ".")
 
-	records, err := p.getZoneRecords(ctx, zone)
+	ctx, end := p.startOperation(ctx, "GetRecords", zone)
+	defer end(&err)
+
+	records, err := p.inManagedZone(ctx, zone, false, nil, func(ctx context.Context, zone string, _ []libdns.Record) ([]libdns.Record, error) {
+		return p.getZoneRecords(ctx, zone)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	records = p.visibleRecords(zone, records)
+
+	if p.SortRecords {
+		sortRecords(records, zone)
+	}
+
 	return records, nil
 }
 
+// visibleRecords leaves out the records the provider keeps to itself, see
+// isVisible.
+func (p *Provider) visibleRecords(zone string, records []libdns.Record) []libdns.Record {
+	var apex map[string]bool
+	if p.HideDefaultRecords {
+		apex = apexAddresses(zone, records)
+	}
+
+	filtered := make([]libdns.Record, 0, len(records))
+	for _, rec := range records {
+		if p.isVisible(zone, rec, apex) {
+			filtered = append(filtered, rec)
+		}
+	}
+
+	return filtered
+}
+
+// isVisible reports whether GetRecords and RecordsIter return the record,
+// which they don't for leases with LeaseLock, ownership markers with
+// OwnerID and DirectAdmin's default records with HideDefaultRecords. apex
+// holds the zone's apex addresses, see apexAddresses.
+func (p *Provider) isVisible(zone string, rec libdns.Record, apex map[string]bool) bool {
+	switch {
+	case p.LeaseLock && isLeaseRecord(rec):
+		return false
+	case len(p.OwnerID) > 0 && isOwnerMarker(rec):
+		return false
+	case p.HideDefaultRecords && isDefaultRecord(zone, rec, apex):
+		return false
+	}
+
+	return true
+}
+
 // AppendRecords adds records to the zone. It returns the records that were added.
-func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+//
+// With SkipDuplicates set, records identical to one already in the zone are
+// not added again; the existing record is returned in their place.
+//
+// With UpsertOnConflict set, a record DirectAdmin refuses to add because one
+// with the same name and type exists replaces that record instead.
+//
+// Records are added one at a time. If adding one fails, the records added
+// before it are returned together with a *BatchError, unless
+// RollbackOnFailure is set and they could be removed again.
+//
+// A record whose request failed without telling whether it was added, e.g.
+// when the connection dropped waiting for the response, is only sent again
+// if reading the zone shows it's missing.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) (_ []libdns.Record, err error) {
 	zone = strings.TrimSuffix(zone, ".")
 
+	opCtx, end := p.startOperation(ctx, "AppendRecords", zone, attribute.Int("dns.records", len(records)))
+	defer end(&err)
+
+	// Only an upsert can change an existing record
+	if p.UpsertOnConflict {
+		if err := p.checkProtected(zone, records); err != nil {
+			return nil, err
+		}
+	}
+
+	changed, err := p.inManagedZone(opCtx, zone, true, records, p.serialized(p.withLease(p.verified(p.owned(RecordAdded, p.appendRecords)))))
+	if err != nil {
+		return changed, err
+	}
+
+	return changed, p.waitForSync(withRequestIDOf(ctx, opCtx), zone, changed, true)
+}
+
+func (p *Provider) appendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	if err := validateRecords(records); err != nil {
+		return nil, err
+	}
+	if err := p.checkRecordTypes(records); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := p.rollbackSnapshot(ctx, zone, records)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := p.existingRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
 	var created []libdns.Record
 	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			batchErr := &BatchError{Applied: created, Failed: rec, Err: err}
+			if p.rollback(ctx, zone, snapshot, batchErr) {
+				return nil, batchErr
+			}
+			return created, batchErr
+		}
+
+		if dup, ok := existing[recordKey(zone, rec)]; ok {
+			p.logger(ctx).Debugw("skipping duplicate record", "zone", zone, "type", rec.Type, "name", rec.Name)
+			created = append(created, dup)
+			continue
+		}
+
 		result, err := p.appendZoneRecord(ctx, zone, rec)
+		if err != nil && p.UpsertOnConflict && errors.Is(err, ErrDuplicateRecord) {
+			p.logger(ctx).Debugw("record exists, updating instead", "zone", zone, "type", rec.Type, "name", rec.Name)
+			result, err = p.setZoneRecord(ctx, zone, rec)
+		}
 		if err != nil {
-			return nil, err
+			batchErr := &BatchError{Applied: created, Failed: rec, Err: err}
+			if p.rollback(ctx, zone, snapshot, batchErr) {
+				return nil, batchErr
+			}
+			return created, batchErr
 		}
 		created = append(created, result)
 	}
@@ -74,14 +508,62 @@ func (p *Provider) AppendRecords(ctx context.Context, zone string, records []lib
 
 // SetRecords sets the records in the zone, either by updating existing records or creating new ones.
 // It returns the updated records.
-func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+//
+// A record without an ID replaces the existing record with the same value,
+// so changing only its TTL edits it in place, or else the first with the
+// same name and type. Records that already exist as given are left alone.
+//
+// Records are set one at a time. If setting one fails, the records set
+// before it are returned together with a *BatchError, unless
+// RollbackOnFailure is set and the zone could be restored.
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) (_ []libdns.Record, err error) {
 	zone = strings.TrimSuffix(zone, ".")
 
+	opCtx, end := p.startOperation(ctx, "SetRecords", zone, attribute.Int("dns.records", len(records)))
+	defer end(&err)
+
+	if err := p.checkProtected(zone, records); err != nil {
+		return nil, err
+	}
+
+	changed, err := p.inManagedZone(opCtx, zone, true, records, p.serialized(p.withLease(p.verified(p.owned(RecordModified, p.setRecords)))))
+	if err != nil {
+		return changed, err
+	}
+
+	return changed, p.waitForSync(withRequestIDOf(ctx, opCtx), zone, changed, true)
+}
+
+func (p *Provider) setRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	if err := validateRecords(records); err != nil {
+		return nil, err
+	}
+	if err := p.checkRecordTypes(records); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := p.rollbackSnapshot(ctx, zone, records)
+	if err != nil {
+		return nil, err
+	}
+
 	var updated []libdns.Record
 	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			batchErr := &BatchError{Applied: updated, Failed: rec, Err: err}
+			if p.rollback(ctx, zone, snapshot, batchErr) {
+				return nil, batchErr
+			}
+			return updated, batchErr
+		}
+
 		result, err := p.setZoneRecord(ctx, zone, rec)
 		if err != nil {
-			return nil, err
+			batchErr := &BatchError{Applied: updated, Failed: rec, Err: err}
+			if p.rollback(ctx, zone, snapshot, batchErr) {
+				return nil, batchErr
+			}
+			return updated, batchErr
 		}
 		updated = append(updated, result)
 	}
@@ -90,14 +572,37 @@ func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns
 }
 
 // DeleteRecords deletes the records from the zone. It returns the records that were deleted.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+//
+// Records are deleted one at a time. If deleting one fails, the records
+// deleted before it are returned together with a *BatchError.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) (_ []libdns.Record, err error) {
 	zone = strings.TrimSuffix(zone, ".")
 
+	opCtx, end := p.startOperation(ctx, "DeleteRecords", zone, attribute.Int("dns.records", len(records)))
+	defer end(&err)
+
+	if err := p.checkDeletable(zone, records); err != nil {
+		return nil, err
+	}
+
+	changed, err := p.inManagedZone(opCtx, zone, true, records, p.serialized(p.withLease(p.owned(RecordDeleted, p.deleteRecords))))
+	if err != nil {
+		return changed, err
+	}
+
+	return changed, p.waitForSync(withRequestIDOf(ctx, opCtx), zone, changed, false)
+}
+
+func (p *Provider) deleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
 	var deleted []libdns.Record
 	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return deleted, &BatchError{Applied: deleted, Failed: rec, Err: err}
+		}
+
 		result, err := p.deleteZoneRecord(ctx, zone, rec)
 		if err != nil {
-			return nil, err
+			return deleted, &BatchError{Applied: deleted, Failed: rec, Err: err}
 		}
 		deleted = append(deleted, result)
 	}
@@ -111,4 +616,50 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
+
+// sortRecords sorts records by name, type, value, priority and TTL. Names
+// are compared relative to the zone, so the apex sorts first.
+func sortRecords(records []libdns.Record, zone string) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+
+		if an, bn := relativeName(a.Name, zone), relativeName(b.Name, zone); an != bn {
+			if an == "@" || bn == "@" {
+				return an == "@"
+			}
+			return an < bn
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.TTL < b.TTL
+	})
+}
+
+// existingRecords indexes the zone's records by recordKey when
+// SkipDuplicates is set, and returns nil otherwise.
+func (p *Provider) existingRecords(ctx context.Context, zone string) (map[string]libdns.Record, error) {
+	if !p.SkipDuplicates {
+		return nil, nil
+	}
+
+	current, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]libdns.Record, len(current))
+	for _, rec := range current {
+		existing[recordKey(zone, rec)] = rec
+	}
+
+	return existing, nil
+}
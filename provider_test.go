@@ -4,16 +4,67 @@ import (
 	"context"
 	"fmt"
 	"github.com/joho/godotenv"
+	"github.com/libdns/directadmin/directadmintest"
 	"github.com/libdns/libdns"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
-func initProvider() (*Provider, string) {
+// Test modes selected with LIBDNS_DA_TEST_MODE. Without it, the tests run
+// against the panel configured in .env if there is one, and replay the
+// fixtures in testdata otherwise.
+const (
+	testModeLive   = "live"
+	testModeRecord = "record"
+	testModeReplay = "replay"
+)
+
+// Names the fixtures use in place of the real zone and panel
+const (
+	replayZone      = "example.com."
+	replayServerURL = "https://da.example.com:2222"
+	replayUser      = "admin"
+)
+
+func testMode() string {
+	if mode := os.Getenv("LIBDNS_DA_TEST_MODE"); len(mode) > 0 {
+		return mode
+	}
+
+	if _, err := os.Stat(".env"); err == nil {
+		return testModeLive
+	}
+
+	return testModeReplay
+}
+
+func initProvider(t *testing.T) (*Provider, string) {
+	mode := testMode()
+	fixture := filepath.Join("testdata", "fixtures", t.Name()+".json")
+
+	if mode == testModeReplay {
+		recorder, err := directadmintest.NewRecorder(fixture, directadmintest.ModeReplay, nil)
+		if err != nil {
+			t.Skipf("no fixture to replay, record one with LIBDNS_DA_TEST_MODE=record: %v", err)
+		}
+
+		return &Provider{
+			ServerURL: replayServerURL,
+			User:      replayUser,
+			LoginKey:  "replay",
+			Transport: recorder,
+		}, replayZone
+	}
+
+	// The environment may already be configured, e.g. by the container harness
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && len(os.Getenv("LIBDNS_DA_TEST_SERVER_URL")) == 0 {
 		fmt.Println("Error loading .env file")
 		os.Exit(1)
 	}
@@ -31,9 +82,44 @@ func initProvider() (*Provider, string) {
 		LoginKey:         envOrFail("LIBDNS_DA_TEST_LOGIN_KEY"),
 		InsecureRequests: insecureRequest,
 	}
+
+	if mode == testModeRecord {
+		serverURL, err := url.Parse(provider.ServerURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Requests still need to reach the panel, the fixture is sanitized
+		// on the way to disk
+		next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return provider.newTransport().RoundTrip(req)
+		})
+		recorder, err := directadmintest.NewRecorder(fixture, directadmintest.ModeRecord, next,
+			strings.TrimSuffix(zone, "."), strings.TrimSuffix(replayZone, "."),
+			serverURL.Hostname(), "da.example.com",
+			provider.User, replayUser,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider.Transport = recorder
+
+		t.Cleanup(func() {
+			if err := recorder.Save(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
 	return provider, zone
 }
 
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 func defaultEnv(key, fallback string) string {
 	val := os.Getenv(key)
 	if len(val) == 0 {
@@ -60,7 +146,7 @@ func TestProvider_GetRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
+	provider, zone := initProvider(t)
 
 	// list records
 	records, err := provider.GetRecords(ctx, zone)
@@ -83,8 +169,10 @@ func TestProvider_InsecureGetRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
-	provider.ServerURL = envOrFail("LIBDNS_DA_TEST_INSECURE_SERVER_URL")
+	provider, zone := initProvider(t)
+	if testMode() != testModeReplay {
+		provider.ServerURL = envOrFail("LIBDNS_DA_TEST_INSECURE_SERVER_URL")
+	}
 	provider.InsecureRequests = true
 
 	// list records
@@ -108,7 +196,7 @@ func TestProvider_AppendRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
+	provider, zone := initProvider(t)
 
 	var tests = []struct {
 		records       []libdns.Record
@@ -208,7 +296,7 @@ func TestProvider_DotZoneAppendRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
+	provider, zone := initProvider(t)
 	if zone[len(zone)-1:] != "." {
 		zone = zone + "."
 	}
@@ -261,7 +349,7 @@ func TestProvider_SetRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
+	provider, zone := initProvider(t)
 
 	var tests = []struct {
 		records       []libdns.Record
@@ -344,7 +432,7 @@ func TestProvider_DeleteRecords(t *testing.T) {
 	ctx := context.TODO()
 
 	// Configure the DNS provider
-	provider, zone := initProvider()
+	provider, zone := initProvider(t)
 
 	var tests = []struct {
 		records       []libdns.Record
@@ -0,0 +1,118 @@
+package directadmin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrInvalidRecord is returned for records AppendRecords and SetRecords
+// refuse to send to DirectAdmin. The error is a *RecordError.
+var ErrInvalidRecord = errors.New("invalid record")
+
+// maxTTL is the largest TTL DNS allows, see RFC 2181.
+const maxTTL = math.MaxInt32 * time.Second
+
+// recordTTL returns the TTL to write for a record given with ttl, which is
+// DefaultTTL for records without one.
+func (p *Provider) recordTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return p.DefaultTTL
+	}
+
+	return ttl
+}
+
+// RecordError describes what is wrong with a record.
+type RecordError struct {
+	Record libdns.Record
+
+	// Field is the field of the record that is invalid: name, type, value,
+	// priority or ttl
+	Field  string
+	Reason string
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%v: %v %v %v: %v", ErrInvalidRecord, e.Record.Type, e.Record.Name, e.Field, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidRecord, or ErrInvalidTTL for an
+// invalid TTL, which DirectAdmin would have reported.
+func (e *RecordError) Is(target error) bool {
+	return target == ErrInvalidRecord || (e.Field == "ttl" && target == ErrInvalidTTL)
+}
+
+// ValidateRecord checks a record without contacting DirectAdmin: that the
+// name is a valid relative or fully qualified name, A and AAAA values are
+// IPv4 and IPv6 addresses, CNAME, NS and MX values are host names, and the
+// priority and TTL are in range. Values of other types aren't checked. It
+// returns a *RecordError describing the first problem found, or nil.
+func ValidateRecord(record libdns.Record) error {
+	invalid := func(field, format string, args ...interface{}) error {
+		return &RecordError{Record: record, Field: field, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if len(record.Type) == 0 {
+		return invalid("type", "missing")
+	}
+
+	if record.Name != "" && record.Name != "@" && !validRecordName(record.Name) {
+		return invalid("name", "%q is not a valid record name", record.Name)
+	}
+
+	switch record.Type {
+	case "A":
+		if ip := net.ParseIP(record.Value); ip == nil || ip.To4() == nil || strings.Contains(record.Value, ":") {
+			return invalid("value", "%q is not an IPv4 address", record.Value)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(record.Value); ip == nil || !strings.Contains(record.Value, ":") {
+			return invalid("value", "%q is not an IPv6 address", record.Value)
+		}
+	case "CNAME", "NS", "MX":
+		if !validHostname(strings.ToLower(strings.TrimSuffix(record.Value, "."))) {
+			return invalid("value", "%q is not a host name", record.Value)
+		}
+	}
+
+	if record.Priority > math.MaxUint16 {
+		return invalid("priority", "%d is larger than %d", record.Priority, math.MaxUint16)
+	}
+
+	switch {
+	case record.TTL < 0:
+		return invalid("ttl", "%v is negative", record.TTL)
+	case record.TTL > maxTTL:
+		return invalid("ttl", "%v is longer than %v", record.TTL, maxTTL)
+	}
+
+	return nil
+}
+
+// validateRecords checks every record with ValidateRecord.
+func validateRecords(records []libdns.Record) error {
+	for _, record := range records {
+		if err := ValidateRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validRecordName reports whether name is a valid relative or fully
+// qualified record name. The leftmost label may be a wildcard.
+func validRecordName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "*" {
+		return true
+	}
+
+	return validHostname(strings.TrimPrefix(name, "*."))
+}
@@ -0,0 +1,70 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestValidateRecord(t *testing.T) {
+	var tests = []struct {
+		record libdns.Record
+		field  string
+	}{
+		{record: libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: time.Hour}},
+		{record: libdns.Record{Type: "A", Name: "@", Value: "192.0.2.1"}},
+		{record: libdns.Record{Type: "A", Name: "*.dev", Value: "192.0.2.1"}},
+		{record: libdns.Record{Type: "AAAA", Name: "www.example.com.", Value: "2001:db8::1"}},
+		{record: libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token with spaces"}},
+		{record: libdns.Record{Type: "CNAME", Name: "docs", Value: "example.github.io."}},
+		{record: libdns.Record{Type: "MX", Name: "@", Value: "mail", Priority: 10}},
+		{record: libdns.Record{Type: "A", Name: "www", Value: "www"}, field: "value"},
+		{record: libdns.Record{Type: "A", Name: "www", Value: "2001:db8::1"}, field: "value"},
+		{record: libdns.Record{Type: "AAAA", Name: "www", Value: "192.0.2.1"}, field: "value"},
+		{record: libdns.Record{Type: "CNAME", Name: "docs", Value: "not a host"}, field: "value"},
+		{record: libdns.Record{Type: "NS", Name: "sub", Value: "-ns1.example.net"}, field: "value"},
+		{record: libdns.Record{Type: "MX", Name: "@", Value: "mail", Priority: 70000}, field: "priority"},
+		{record: libdns.Record{Type: "A", Name: "bad name", Value: "192.0.2.1"}, field: "name"},
+		{record: libdns.Record{Type: "A", Name: "www..example", Value: "192.0.2.1"}, field: "name"},
+		{record: libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: -time.Second}, field: "ttl"},
+		{record: libdns.Record{Name: "www", Value: "192.0.2.1"}, field: "type"},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRecord(tt.record)
+		if len(tt.field) == 0 {
+			if err != nil {
+				t.Errorf("%v: expected no error, got %v", tt.record, err)
+			}
+			continue
+		}
+
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || !errors.Is(err, ErrInvalidRecord) || recordErr.Field != tt.field {
+			t.Errorf("%v: expected an invalid %v, got %v", tt.record, tt.field, err)
+		}
+	}
+
+	err := ValidateRecord(libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: -time.Second})
+	if !errors.Is(err, ErrInvalidTTL) {
+		t.Errorf("expected an invalid TTL to be ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestFake_InvalidRecordsNotSent(t *testing.T) {
+	provider, server := newFakeProvider(t)
+
+	_, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{
+		{Type: "A", Name: "api", Value: "192.0.2.2"},
+		{Type: "A", Name: "www", Value: "not-an-ip"},
+	})
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord, got %v", err)
+	}
+	if n := server.RequestCount("CMD_API_DNS_CONTROL"); n != 0 {
+		t.Errorf("expected no request to be made, got %d", n)
+	}
+}
@@ -0,0 +1,148 @@
+//go:build go1.23
+
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordsIter returns the records of the zone like GetRecords, but decodes
+// and yields them one at a time instead of building the whole slice, for
+// tools walking zones with tens of thousands of records. Records
+// DirectAdmin returns in a form the provider doesn't support are skipped.
+// If the zone can't be read, or a record fails to decode, the error is
+// yielded once and iteration stops. The same records are left out as by
+// GetRecords, but SortRecords has no effect, the records come in
+// DirectAdmin's order.
+func (p *Provider) RecordsIter(ctx context.Context, zone string) iter.Seq2[libdns.Record, error] {
+	return func(yield func(libdns.Record, error) bool) {
+		zone := strings.TrimSuffix(zone, ".")
+
+		var err error
+		ctx, end := p.startOperation(ctx, "RecordsIter", zone)
+		defer func() { end(&err) }()
+
+		managed := zone
+		if cached, ok := p.managedZones.Load(zone); ok {
+			managed = cached.(string)
+		}
+
+		resp, err := p.zoneResponse(ctx, managed)
+		if errors.Is(err, ErrDomainNotFound) && managed == zone {
+			// DirectAdmin doesn't know the zone, it may be a subdomain
+			managed, err = p.managedZone(ctx, zone, false)
+			if err == nil && managed != zone {
+				resp, err = p.zoneResponse(ctx, managed)
+			} else if err == nil {
+				err = fmt.Errorf("%w: %v", ErrDomainNotFound, zone)
+			}
+		}
+		if err != nil {
+			yield(libdns.Record{}, err)
+			return
+		}
+
+		// Default records are told apart by the apex's addresses, which
+		// may come after them
+		var apex map[string]bool
+		if p.HideDefaultRecords {
+			apex = make(map[string]bool)
+			err = p.decodeRecords(ctx, resp.Body, zone, managed, func(rec libdns.Record) bool {
+				if isApexAddress(zone, rec) {
+					apex[rec.Value] = true
+				}
+				return true
+			})
+			if err != nil {
+				yield(libdns.Record{}, err)
+				return
+			}
+		}
+
+		err = p.decodeRecords(ctx, resp.Body, zone, managed, func(rec libdns.Record) bool {
+			return !p.isVisible(zone, rec, apex) || yield(rec, nil)
+		})
+		if err != nil {
+			yield(libdns.Record{}, err)
+		}
+	}
+}
+
+// decodeRecords decodes the records of a zone response one at a time,
+// calling fn with each until it returns false.
+func (p *Provider) decodeRecords(ctx context.Context, body []byte, zone, managed string, fn func(libdns.Record) bool) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if err := seekRecords(dec); err != nil {
+		return fmt.Errorf("failed to decode zone %v: %w", zone, err)
+	}
+
+	for dec.More() {
+		var daRec daRecord
+		if err := dec.Decode(&daRec); err != nil {
+			return fmt.Errorf("failed to decode zone %v: %w", zone, err)
+		}
+
+		rec, err := daRec.libdnsRecord(managed)
+		if errors.Is(err, ErrUnsupported) {
+			p.logger(ctx).Warnw("unsupported record conversion", "type", rec.Type, "name", rec.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		restored := p.restoreRecordsForZone([]libdns.Record{rec}, zone, managed)
+		if len(restored) == 0 {
+			continue
+		}
+		if !fn(restored[0]) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// seekRecords advances dec, positioned at the start of a zone response,
+// into its records array.
+func seekRecords(dec *json.Decoder) error {
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return errors.New("expected an object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if tok == "records" {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok != json.Delim('[') {
+				return errors.New("expected a records array")
+			}
+			return nil
+		}
+
+		// Skip the value of any other key
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+
+	return errors.New("no records in response")
+}
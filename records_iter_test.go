@@ -0,0 +1,108 @@
+//go:build go1.23
+
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestFake_RecordsIter(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newFakeProvider(t)
+
+	want, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got int
+	for rec, err := range provider.RecordsIter(ctx, fakeZone) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec != want[got] {
+			t.Errorf("record %d: expected %v, got %v", got, want[got], rec)
+		}
+		got++
+	}
+	if got != len(want) {
+		t.Errorf("expected %d records, got %d", len(want), got)
+	}
+
+	// Stopping early is fine
+	for range provider.RecordsIter(ctx, fakeZone) {
+		break
+	}
+
+	for _, err := range provider.RecordsIter(ctx, "example.org") {
+		if !errors.Is(err, ErrZoneNotFound) {
+			t.Errorf("expected ErrZoneNotFound, got %v", err)
+		}
+	}
+}
+
+func TestFake_RecordsIterFilters(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.OwnerID = "test"
+	provider.LeaseLock = true
+	provider.HideDefaultRecords = true
+
+	// The default www record comes before the apex address it points at
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+		directadmintest.Record{Type: "A", Name: fakeZone + ".", Value: "192.0.2.1", TTL: 300},
+	)
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "owned", Value: "token"}}); err != nil {
+		t.Fatal(err)
+	}
+	server.AddZone(fakeZone, append(server.Records(fakeZone),
+		directadmintest.Record{Type: "TXT", Name: LeaseRecordName, Value: fmt.Sprintf("token=other expires=%d", time.Now().Add(time.Hour).Unix()), TTL: 60},
+	)...)
+
+	want, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 {
+		t.Fatalf("expected the apex and the owned record, got %v", want)
+	}
+
+	var got []libdns.Record
+	for rec, err := range provider.RecordsIter(ctx, fakeZone) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected the same records as GetRecords, got %v and %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("record %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestFake_RecordsIterSubdomain(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.AutoCreateSubdomains = true
+
+	for _, err := range provider.RecordsIter(ctx, "shop."+fakeZone) {
+		if !errors.Is(err, ErrDomainNotFound) {
+			t.Errorf("expected ErrDomainNotFound, got %v", err)
+		}
+	}
+	if subdomains := server.Subdomains(fakeZone); len(subdomains) != 0 {
+		t.Errorf("expected nothing to be created, got subdomains %v", subdomains)
+	}
+}
@@ -0,0 +1,89 @@
+package directadmin
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// redactURL returns the URL without user info and with every query value
+// replaced, so it can be logged or returned in errors.
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+
+	if len(c.RawQuery) > 0 {
+		query := c.Query()
+		for key := range query {
+			query[key] = []string{redacted}
+		}
+		c.RawQuery = query.Encode()
+	}
+
+	return c.String()
+}
+
+// redactRawURL is like redactURL for URLs that may not parse.
+func redactRawURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+
+	return redactURL(u)
+}
+
+// redactedError replaces the message of an error while keeping it
+// unwrappable.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError scrubs URLs and credentials, including any additional
+// secrets given, from errors before they are logged or returned to callers.
+func (p *Provider) redactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = &url.Error{
+			Op:  urlErr.Op,
+			URL: redactRawURL(urlErr.URL),
+			Err: urlErr.Err,
+		}
+	}
+
+	msg := err.Error()
+	if scrubbed := p.scrubSecrets(msg, secrets...); scrubbed != msg {
+		return &redactedError{msg: scrubbed, err: err}
+	}
+
+	return err
+}
+
+// scrubSecrets replaces the login key, any additional secrets given and the
+// credentials of ServerURL in s.
+func (p *Provider) scrubSecrets(s string, secrets ...string) string {
+	p.keyCache.mutex.Lock()
+	secrets = append(secrets, p.LoginKey, p.keyCache.key)
+	p.keyCache.mutex.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	if u, err := url.Parse(p.ServerURL); err == nil && u.User != nil {
+		s = strings.ReplaceAll(s, p.ServerURL, redactURL(u))
+	}
+
+	return s
+}
@@ -0,0 +1,166 @@
+package directadmin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+// ownerRefreshInterval limits how often the owners are reloaded because a
+// zone wasn't found, so requests for unknown zones don't each list every
+// user's domains.
+const ownerRefreshInterval = time.Minute
+
+// ownerCache maps the domains a reseller can manage to the users owning
+// them.
+type ownerCache struct {
+	mutex    sync.RWMutex
+	byDomain map[string]string
+	loaded   time.Time
+}
+
+// zoneOwner returns the user owning the zone, or "" if the reseller owns
+// it itself.
+func (p *Provider) zoneOwner(ctx context.Context, zone string) (string, error) {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	// Concurrent requests for known zones only need to share the cache,
+	// they don't wait for each other
+	p.owners.mutex.RLock()
+	owner, ok := p.owners.byDomain[zone]
+	p.owners.mutex.RUnlock()
+	if ok {
+		return owner, nil
+	}
+
+	p.owners.mutex.Lock()
+	defer p.owners.mutex.Unlock()
+
+	// Another request may have loaded the owners in the meantime
+	if owner, ok := p.owners.byDomain[zone]; ok {
+		return owner, nil
+	}
+	if p.owners.byDomain != nil && time.Since(p.owners.loaded) < ownerRefreshInterval {
+		return "", fmt.Errorf("%w: %v isn't owned by the reseller or its users", ErrDomainNotFound, zone)
+	}
+
+	owners, err := p.loadOwners(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the owner of %v: %w", zone, err)
+	}
+	p.owners.byDomain = owners
+	p.owners.loaded = time.Now()
+
+	owner, ok = owners[zone]
+	if !ok {
+		return "", fmt.Errorf("%w: %v isn't owned by the reseller or its users", ErrDomainNotFound, zone)
+	}
+
+	return owner, nil
+}
+
+// loadOwners lists the domains of the reseller and of each of its users.
+func (p *Provider) loadOwners(ctx context.Context) (map[string]string, error) {
+	owners := make(map[string]string)
+
+	domains, err := p.listDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range domains {
+		owners[strings.ToLower(domain)] = ""
+	}
+
+	body, err := p.showList(ctx, "CMD_API_SHOW_USERS", nil)
+	if err != nil {
+		return nil, err
+	}
+	users, err := daapi.ParseList("CMD_API_SHOW_USERS", body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		body, err := p.showList(ctx, "CMD_API_SHOW_USER_DOMAINS", url.Values{"user": {user}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the domains of %v: %w", user, err)
+		}
+		domains, err := parseUserDomains(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the domains of %v: %w", user, err)
+		}
+		for _, domain := range domains {
+			if _, ok := owners[strings.ToLower(domain)]; !ok {
+				owners[strings.ToLower(domain)] = user
+			}
+		}
+	}
+
+	return owners, nil
+}
+
+// resellerDomains returns every domain the reseller can manage.
+func (p *Provider) resellerDomains(ctx context.Context) ([]string, error) {
+	p.owners.mutex.Lock()
+	defer p.owners.mutex.Unlock()
+
+	owners, err := p.loadOwners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.owners.byDomain = owners
+	p.owners.loaded = time.Now()
+
+	domains := make([]string, 0, len(owners))
+	for domain := range owners {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	return domains, nil
+}
+
+// parseUserDomains parses the response of CMD_API_SHOW_USER_DOMAINS, which
+// maps each domain to its usage, as JSON or url-encoded.
+func parseUserDomains(body []byte) ([]string, error) {
+	var usage map[string]interface{}
+	if err := json.Unmarshal(body, &usage); err == nil {
+		if _, ok := usage["error"]; ok {
+			var result daapi.Result
+			if err := json.Unmarshal(body, &result); err == nil && result.Failed() {
+				return nil, result.Err("CMD_API_SHOW_USER_DOMAINS")
+			}
+		}
+
+		domains := make([]string, 0, len(usage))
+		for domain := range usage {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		return domains, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected CMD_API_SHOW_USER_DOMAINS response: %v", err)
+	}
+	if values.Get("error") == "1" {
+		result := daapi.Result{Error: "1", Text: values.Get("text"), Details: values.Get("details")}
+		return nil, result.Err("CMD_API_SHOW_USER_DOMAINS")
+	}
+
+	domains := make([]string, 0, len(values))
+	for domain := range values {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	return domains, nil
+}
@@ -0,0 +1,81 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProvider_Reseller(t *testing.T) {
+	var users []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, key, _ := r.BasicAuth()
+		if key != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/CMD_API_SHOW_DOMAINS":
+			_, _ = w.Write([]byte(`["reseller.example"]`))
+		case "/CMD_API_SHOW_USERS":
+			_, _ = w.Write([]byte(`["alice","bob"]`))
+		case "/CMD_API_SHOW_USER_DOMAINS":
+			switch r.URL.Query().Get("user") {
+			case "alice":
+				_, _ = w.Write([]byte(`{"alice.example":"0.0:unlimited:0.0:unlimited:0:0:0:ON:ON:ON"}`))
+			case "bob":
+				_, _ = w.Write([]byte(`bob.example=0.0:unlimited&bob.test=0.0:unlimited`))
+			}
+		case "/CMD_API_DNS_CONTROL":
+			users = append(users, user)
+			_, _ = w.Write([]byte(`{"records":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := &Provider{
+		ServerURL:         server.URL,
+		User:              "reseller",
+		LoginKey:          "key",
+		Reseller:          true,
+		AllowInsecureHTTP: true,
+	}
+
+	ctx := context.Background()
+	for _, zone := range []string{"reseller.example", "alice.example.", "bob.test"} {
+		if _, err := provider.GetRecords(ctx, zone); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"reseller", "reseller|alice", "reseller|bob"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("expected requests as %v, got %v", want, users)
+	}
+
+	if _, err := provider.GetRecords(ctx, "unknown.example"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("expected ErrDomainNotFound for a zone no user owns, got %v", err)
+	}
+
+	zones, err := provider.ListZones(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []libdns.Zone{{Name: "alice.example."}, {Name: "bob.example."}, {Name: "bob.test."}, {Name: "reseller.example."}}
+	if !reflect.DeepEqual(zones, want) {
+		t.Errorf("expected zones %v, got %v", want, zones)
+	}
+
+	managed, owner, err := provider.ResolveZone(ctx, "shop.alice.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if managed != "alice.example" || owner != "alice" {
+		t.Errorf("expected alice.example owned by alice, got %v owned by %v", managed, owner)
+	}
+}
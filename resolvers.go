@@ -0,0 +1,162 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AuthoritativeNameservers stands for the zone's authoritative nameservers,
+// or SyncNameservers if set, in PropagationResolvers.
+const AuthoritativeNameservers = "authoritative"
+
+// maxDoHResponse is the size of the largest DNS message.
+const maxDoHResponse = 64 << 10
+
+// dohClient sends the queries to DNS-over-HTTPS resolvers.
+var dohClient = &http.Client{Timeout: 5 * time.Second}
+
+// resolverAddress returns the address to query for a resolver given as
+// host:port, an IP address, which is queried on port 53, or the https:// URL
+// of a DNS-over-HTTPS endpoint.
+func resolverAddress(resolver string) (string, error) {
+	if strings.HasPrefix(resolver, "https://") {
+		u, err := url.Parse(resolver)
+		if err != nil || len(u.Host) == 0 {
+			return "", fmt.Errorf("resolver %q is not a valid DNS-over-HTTPS url", resolver)
+		}
+		return resolver, nil
+	}
+
+	if ip := net.ParseIP(strings.Trim(resolver, "[]")); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		return "", fmt.Errorf("resolver %q must be host:port, an ip address or an https:// url", resolver)
+	}
+
+	return resolver, nil
+}
+
+// propagationTargets returns whether the authoritative nameservers are
+// polled while waiting for a change, and the other resolvers to poll.
+func (p *Provider) propagationTargets() (bool, []string) {
+	if len(p.PropagationResolvers) == 0 {
+		return true, nil
+	}
+
+	authoritative := false
+	var resolvers []string
+	for _, resolver := range p.PropagationResolvers {
+		if resolver == AuthoritativeNameservers {
+			authoritative = true
+			continue
+		}
+		resolvers = append(resolvers, resolver)
+	}
+
+	return authoritative, resolvers
+}
+
+// newResolver returns a resolver sending every query to addr, as returned
+// by resolverAddress.
+func newResolver(addr string) *net.Resolver {
+	if !strings.HasPrefix(addr, "https://") {
+		return resolverFor(addr)
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, url: addr}, nil
+		},
+	}
+}
+
+// dohConn carries the queries of a net.Resolver to a DNS-over-HTTPS
+// endpoint (RFC 8484). Not being a net.PacketConn, the resolver frames
+// messages as over TCP, each preceded by its length.
+type dohConn struct {
+	ctx      context.Context
+	url      string
+	query    bytes.Buffer
+	response *bytes.Reader
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	// A new query after the previous response was read
+	if c.response != nil && c.response.Len() == 0 {
+		c.response = nil
+	}
+
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.response == nil {
+		if err := c.exchange(); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.response.Read(b)
+}
+
+// exchange posts the buffered query and buffers the response.
+func (c *dohConn) exchange() error {
+	query := c.query.Bytes()
+	if len(query) < 2 || len(query) < 2+int(binary.BigEndian.Uint16(query)) {
+		return errors.New("incomplete dns query")
+	}
+	msg := query[2 : 2+int(binary.BigEndian.Uint16(query))]
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dns-over-https resolver responded with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDoHResponse))
+	if err != nil {
+		return err
+	}
+
+	framed := make([]byte, 2, 2+len(body))
+	binary.BigEndian.PutUint16(framed, uint16(len(body)))
+	c.query.Reset()
+	c.response = bytes.NewReader(append(framed, body...))
+
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr("") }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(t time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,89 @@
+package directadmin
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ChangeResult is what DirectAdmin reported for a change it applied.
+type ChangeResult struct {
+	// Command and Action are the DirectAdmin command and its action, e.g.
+	// `CMD_API_DNS_CONTROL` and `add`
+	Command string
+	Action  string
+
+	// Zone is the zone the change was made in
+	Zone string
+
+	// Message is DirectAdmin's success text, e.g. "Record Added"
+	Message string
+
+	// Warnings are the further lines DirectAdmin returned with the success,
+	// if any
+	Warnings []string
+}
+
+// ResultCollector gathers the ChangeResult of every change made with a
+// context from CollectResults. It is safe for concurrent use.
+type ResultCollector struct {
+	mutex   sync.Mutex
+	results []ChangeResult
+}
+
+// Results returns the results collected so far, in the order the changes
+// were made.
+func (c *ResultCollector) Results() []ChangeResult {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]ChangeResult(nil), c.results...)
+}
+
+func (c *ResultCollector) add(result ChangeResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.results = append(c.results, result)
+}
+
+type resultCollectorKey struct{}
+
+// CollectResults returns a context that collects what DirectAdmin reports
+// for the changes made with it, such as by AppendRecords, which the
+// methods' return values leave out.
+func CollectResults(ctx context.Context) (context.Context, *ResultCollector) {
+	collector := &ResultCollector{}
+	return context.WithValue(ctx, resultCollectorKey{}, collector), collector
+}
+
+// collectResult adds the result of a successful request to the collector
+// of ctx, if there is one.
+func collectResult(ctx context.Context, req *APIRequest, result *daResponse) {
+	collector, ok := ctx.Value(resultCollectorKey{}).(*ResultCollector)
+	if !ok {
+		return
+	}
+
+	message := result.Success
+	if len(message) == 0 {
+		message = result.Text
+	}
+
+	var warnings []string
+	for _, details := range []string{result.Result, result.Details} {
+		for _, line := range strings.Split(details, "\n") {
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				warnings = append(warnings, line)
+			}
+		}
+	}
+
+	collector.add(ChangeResult{
+		Command:  req.Command,
+		Action:   req.Params.Get("action"),
+		Zone:     req.Zone,
+		Message:  message,
+		Warnings: warnings,
+	})
+}
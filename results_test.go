@@ -0,0 +1,54 @@
+package directadmin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_CollectResults(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+
+	ctx, collector := CollectResults(context.Background())
+	records, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.DeleteRecords(ctx, fakeZone, records); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ChangeResult{
+		{Command: "CMD_API_DNS_CONTROL", Action: "add", Zone: fakeZone, Message: "Record Added"},
+		{Command: "CMD_API_DNS_CONTROL", Action: "select", Zone: fakeZone, Message: "Records Deleted"},
+	}
+	if got := collector.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected results %+v, got %+v", want, got)
+	}
+}
+
+func TestProvider_ResultWarnings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":"Record Added","result":"Zone reloaded\nSerial not increased"}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, collector := CollectResults(context.Background())
+	if _, err := provider.appendZoneRecord(ctx, "example.com", libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	results := collector.Results()
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Warnings, []string{"Zone reloaded", "Serial not increased"}) {
+		t.Errorf("expected the result lines as warnings, got %+v", results)
+	}
+}
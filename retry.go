@@ -0,0 +1,334 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/directadmin/daapi"
+)
+
+const (
+	defaultMaxRetries      = 3
+	defaultRetryMaxDelay   = time.Minute
+	defaultRetryDelay      = time.Second
+	defaultRetryMultiplier = 2
+
+	// zoneRewriteRetries is how often a zone read is repeated when it
+	// failed because DirectAdmin was rewriting the zone file
+	zoneRewriteRetries = 2
+
+	defaultZoneLockTimeout = 30 * time.Second
+	maxZoneLockDelay       = 5 * time.Second
+)
+
+// zoneRewriteDelay is how long to wait before the first repeated zone read,
+// each further one waits as long again.
+var zoneRewriteDelay = 250 * time.Millisecond
+
+// zoneLockDelay is how long to wait before repeating a change rejected
+// because the zone was locked, doubling for every further attempt up to
+// maxZoneLockDelay.
+var zoneLockDelay = 500 * time.Millisecond
+
+// jitter is the source of random delays. The global source isn't seeded
+// before Go 1.20, so every process would otherwise draw the same delays.
+var jitter = &lockedRand{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// lockedRand is a rand.Rand safe for concurrent use.
+type lockedRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()
+}
+
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Int63n(n)
+}
+
+// RetryPolicy configures how requests DirectAdmin rejected because it was
+// throttled or busy are retried. Zero fields take their defaults.
+type RetryPolicy struct {
+	// InitialDelay is the delay before the first retry, 1s by default
+	InitialDelay time.Duration `json:"initial_delay,omitempty"`
+
+	// Multiplier grows the delay for every further retry, 2 by default
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// MaxDelay caps the delay between two attempts, including the delay a
+	// Retry-After header asks for, 1m by default
+	MaxDelay time.Duration `json:"max_delay,omitempty"`
+
+	// Jitter randomizes every delay by up to this fraction of it, so
+	// instances throttled at the same time don't retry in lockstep. Backoff
+	// delays move in either direction, e.g. ±20% for 0.2, while Retry-After
+	// delays are only lengthened. MaxDelay caps the result either way.
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// MaxAttempts is how often a request is sent at most, including the
+	// first attempt. It defaults to MaxRetries+1.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// MaxElapsed is how long a request is retried at most, counted from
+	// its first attempt. Unlimited by default, though the context's
+	// deadline always applies.
+	MaxElapsed time.Duration `json:"max_elapsed,omitempty"`
+}
+
+// validate returns the problems with the policy's fields.
+func (rp RetryPolicy) validate() []error {
+	var errs []error
+	if rp.InitialDelay < 0 || rp.MaxDelay < 0 || rp.MaxElapsed < 0 {
+		errs = append(errs, errors.New("retry policy delays must not be negative"))
+	}
+	if rp.Multiplier != 0 && rp.Multiplier < 1 {
+		errs = append(errs, fmt.Errorf("retry policy multiplier %v must be at least 1", rp.Multiplier))
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("retry policy jitter %v must be between 0 and 1", rp.Jitter))
+	}
+	if rp.MaxAttempts < 0 {
+		errs = append(errs, errors.New("retry policy max attempts must not be negative"))
+	}
+
+	return errs
+}
+
+// delay returns how long to wait before retrying after attempt, counted
+// from 0, given the delay a Retry-After header asked for, if any.
+func (rp RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	maxDelay := rp.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	var delay time.Duration
+	if retryAfter > 0 {
+		delay = retryAfter
+		if rp.Jitter > 0 {
+			delay += time.Duration(jitter.Float64() * rp.Jitter * float64(delay))
+		}
+	} else {
+		initial, multiplier := rp.InitialDelay, rp.Multiplier
+		if initial == 0 {
+			initial = defaultRetryDelay
+		}
+		if multiplier == 0 {
+			multiplier = defaultRetryMultiplier
+		}
+
+		backoff := float64(initial)
+		for i := 0; i < attempt && backoff < float64(maxDelay); i++ {
+			backoff *= multiplier
+		}
+		if rp.Jitter > 0 {
+			backoff += (2*jitter.Float64() - 1) * rp.Jitter * backoff
+		}
+		delay = time.Duration(backoff)
+	}
+
+	if delay > maxDelay || delay < 0 {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+// maxRetries returns how often a throttled request is retried.
+func (p *Provider) maxRetries() int {
+	switch {
+	case p.RetryPolicy.MaxAttempts > 0:
+		return p.RetryPolicy.MaxAttempts - 1
+	case p.MaxRetries < 0:
+		return 0
+	case p.MaxRetries == 0:
+		return defaultMaxRetries
+	default:
+		return p.MaxRetries
+	}
+}
+
+// retryMiddleware retries requests DirectAdmin, or a proxy in front of it,
+// rejected with 429 Too Many Requests or 503 Service Unavailable, waiting as
+// long as the Retry-After header asks for.
+func (p *Provider) retryMiddleware(next Handler) Handler {
+	return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+		start := time.Now()
+		for attempt := 0; ; attempt++ {
+			resp, err := next(ctx, req)
+			if err != nil || !throttled(resp) || attempt >= p.maxRetries() {
+				return resp, err
+			}
+
+			// A blacklisted client won't be let in by waiting, and an update
+			// of the panel takes longer than is worth waiting for here
+			if err := daapi.CheckResponse(req.Command, resp); errors.Is(err, ErrIPBlacklisted) || errors.Is(err, ErrServerMaintenance) {
+				return resp, nil
+			}
+
+			delay := p.RetryPolicy.delay(attempt, retryAfter(resp.Header.Get("Retry-After"), time.Now()))
+
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return resp, nil
+			}
+			if maxElapsed := p.RetryPolicy.MaxElapsed; maxElapsed > 0 && time.Since(start)+delay > maxElapsed {
+				return resp, nil
+			}
+
+			p.logger(ctx).Warnw("request throttled, retrying",
+				"command", req.Command,
+				"status_code", resp.StatusCode,
+				"attempt", attempt+1,
+				"delay", delay)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func throttled(resp *APIResponse) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns 0 if the header is missing or invalid.
+func retryAfter(header string, now time.Time) time.Duration {
+	if len(header) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// partialZoneError is returned for a zone response that isn't valid JSON,
+// as DirectAdmin may send a truncated zone while rewriting the zone file.
+type partialZoneError struct {
+	err error
+}
+
+func (e *partialZoneError) Error() string {
+	return e.err.Error()
+}
+
+func (e *partialZoneError) Unwrap() error {
+	return e.err
+}
+
+// zoneRewritePatterns match the errors DirectAdmin returns while it rewrites
+// a zone file, e.g. right after a record was changed. Each pattern is a list
+// of substrings that all have to appear in the lowercased error text.
+var zoneRewritePatterns = [][]string{
+	{"zone file", "unable"},
+	{"zone file", "cannot"},
+	{"zone file", "could not"},
+	{"zone", "being written"},
+	{"zone", "being updated"},
+}
+
+// zoneRewriting reports whether err from reading a zone is likely due to
+// DirectAdmin rewriting the zone file, so reading it again shortly after
+// succeeds.
+func zoneRewriting(err error) bool {
+	var partial *partialZoneError
+	if errors.As(err, &partial) {
+		return true
+	}
+
+	if errors.Is(err, ErrZoneLocked) {
+		return true
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Unwrap() != nil {
+		return false
+	}
+
+	text := strings.ToLower(apiErr.Message + " " + apiErr.Details)
+	for _, pattern := range zoneRewritePatterns {
+		matched := true
+		for _, substr := range pattern {
+			if !strings.Contains(text, substr) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// zoneLockTimeout returns how long changes rejected because the zone was
+// locked are retried.
+func (p *Provider) zoneLockTimeout() time.Duration {
+	switch {
+	case p.ZoneLockTimeout < 0:
+		return 0
+	case p.ZoneLockTimeout == 0:
+		return defaultZoneLockTimeout
+	default:
+		return p.ZoneLockTimeout
+	}
+}
+
+// waitForZoneLock waits before the next attempt of a change rejected with
+// lockErr, started at start. It returns lockErr if ZoneLockTimeout would
+// expire first, and the context's error if the context ends before the
+// change can be attempted again.
+func (p *Provider) waitForZoneLock(ctx context.Context, req *APIRequest, lockErr error, start time.Time, attempt int) error {
+	delay := zoneLockDelay << attempt
+	if delay > maxZoneLockDelay || delay <= 0 {
+		delay = maxZoneLockDelay
+	}
+
+	if time.Since(start)+delay > p.zoneLockTimeout() {
+		return lockErr
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return fmt.Errorf("%w before the zone was unlocked: %v", context.DeadlineExceeded, lockErr)
+	}
+
+	p.logger(ctx).Warnw("zone locked, retrying",
+		"command", req.Command,
+		"zone", req.Zone,
+		"attempt", attempt+1,
+		"delay", delay)
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return fmt.Errorf("%w before the zone was unlocked: %v", ctx.Err(), lockErr)
+	case <-timer.C:
+		return nil
+	}
+}
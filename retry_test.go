@@ -0,0 +1,227 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: 0},
+		{header: "5", want: 5 * time.Second},
+		{header: "Mon, 01 Jan 2024 12:00:30 GMT", want: 30 * time.Second},
+		{header: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := retryAfter(tt.header, now); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProvider_GetRecordsDuringZoneRewrite(t *testing.T) {
+	delay := zoneRewriteDelay
+	zoneRewriteDelay = time.Millisecond
+	t.Cleanup(func() { zoneRewriteDelay = delay })
+
+	responses := []string{
+		`{"error":"1","text":"Unable to read the zone file"}`,
+		`{"records":[{"type":"A","name":"www","value":"192.0`,
+		`{"records":[{"type":"A","name":"www","value":"192.0.2.1","combined":"name=www&value=192.0.2.1","ttl":"300"}]}`,
+	}
+	var reads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(responses[reads%len(responses)]))
+		reads++
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := provider.GetRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("expected the read to be retried, got %v", err)
+	}
+	if len(records) != 1 || reads != 3 {
+		t.Errorf("expected 1 record after 3 reads, got %d after %d", len(records), reads)
+	}
+
+	// Errors that aren't due to a rewrite fail right away
+	reads = 0
+	responses = []string{`{"error":"1","text":"You cannot perform this action"}`}
+	_, err = provider.GetRecords(context.Background(), "example.com")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || reads != 1 {
+		t.Errorf("expected an APIError after 1 read, got %v after %d", err, reads)
+	}
+
+	// The last error is returned once the retries are used up
+	reads = 0
+	responses = []string{`{"records":[`}
+	_, err = provider.GetRecords(context.Background(), "example.com")
+	if err == nil || reads != zoneRewriteRetries+1 {
+		t.Errorf("expected an error after %d reads, got %v after %d", zoneRewriteRetries+1, err, reads)
+	}
+
+	// Canceling the context during the backoff returns the context's error
+	zoneRewriteDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = provider.GetRecords(ctx, "example.com")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+}
+
+func TestFake_ZoneLocked(t *testing.T) {
+	delay := zoneLockDelay
+	zoneLockDelay = time.Millisecond
+	t.Cleanup(func() { zoneLockDelay = delay })
+
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	lockMessage := "You cannot currently modify this zone"
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+
+	server.FailNext("CMD_API_DNS_CONTROL", lockMessage)
+	server.FailNext("CMD_API_DNS_CONTROL", lockMessage)
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec}); err != nil {
+		t.Fatalf("expected the change to be retried, got %v", err)
+	}
+	if len(server.Records(fakeZone)) != 3 {
+		t.Errorf("expected the record to be added, got %v", server.Records(fakeZone))
+	}
+
+	provider.ZoneLockTimeout = -1
+	server.FailNext("CMD_API_DNS_CONTROL", lockMessage)
+	rec.Value = "other"
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{rec}); !errors.Is(err, ErrZoneLocked) {
+		t.Errorf("expected ErrZoneLocked without retries, got %v", err)
+	}
+
+	// A deadline before the next attempt returns the context's error
+	provider.ZoneLockTimeout = 0
+	zoneLockDelay = time.Second
+	deadlineCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	server.FailNext("CMD_API_DNS_CONTROL", lockMessage)
+	if _, err := provider.AppendRecords(deadlineCtx, fakeZone, []libdns.Record{rec}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be reported, got %v", err)
+	}
+
+	// So does canceling the context while waiting
+	zoneLockDelay = 50 * time.Millisecond
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	server.FailNext("CMD_API_DNS_CONTROL", lockMessage)
+	if _, err := provider.AppendRecords(cancelCtx, fakeZone, []libdns.Record{rec}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be reported, got %v", err)
+	}
+}
+
+func TestProvider_ServerMaintenance(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("<html><body>DirectAdmin is currently being updated</body></html>"))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.GetRecords(context.Background(), "example.com")
+	if !errors.Is(err, ErrServerMaintenance) {
+		t.Fatalf("expected ErrServerMaintenance, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the request not to be retried, got %d requests", requests)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	var tests = []struct {
+		name       string
+		policy     RetryPolicy
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{name: "default", attempt: 2, min: 4 * time.Second, max: 4 * time.Second},
+		{name: "capped", attempt: 10, min: time.Minute, max: time.Minute},
+		{name: "retry-after", attempt: 2, retryAfter: 5 * time.Second, min: 5 * time.Second, max: 5 * time.Second},
+		{name: "custom", policy: RetryPolicy{InitialDelay: 100 * time.Millisecond, Multiplier: 3}, attempt: 2,
+			min: 900 * time.Millisecond, max: 900 * time.Millisecond},
+		{name: "max delay", policy: RetryPolicy{MaxDelay: 3 * time.Second}, attempt: 5, retryAfter: 10 * time.Second,
+			min: 3 * time.Second, max: 3 * time.Second},
+		{name: "jitter", policy: RetryPolicy{Jitter: 0.5}, attempt: 1, min: time.Second, max: 3 * time.Second},
+		{name: "jitter retry-after", policy: RetryPolicy{Jitter: 0.5}, retryAfter: 2 * time.Second,
+			min: 2 * time.Second, max: 3 * time.Second},
+		{name: "jitter retry-after capped", policy: RetryPolicy{Jitter: 0.5, MaxDelay: 2 * time.Second}, retryAfter: 2 * time.Second,
+			min: 2 * time.Second, max: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := tt.policy.delay(tt.attempt, tt.retryAfter); got < tt.min || got > tt.max {
+					t.Fatalf("expected a delay between %v and %v, got %v", tt.min, tt.max, got)
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_RetryPolicy(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(), WithLogger(NopLogger()),
+		WithRetryPolicy(RetryPolicy{InitialDelay: time.Millisecond, MaxAttempts: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.GetRecords(context.Background(), "example.com"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 attempts, got %d", requests)
+	}
+
+	requests = 0
+	provider.RetryPolicy.MaxAttempts = 10
+	provider.RetryPolicy.MaxElapsed = 5 * time.Millisecond
+	provider.RetryPolicy.InitialDelay = 2 * time.Millisecond
+	if _, err := provider.GetRecords(context.Background(), "example.com"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests >= 10 {
+		t.Errorf("expected MaxElapsed to stop the retries, got %d attempts", requests)
+	}
+}
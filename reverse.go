@@ -0,0 +1,95 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ReverseName returns the name of the PTR record of ip, e.g.
+// "1.2.0.192.in-addr.arpa" for 192.0.2.1 and the nibble format under
+// ip6.arpa for IPv6 addresses.
+func ReverseName(ip net.IP) (string, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return "", fmt.Errorf("invalid IP address %v", ip)
+	}
+
+	const hexDigits = "0123456789abcdef"
+	labels := make([]string, 0, 33)
+	for i := len(ip16) - 1; i >= 0; i-- {
+		labels = append(labels, string(hexDigits[ip16[i]&0xF]), string(hexDigits[ip16[i]>>4]))
+	}
+	labels = append(labels, "ip6.arpa")
+
+	return strings.Join(labels, "."), nil
+}
+
+// SetPTR points the reverse DNS of ip at hostname. The reverse zone is the
+// most specific in-addr.arpa or ip6.arpa zone of the user containing the
+// address, e.g. 2.0.192.in-addr.arpa for 192.0.2.1; an existing PTR record
+// for the address is replaced. It returns a *ZoneNotFoundError when the
+// user has no reverse zone for the address. RFC 2317 classless zones are
+// not detected.
+func (p *Provider) SetPTR(ctx context.Context, ip net.IP, hostname string, ttl time.Duration) (_ libdns.Record, err error) {
+	ctx, end := p.startOperation(ctx, "SetPTR", "")
+	defer end(&err)
+
+	name, err := ReverseName(ip)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if !validHostname(host) {
+		return libdns.Record{}, fmt.Errorf("invalid host name %q", hostname)
+	}
+
+	zone, err := p.reverseZone(ctx, name)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	set, err := p.SetRecords(ctx, zone, []libdns.Record{{
+		Type:  "PTR",
+		Name:  relativeName(name, zone),
+		Value: host + ".",
+		TTL:   ttl,
+	}})
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	return set[0], nil
+}
+
+// reverseZone returns the most specific zone of the user containing the
+// reverse name.
+func (p *Provider) reverseZone(ctx context.Context, name string) (string, error) {
+	zones, err := p.listZones(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var zone string
+	for _, candidate := range zones {
+		c := strings.ToLower(candidate)
+		if (name == c || strings.HasSuffix(name, "."+c)) && len(c) > len(zone) {
+			zone = c
+		}
+	}
+
+	if len(zone) == 0 {
+		return "", &ZoneNotFoundError{Zone: name, Available: zones}
+	}
+
+	return zone, nil
+}
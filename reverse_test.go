@@ -0,0 +1,66 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestReverseName(t *testing.T) {
+	var tests = []struct {
+		ip   string
+		want string
+	}{
+		{ip: "192.0.2.1", want: "1.2.0.192.in-addr.arpa"},
+		{ip: "::ffff:192.0.2.1", want: "1.2.0.192.in-addr.arpa"},
+		{ip: "2001:db8::567:89ab", want: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+	}
+
+	for _, tt := range tests {
+		got, err := ReverseName(net.ParseIP(tt.ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.ip, tt.want, got)
+		}
+	}
+
+	if _, err := ReverseName(nil); err == nil {
+		t.Error("expected an invalid address to fail")
+	}
+}
+
+func TestFake_SetPTR(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	server.AddZone("0.192.in-addr.arpa")
+	server.AddZone("2.0.192.in-addr.arpa",
+		directadmintest.Record{Type: "PTR", Name: "1", Value: "old.example.com.", TTL: 300},
+	)
+
+	record, err := provider.SetPTR(ctx, net.ParseIP("192.0.2.1"), "mail.example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "1" || record.Value != "mail.example.com." {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	records := server.Records("2.0.192.in-addr.arpa")
+	if len(records) != 1 || records[0].Value != "mail.example.com." || records[0].TTL != 3600 {
+		t.Errorf("expected the PTR record to be replaced, got %v", records)
+	}
+	if records := server.Records("0.192.in-addr.arpa"); len(records) != 0 {
+		t.Errorf("expected the less specific zone to be untouched, got %v", records)
+	}
+
+	_, err = provider.SetPTR(ctx, net.ParseIP("198.51.100.1"), "mail.example.com", time.Hour)
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("expected ErrZoneNotFound, got %v", err)
+	}
+}
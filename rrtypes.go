@@ -0,0 +1,139 @@
+package directadmin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// recordTypesSince lists the record types DirectAdmin can store, by the
+// version that introduced them. The types of the zero version are
+// supported by every version the provider works with.
+var recordTypesSince = []struct {
+	version daVersion
+	types   []string
+}{
+	{version: daVersion{}, types: []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SRV", "TXT"}},
+	{version: daVersion{1, 50}, types: []string{"CAA"}},
+	{version: daVersion{1, 57}, types: []string{"TLSA"}},
+	{version: daVersion{1, 60}, types: []string{"DS"}},
+	{version: daVersion{1, 64}, types: []string{"HTTPS", "SVCB"}},
+}
+
+// UnsupportedTypeError is returned for records of a type the DirectAdmin
+// server can't store. It matches ErrUnsupported with errors.Is.
+type UnsupportedTypeError struct {
+	Type string
+
+	// Version is the DirectAdmin version the type was checked against
+	Version string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("%v: DirectAdmin %v can't store %v records", ErrUnsupported, e.Version, e.Type)
+}
+
+// Is reports whether target is ErrUnsupported.
+func (e *UnsupportedTypeError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+// daVersion is a DirectAdmin version, e.g. 1.62.
+type daVersion struct {
+	major, minor int
+}
+
+func (v daVersion) less(other daVersion) bool {
+	return v.major < other.major || (v.major == other.major && v.minor < other.minor)
+}
+
+func (v daVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// parseDAVersion parses a version such as `1.62` or `1.62.4`, ignoring
+// anything after the minor version.
+func parseDAVersion(s string) (daVersion, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".", 3)
+	if len(parts) < 2 {
+		return daVersion{}, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return daVersion{}, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return daVersion{}, false
+	}
+
+	return daVersion{major, minor}, true
+}
+
+// serverHeaderVersion matches the version in the Server header DirectAdmin
+// sends, e.g. `DirectAdmin Daemon v1.62.4 Registered to ...`.
+var serverHeaderVersion = regexp.MustCompile(`(?i)DirectAdmin(?: Daemon)? v?(\d+\.\d+(?:\.\d+)?)`)
+
+// noteServerVersion remembers the DirectAdmin version announced in a
+// response's Server header.
+func (p *Provider) noteServerVersion(server string) {
+	m := serverHeaderVersion.FindStringSubmatch(server)
+	if m == nil {
+		return
+	}
+
+	if v, ok := parseDAVersion(m[1]); ok {
+		p.serverVersion.Store(v)
+	}
+}
+
+// daVersion returns the version of the DirectAdmin server: the configured
+// DirectAdminVersion, or else the version the server announced, if any
+// response did so yet.
+func (p *Provider) daVersion() (daVersion, bool) {
+	if len(p.DirectAdminVersion) > 0 {
+		return parseDAVersion(p.DirectAdminVersion)
+	}
+
+	v, ok := p.serverVersion.Load().(daVersion)
+	return v, ok
+}
+
+// supportsType reports whether DirectAdmin version v can store records of
+// the type.
+func supportsType(v daVersion, recordType string) bool {
+	for _, since := range recordTypesSince {
+		if v.less(since.version) {
+			continue
+		}
+		for _, t := range since.types {
+			if strings.EqualFold(t, recordType) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkRecordTypes returns an *UnsupportedTypeError for the first record
+// the DirectAdmin server can't store. Nothing is rejected while the
+// server's version is unknown.
+func (p *Provider) checkRecordTypes(records []libdns.Record) error {
+	v, ok := p.daVersion()
+	if !ok {
+		return nil
+	}
+
+	for _, record := range records {
+		if !supportsType(v, record.Type) {
+			return &UnsupportedTypeError{Type: record.Type, Version: v.String()}
+		}
+	}
+
+	return nil
+}
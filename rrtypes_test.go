@@ -0,0 +1,80 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSupportsType(t *testing.T) {
+	var tests = []struct {
+		version    string
+		recordType string
+		want       bool
+	}{
+		{version: "1.40", recordType: "A", want: true},
+		{version: "1.40", recordType: "CAA", want: false},
+		{version: "1.50", recordType: "CAA", want: true},
+		{version: "1.56.2", recordType: "TLSA", want: false},
+		{version: "1.62.4", recordType: "tlsa", want: true},
+		{version: "1.62", recordType: "HTTPS", want: false},
+		{version: "1.65", recordType: "HTTPS", want: true},
+		{version: "2.0", recordType: "DS", want: true},
+		{version: "1.65", recordType: "NAPTR", want: false},
+	}
+
+	for _, tt := range tests {
+		v, ok := parseDAVersion(tt.version)
+		if !ok {
+			t.Fatalf("failed to parse version %v", tt.version)
+		}
+		if got := supportsType(v, tt.recordType); got != tt.want {
+			t.Errorf("%v %v: expected %v, got %v", tt.version, tt.recordType, tt.want, got)
+		}
+	}
+}
+
+func TestProvider_UnsupportedType(t *testing.T) {
+	var adds int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "DirectAdmin Daemon v1.56.3 Registered to Example")
+		if r.URL.Query().Get("action") == "add" {
+			adds++
+			_, _ = w.Write([]byte(`{"success":"Record added"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"records":[]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	tlsa := libdns.Record{Type: "TLSA", Name: "_443._tcp.www", Value: "3 1 1 abcdef"}
+
+	// The version is unknown before the first response
+	if _, err := provider.AppendRecords(ctx, "example.com", []libdns.Record{tlsa}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.AppendRecords(ctx, "example.com", []libdns.Record{tlsa})
+	var typeErr *UnsupportedTypeError
+	if !errors.As(err, &typeErr) || !errors.Is(err, ErrUnsupported) || typeErr.Version != "1.56" {
+		t.Fatalf("expected an UnsupportedTypeError for 1.56, got %v", err)
+	}
+	if adds != 1 {
+		t.Errorf("expected the rejected record not to be sent, got %d adds", adds)
+	}
+
+	provider.DirectAdminVersion = "1.62"
+	if _, err := provider.AppendRecords(ctx, "example.com", []libdns.Record{tlsa}); err != nil {
+		t.Errorf("expected the configured version to take precedence, got %v", err)
+	}
+}
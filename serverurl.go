@@ -0,0 +1,65 @@
+package directadmin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultPort is the port DirectAdmin listens on out of the box.
+const defaultPort = "2222"
+
+// baseURL returns the parsed ServerURL, see parseServerURL.
+func (p *Provider) baseURL() (*url.URL, error) {
+	return parseServerURL(p.ServerURL, p.AllowInsecureHTTP)
+}
+
+// parseServerURL parses a server url, which may be given as just a host
+// name or IP address. A bare host defaults to https and DirectAdmin's port
+// 2222, unless it has a path prefix; a url with a scheme keeps the scheme's
+// standard port. Plain http is refused unless allowHTTP is set, as it would
+// send the login key in the clear.
+func parseServerURL(raw string, allowHTTP bool) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, errors.New("server url is required")
+	}
+
+	bare := !strings.Contains(raw, "://")
+	if bare {
+		// A bare IPv6 literal needs brackets to tell it apart from a port
+		if ip := net.ParseIP(raw); ip != nil && strings.Contains(raw, ":") {
+			raw = "[" + raw + "]"
+		}
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("server url %v does not parse", redactRawURL(raw))
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowHTTP {
+			return nil, fmt.Errorf("server url %v uses plain http, which sends the login key unencrypted; use https or set AllowInsecureHTTP", redactURL(u))
+		}
+	default:
+		return nil, fmt.Errorf("server url %v must start with https://", redactURL(u))
+	}
+
+	if len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("server url %v has no host", redactURL(u))
+	}
+
+	// An explicit scheme, like a path prefix, points at a reverse proxy or a
+	// panel moved to the standard port rather than DirectAdmin's own
+	if bare && len(u.Port()) == 0 && len(strings.Trim(u.Path, "/")) == 0 {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	return u, nil
+}
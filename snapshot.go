@@ -0,0 +1,222 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ZoneSnapshot is a point-in-time copy of all records of a zone as stored by
+// DirectAdmin. It is safe to serialize with encoding/json.
+type ZoneSnapshot struct {
+	Zone    string           `json:"zone"`
+	Taken   time.Time        `json:"taken"`
+	Records []SnapshotRecord `json:"records"`
+}
+
+// SnapshotRecord is a record in DirectAdmin's own representation, which
+// allows record types the provider can't otherwise convert to be restored.
+type SnapshotRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	TTL   int    `json:"ttl,omitempty"`
+}
+
+// RestoreResult lists the changes RestoreZone made.
+type RestoreResult struct {
+	Deleted []libdns.Record `json:"deleted"`
+	Added   []libdns.Record `json:"added"`
+}
+
+// SnapshotZone captures every record of the zone.
+func (p *Provider) SnapshotZone(ctx context.Context, zone string) (_ *ZoneSnapshot, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "SnapshotZone", zone)
+	defer end(&err)
+
+	return p.snapshotZone(ctx, zone)
+}
+
+func (p *Provider) snapshotZone(ctx context.Context, zone string) (*ZoneSnapshot, error) {
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &ZoneSnapshot{
+		Zone:    zone,
+		Taken:   time.Now(),
+		Records: make([]SnapshotRecord, 0, len(respData.Records)),
+	}
+
+	for _, rec := range respData.Records {
+		if rec.isLease() {
+			continue
+		}
+
+		snapRec := SnapshotRecord{
+			Type:  rec.Type,
+			Name:  rec.Name,
+			Value: rec.Value,
+		}
+
+		if len(rec.TTL) > 0 {
+			snapRec.TTL, err = strconv.Atoi(rec.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse TTL for %v: %v", rec.Name, err)
+			}
+		}
+
+		snapshot.Records = append(snapshot.Records, snapRec)
+	}
+
+	return snapshot, nil
+}
+
+// RestoreZone brings the zone back to the state captured in the snapshot by
+// deleting records that were added since and re-creating records that were
+// removed or changed. SOA records and leases are left untouched. If
+// snapshot.Zone is set, it must match zone.
+func (p *Provider) RestoreZone(ctx context.Context, zone string, snapshot *ZoneSnapshot) (_ *RestoreResult, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "RestoreZone", zone)
+	defer end(&err)
+
+	var result *RestoreResult
+	err = p.leased(ctx, zone, func(ctx context.Context) (err error) {
+		result, err = p.restoreZone(ctx, zone, snapshot)
+		return err
+	})
+
+	return result, err
+}
+
+func (p *Provider) restoreZone(ctx context.Context, zone string, snapshot *ZoneSnapshot) (*RestoreResult, error) {
+	if len(snapshot.Zone) > 0 && !strings.EqualFold(snapshot.Zone, zone) {
+		return nil, fmt.Errorf("snapshot of %v can't be restored to %v", snapshot.Zone, zone)
+	}
+
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(snapshot.Records))
+	for _, rec := range snapshot.Records {
+		wanted[rec.key()] = true
+	}
+
+	result := &RestoreResult{}
+
+	existing := make(map[string]bool, len(respData.Records))
+	for _, rec := range respData.Records {
+		snapRec := SnapshotRecord{Type: rec.Type, Name: rec.Name, Value: rec.Value}
+		snapRec.TTL, _ = strconv.Atoi(rec.TTL)
+
+		key := snapRec.key()
+		existing[key] = true
+		if wanted[key] || rec.Type == "SOA" || rec.isLease() {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		deleted, err := p.deleteZoneRecord(ctx, zone, libdns.Record{
+			ID:    rec.Combined,
+			Type:  rec.Type,
+			Name:  rec.Name,
+			Value: rec.Value,
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Deleted = append(result.Deleted, deleted)
+	}
+
+	for _, rec := range snapshot.Records {
+		if existing[rec.key()] || rec.Type == "SOA" || (daRecord{Type: rec.Type, Name: rec.Name}).isLease() {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		record := libdns.Record{
+			Type:  rec.Type,
+			Name:  rec.Name,
+			Value: rec.Value,
+			TTL:   time.Duration(rec.TTL) * time.Second,
+		}
+		if rec.Type == "MX" {
+			// Snapshots hold DirectAdmin's "priority target" form
+			mx, err := daRecord{Type: rec.Type, Name: rec.Name, Value: rec.Value}.libdnsRecord(zone)
+			if err != nil {
+				return result, err
+			}
+			record.Priority, record.Value = mx.Priority, mx.Value
+		}
+
+		added, err := p.appendZoneRecord(ctx, zone, record)
+		if err != nil {
+			return result, err
+		}
+		result.Added = append(result.Added, added)
+	}
+
+	return result, nil
+}
+
+// isLease reports whether the record is a lease, which snapshots leave out.
+func (r daRecord) isLease() bool {
+	return isLeaseRecord(libdns.Record{Type: r.Type, Name: r.Name})
+}
+
+func (r SnapshotRecord) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d", r.Type, r.Name, r.Value, r.TTL)
+}
+
+// rollbackSnapshot takes the snapshot a failed batch is rolled back to, if
+// rollback is enabled and the batch can partially fail.
+func (p *Provider) rollbackSnapshot(ctx context.Context, zone string, records []libdns.Record) (*ZoneSnapshot, error) {
+	if !p.RollbackOnFailure || len(records) < 2 {
+		return nil, nil
+	}
+
+	return p.snapshotZone(ctx, zone)
+}
+
+// rollback restores the zone to the snapshot taken before a batch, if
+// there is one and part of the batch was applied. It reports whether the
+// zone was restored and records the outcome in batchErr.
+func (p *Provider) rollback(ctx context.Context, zone string, snapshot *ZoneSnapshot, batchErr *BatchError) bool {
+	if snapshot == nil || batchErr.Atomic() {
+		return false
+	}
+
+	p.logger(ctx).Warnw("batch failed, rolling back", "zone", zone, "applied", len(batchErr.Applied), "error", batchErr.Err)
+
+	// The batch may have failed because ctx was canceled or expired, which
+	// mustn't leave the zone half changed
+	restoreCtx, cancel := p.detached(ctx, zone)
+	defer cancel()
+
+	_, err := p.restoreZone(restoreCtx, zone, snapshot)
+	if err != nil {
+		p.logger(ctx).Errorw("rollback failed", "zone", zone, "error", err)
+		batchErr.RollbackErr = err
+		return false
+	}
+
+	batchErr.RolledBack = true
+	return true
+}
@@ -0,0 +1,101 @@
+package directadmin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestFake_SnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+		directadmintest.Record{Type: "MX", Name: fakeZone + ".", Value: "10 mail", TTL: 3600},
+	)
+	before := server.Records(fakeZone)
+
+	snapshot, err := provider.SnapshotZone(ctx, fakeZone+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Zone != fakeZone || len(snapshot.Records) != 3 || snapshot.Taken.IsZero() {
+		t.Fatalf("expected a snapshot of the zone, got %+v", snapshot)
+	}
+
+	// Snapshots survive serialization
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ZoneSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a record, change one and delete one
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.SetRecords(ctx, fakeZone, []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300 * time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := provider.GetRecords(ctx, fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range records {
+		if rec.Type != "MX" {
+			continue
+		}
+		if _, err := provider.DeleteRecords(ctx, fakeZone, []libdns.Record{rec}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(server.Records(fakeZone)) != 3 {
+		t.Fatalf("expected the zone to be changed, got %v", server.Records(fakeZone))
+	}
+
+	result, err := provider.RestoreZone(ctx, fakeZone, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Deleted) != 2 || len(result.Added) != 2 {
+		t.Errorf("expected 2 records to be deleted and 2 added, got %+v", result)
+	}
+	if after := server.Records(fakeZone); !sameRecords(before, after) {
+		t.Errorf("expected the zone to be restored to\n%v\ngot\n%v", before, after)
+	}
+
+	// Restoring an unchanged zone is a no-op
+	result, err = provider.RestoreZone(ctx, fakeZone, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Deleted) != 0 || len(result.Added) != 0 {
+		t.Errorf("expected no changes, got %+v", result)
+	}
+
+	if _, err := provider.RestoreZone(ctx, "example.org", &decoded); err == nil {
+		t.Error("expected an error restoring a snapshot to another zone")
+	}
+}
+
+func sameRecords(a, b []directadmintest.Record) bool {
+	sorted := func(records []directadmintest.Record) []directadmintest.Record {
+		records = append([]directadmintest.Record(nil), records...)
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].Type+records[i].Name+records[i].Value < records[j].Type+records[j].Name+records[j].Value
+		})
+		return records
+	}
+
+	return reflect.DeepEqual(sorted(a), sorted(b))
+}
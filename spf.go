@@ -0,0 +1,233 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// SPFLookupLimit is the number of DNS lookups an SPF policy may cause, see
+// RFC 7208 Section 4.6.4. Receivers treat policies exceeding it as invalid.
+const SPFLookupLimit = 10
+
+// ErrSPFLookupLimit is returned when merging would make the policy exceed
+// SPFLookupLimit.
+var ErrSPFLookupLimit = errors.New("SPF policy exceeds the DNS lookup limit")
+
+// spfResolver resolves the policies included by an SPF policy, tests
+// replace it with a fake nameserver.
+var spfResolver = net.DefaultResolver
+
+// SPF is an SPF policy, the terms following "v=spf1".
+type SPF struct {
+	Terms []string
+}
+
+// ParseSPF parses an SPF policy such as
+// "v=spf1 mx include:_spf.example.net ~all". Quoted TXT record data is
+// accepted as well.
+func ParseSPF(value string) (SPF, error) {
+	fields := strings.Fields(unquoteTXT(value))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "v=spf1") {
+		return SPF{}, fmt.Errorf("not an SPF policy: %q", value)
+	}
+
+	return SPF{Terms: fields[1:]}, nil
+}
+
+// String returns the policy as TXT record text.
+func (s SPF) String() string {
+	return strings.Join(append([]string{"v=spf1"}, s.Terms...), " ")
+}
+
+// Add adds the terms the policy doesn't have yet, keeping the all
+// mechanism and redirect modifier at the end where they take effect.
+func (s *SPF) Add(terms ...string) {
+	// Terms after all are never evaluated, so new ones go before it
+	at := len(s.Terms)
+	for i, term := range s.Terms {
+		if name := spfTermName(term); name == "all" || name == "redirect" {
+			at = i
+			break
+		}
+	}
+
+	var added []string
+	for _, term := range terms {
+		if s.has(term) || containsFold(added, term) {
+			continue
+		}
+		added = append(added, term)
+	}
+
+	s.Terms = append(s.Terms[:at:at], append(added, s.Terms[at:]...)...)
+}
+
+func (s SPF) has(term string) bool {
+	return containsFold(s.Terms, term)
+}
+
+// Lookups returns the number of terms of the policy that cause a DNS
+// lookup, not counting the lookups of included policies.
+func (s SPF) Lookups() int {
+	var n int
+	for _, term := range s.Terms {
+		switch spfTermName(term) {
+		case "include", "a", "mx", "ptr", "exists", "redirect":
+			n++
+		}
+	}
+
+	return n
+}
+
+// spfTermName returns the lowercase mechanism or modifier name of a term,
+// e.g. include for "~include:_spf.example.net".
+func spfTermName(term string) string {
+	term = strings.TrimLeft(term, "+-~?")
+	if i := strings.IndexAny(term, ":=/"); i >= 0 {
+		term = term[:i]
+	}
+
+	return strings.ToLower(term)
+}
+
+// spfTarget returns the domain an include or redirect refers to.
+func spfTarget(term string) (string, bool) {
+	term = strings.TrimLeft(term, "+-~?")
+	switch spfTermName(term) {
+	case "include":
+		return term[len("include:"):], true
+	case "redirect":
+		return term[len("redirect="):], true
+	}
+
+	return "", false
+}
+
+func containsFold(terms []string, term string) bool {
+	for _, t := range terms {
+		if strings.EqualFold(t, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergeSPF adds terms, e.g. "include:_spf.example.net", to the zone's SPF
+// policy and writes it back, creating a "v=spf1 ... ~all" policy when the
+// zone has none. Terms the policy already has are skipped. The included
+// policies are resolved to count the DNS lookups of the merged policy, and
+// ErrSPFLookupLimit is returned without changing the zone when it would
+// exceed SPFLookupLimit.
+func (p *Provider) MergeSPF(ctx context.Context, zone string, terms ...string) (_ libdns.Record, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "MergeSPF", zone)
+	defer end(&err)
+
+	records, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	var existing *libdns.Record
+	var spf SPF
+	for i, rec := range records {
+		if rec.Type != "TXT" || relativeName(rec.Name, zone) != "@" {
+			continue
+		}
+
+		parsed, err := ParseSPF(rec.Value)
+		if err != nil {
+			continue
+		}
+		if existing != nil {
+			return libdns.Record{}, fmt.Errorf("zone %v has more than one SPF policy", zone)
+		}
+		existing, spf = &records[i], parsed
+	}
+
+	if existing == nil {
+		spf.Terms = []string{"~all"}
+	}
+
+	before := spf.String()
+	spf.Add(terms...)
+	if existing != nil && spf.String() == before {
+		return *existing, nil
+	}
+
+	lookups, err := spfLookups(ctx, spf, 0)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+	if lookups > SPFLookupLimit {
+		return libdns.Record{}, fmt.Errorf("%w: %d lookups", ErrSPFLookupLimit, lookups)
+	}
+
+	record := libdns.Record{Type: "TXT", Name: "@", Value: quoteTXT(spf.String())}
+	if existing == nil {
+		added, err := p.AppendRecords(ctx, zone, []libdns.Record{record})
+		if err != nil {
+			return libdns.Record{}, err
+		}
+		return added[0], nil
+	}
+
+	record.ID, record.TTL = existing.ID, existing.TTL
+	set, err := p.SetRecords(ctx, zone, []libdns.Record{record})
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	return set[0], nil
+}
+
+// spfLookups returns the DNS lookups the policy causes including those of
+// the policies it includes. depth guards against include loops.
+func spfLookups(ctx context.Context, spf SPF, depth int) (int, error) {
+	lookups := spf.Lookups()
+	if depth > SPFLookupLimit {
+		return lookups, nil
+	}
+
+	for _, term := range spf.Terms {
+		domain, ok := spfTarget(term)
+		if !ok {
+			continue
+		}
+
+		values, err := spfResolver.LookupTXT(ctx, domain)
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve SPF policy of %v: %w", domain, err)
+		}
+
+		var included *SPF
+		for _, value := range values {
+			if parsed, err := ParseSPF(value); err == nil {
+				included = &parsed
+				break
+			}
+		}
+		if included == nil {
+			return 0, fmt.Errorf("%v has no SPF policy", domain)
+		}
+
+		n, err := spfLookups(ctx, *included, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		lookups += n
+		if lookups > SPFLookupLimit {
+			break
+		}
+	}
+
+	return lookups, nil
+}
@@ -0,0 +1,95 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/libdns/directadmin/directadmintest"
+)
+
+func TestSPF_Add(t *testing.T) {
+	var tests = []struct {
+		policy string
+		add    []string
+		want   string
+	}{
+		{policy: "v=spf1 mx -all", add: []string{"include:_spf.example.net"}, want: "v=spf1 mx include:_spf.example.net -all"},
+		{policy: "v=spf1 mx include:_spf.example.net ~all", add: []string{"INCLUDE:_spf.example.net"}, want: "v=spf1 mx include:_spf.example.net ~all"},
+		{policy: "v=spf1 a redirect=_spf.example.org", add: []string{"ip4:192.0.2.1", "ip4:192.0.2.1"}, want: "v=spf1 a ip4:192.0.2.1 redirect=_spf.example.org"},
+		{policy: `"v=spf1 mx"`, add: []string{"a"}, want: "v=spf1 mx a"},
+	}
+
+	for _, tt := range tests {
+		spf, err := ParseSPF(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spf.Add(tt.add...)
+		if got := spf.String(); got != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.policy, tt.want, got)
+		}
+	}
+
+	if _, err := ParseSPF("v=DKIM1; p=abc"); err == nil {
+		t.Error("expected a non SPF record to fail")
+	}
+}
+
+func TestFake_MergeSPF(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "TXT", Name: fakeZone + ".", Value: `"v=spf1 mx -all"`, TTL: 300},
+	)
+
+	addr := fakeNameserver(t, func(name string) []string {
+		switch {
+		case name == "_spf.example.net":
+			return []string{"v=spf1 include:_spf2.example.net ip4:192.0.2.0/24 ~all"}
+		case name == "_big.example.net":
+			var includes []string
+			for i := 0; i < 10; i++ {
+				includes = append(includes, fmt.Sprintf("include:_%d.example.net", i))
+			}
+			return []string{"v=spf1 " + strings.Join(includes, " ") + " ~all"}
+		default:
+			return []string{"v=spf1 ip4:198.51.100.0/24 ~all"}
+		}
+	})
+	orig := spfResolver
+	spfResolver = resolverFor(addr)
+	t.Cleanup(func() { spfResolver = orig })
+
+	record, err := provider.MergeSPF(ctx, fakeZone, "include:_spf.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"v=spf1 mx include:_spf.example.net -all"`; record.Value != want {
+		t.Errorf("expected %v, got %v", want, record.Value)
+	}
+
+	records := server.Records(fakeZone)
+	if len(records) != 1 || records[0].Value != record.Value || records[0].TTL != 300 {
+		t.Errorf("expected the policy to be replaced, zone has %v", records)
+	}
+
+	_, err = provider.MergeSPF(ctx, fakeZone, "include:_big.example.net")
+	if !errors.Is(err, ErrSPFLookupLimit) {
+		t.Errorf("expected ErrSPFLookupLimit, got %v", err)
+	}
+	if got := server.Records(fakeZone)[0].Value; got != record.Value {
+		t.Errorf("expected the zone to be unchanged, got %v", got)
+	}
+
+	server.AddZone(fakeZone)
+	record, err = provider.MergeSPF(ctx, fakeZone, "mx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"v=spf1 mx ~all"`; record.Value != want {
+		t.Errorf("expected a new policy %v, got %v", want, record.Value)
+	}
+}
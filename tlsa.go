@@ -0,0 +1,174 @@
+package directadmin
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// TLSA certificate usages, see RFC 6698 Section 2.1.1 and RFC 7218
+const (
+	TLSAUsagePKIXTA uint8 = 0
+	TLSAUsagePKIXEE uint8 = 1
+	TLSAUsageDANETA uint8 = 2
+	TLSAUsageDANEEE uint8 = 3
+)
+
+// TLSA selectors, the part of the certificate that is matched
+const (
+	TLSASelectorCert uint8 = 0
+	TLSASelectorSPKI uint8 = 1
+)
+
+// TLSA matching types
+const (
+	TLSAMatchingFull   uint8 = 0
+	TLSAMatchingSHA256 uint8 = 1
+	TLSAMatchingSHA512 uint8 = 2
+)
+
+// TLSAData returns the TLSA record data for the certificate, e.g.
+// "3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6".
+// DANE-EE with the SPKI selector and SHA-256 (3 1 1) is the common choice
+// for a server's own certificate, it survives renewals that keep the key.
+func TLSAData(cert *x509.Certificate, usage, selector, matchingType uint8) (string, error) {
+	var data []byte
+	switch selector {
+	case TLSASelectorCert:
+		data = cert.Raw
+	case TLSASelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return "", fmt.Errorf("unsupported TLSA selector %d", selector)
+	}
+
+	return tlsaData(data, usage, selector, matchingType)
+}
+
+// TLSADataForKey returns the TLSA record data matching the public key with
+// the SPKI selector, e.g. for the key of a certificate not issued yet.
+func TLSADataForKey(publicKey crypto.PublicKey, usage, matchingType uint8) (string, error) {
+	spki, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %v", err)
+	}
+
+	return tlsaData(spki, usage, TLSASelectorSPKI, matchingType)
+}
+
+func tlsaData(data []byte, usage, selector, matchingType uint8) (string, error) {
+	if usage > TLSAUsageDANEEE {
+		return "", fmt.Errorf("unsupported TLSA usage %d", usage)
+	}
+
+	switch matchingType {
+	case TLSAMatchingFull:
+	case TLSAMatchingSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case TLSAMatchingSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported TLSA matching type %d", matchingType)
+	}
+
+	return fmt.Sprintf("%d %d %d %s", usage, selector, matchingType, strings.ToUpper(hex.EncodeToString(data))), nil
+}
+
+// TLSAName returns the owner name of the TLSA records for a service, e.g.
+// "_443._tcp.www" for port 443 over TCP on host www. An empty host or "@"
+// is the zone's apex.
+func TLSAName(port uint16, protocol, host string) string {
+	name := fmt.Sprintf("_%d._%s", port, strings.ToLower(protocol))
+	if len(host) == 0 || host == "@" {
+		return name
+	}
+
+	return name + "." + host
+}
+
+// PublishTLSA makes data the TLSA records published under name, e.g.
+// TLSAName(443, "tcp", "www"). Records missing are added before stale
+// ones are deleted, so a rollover can publish the current and the next
+// certificate's data together and drop the old one once the new
+// certificate is deployed.
+func (p *Provider) PublishTLSA(ctx context.Context, zone, name string, ttl time.Duration, data ...string) (_ []libdns.Record, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "PublishTLSA", zone)
+	defer end(&err)
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no TLSA data for %v", name)
+	}
+
+	records, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(data))
+	for _, d := range data {
+		wanted[normalizeTLSA(d)] = true
+	}
+
+	var stale []libdns.Record
+	var published []libdns.Record
+	for _, rec := range records {
+		if rec.Type != "TLSA" || relativeName(rec.Name, zone) != strings.ToLower(name) {
+			continue
+		}
+
+		value := normalizeTLSA(rec.Value)
+		if wanted[value] {
+			delete(wanted, value)
+			published = append(published, rec)
+			continue
+		}
+		stale = append(stale, rec)
+	}
+
+	var missing []libdns.Record
+	for _, d := range data {
+		if value := normalizeTLSA(d); wanted[value] {
+			delete(wanted, value)
+			missing = append(missing, libdns.Record{Type: "TLSA", Name: name, Value: value, TTL: ttl})
+		}
+	}
+
+	if len(missing) > 0 {
+		added, err := p.AppendRecords(ctx, zone, missing)
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, added...)
+	}
+
+	if len(stale) > 0 {
+		if _, err := p.DeleteRecords(ctx, zone, stale); err != nil {
+			return published, err
+		}
+	}
+
+	return published, nil
+}
+
+// normalizeTLSA returns TLSA record data with single spaces and the
+// certificate data in upper case hex without spaces.
+func normalizeTLSA(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return value
+	}
+
+	return strings.Join(fields[:3], " ") + " " + strings.ToUpper(strings.Join(fields[3:], ""))
+}
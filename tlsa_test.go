@@ -0,0 +1,129 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestTLSAData(t *testing.T) {
+	cert := testCertificate(t)
+
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	full := sha256.Sum256(cert.Raw)
+
+	var tests = []struct {
+		usage, selector, matching uint8
+		want                      string
+	}{
+		{usage: TLSAUsageDANEEE, selector: TLSASelectorSPKI, matching: TLSAMatchingSHA256, want: "3 1 1 " + strings.ToUpper(hex.EncodeToString(spki[:]))},
+		{usage: TLSAUsageDANETA, selector: TLSASelectorCert, matching: TLSAMatchingSHA256, want: "2 0 1 " + strings.ToUpper(hex.EncodeToString(full[:]))},
+		{usage: TLSAUsageDANEEE, selector: TLSASelectorCert, matching: TLSAMatchingFull, want: "3 0 0 " + strings.ToUpper(hex.EncodeToString(cert.Raw))},
+	}
+
+	for _, tt := range tests {
+		got, err := TLSAData(cert, tt.usage, tt.selector, tt.matching)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%d %d %d: expected %v, got %v", tt.usage, tt.selector, tt.matching, tt.want, got)
+		}
+	}
+
+	fromKey, err := TLSADataForKey(cert.PublicKey, TLSAUsageDANEEE, TLSAMatchingSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromKey != tests[0].want {
+		t.Errorf("expected the key to match the certificate's SPKI, got %v", fromKey)
+	}
+
+	if _, err := TLSAData(cert, 4, TLSASelectorSPKI, TLSAMatchingSHA256); err == nil {
+		t.Error("expected an invalid usage to fail")
+	}
+
+	if got := TLSAName(443, "TCP", "www"); got != "_443._tcp.www" {
+		t.Errorf("unexpected name %v", got)
+	}
+}
+
+func TestFake_PublishTLSA(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	name := TLSAName(443, "tcp", "www")
+	current, err := TLSAData(testCertificate(t), TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := TLSAData(testCertificate(t), TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlsa := func() []string {
+		var values []string
+		for _, rec := range server.Records(fakeZone) {
+			if rec.Type == "TLSA" {
+				values = append(values, rec.Value)
+			}
+		}
+		return values
+	}
+
+	if _, err := provider.PublishTLSA(ctx, fakeZone, name, time.Hour, current); err != nil {
+		t.Fatal(err)
+	}
+	// Pre-publish the next key, then drop the current one
+	if _, err := provider.PublishTLSA(ctx, fakeZone, name, time.Hour, current, next); err != nil {
+		t.Fatal(err)
+	}
+	if got := tlsa(); len(got) != 2 {
+		t.Fatalf("expected both keys during the rollover, got %v", got)
+	}
+
+	published, err := provider.PublishTLSA(ctx, fakeZone, name, time.Hour, strings.ToLower(next))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tlsa(); len(got) != 1 || got[0] != next || len(published) != 1 {
+		t.Errorf("expected only the next key, got %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package directadmin
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/libdns/directadmin"
+
+func (p *Provider) tracer() trace.Tracer {
+	tp := p.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a span for one of the provider's operations. The returned
+// function ends the span, recording err if it isn't nil.
+func (p *Provider) startSpan(ctx context.Context, name, zone string, attrs ...attribute.KeyValue) (context.Context, func(err *error)) {
+	attrs = append(attrs, attribute.String("dns.zone", zone))
+
+	ctx, span := p.tracer().Start(ctx, "directadmin."+name, trace.WithAttributes(attrs...))
+
+	return ctx, func(err *error) {
+		if err != nil && *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
+
+// tracingMiddleware wraps every API call in a client span.
+func (p *Provider) tracingMiddleware(next Handler) Handler {
+	return func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+		ctx, span := p.tracer().Start(ctx, req.Command,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("directadmin.command", req.Command),
+				attribute.String("directadmin.action", req.Params.Get("action")),
+				attribute.String("dns.zone", req.Zone),
+			))
+		defer span.End()
+
+		resp, err := next(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return resp, err
+		}
+
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return resp, nil
+	}
+}
@@ -0,0 +1,126 @@
+package directadmin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/libdns/libdns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingTracer is a trace.TracerProvider keeping every span it started.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (rt *recordingTracer) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return rt
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	span := &recordingSpan{
+		Span:  trace.SpanFromContext(context.Background()),
+		name:  name,
+		kind:  cfg.SpanKind(),
+		attrs: map[attribute.Key]attribute.Value{},
+	}
+	span.parent, _ = trace.SpanFromContext(ctx).(*recordingSpan)
+	span.SetAttributes(cfg.Attributes()...)
+
+	rt.mu.Lock()
+	rt.spans = append(rt.spans, span)
+	rt.mu.Unlock()
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (rt *recordingTracer) find(name string) []*recordingSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	var found []*recordingSpan
+	for _, span := range rt.spans {
+		if span.name == name {
+			found = append(found, span)
+		}
+	}
+
+	return found
+}
+
+type recordingSpan struct {
+	trace.Span
+
+	name   string
+	kind   trace.SpanKind
+	parent *recordingSpan
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	err    error
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string)           { s.status = code }
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) { s.err = err }
+func (s *recordingSpan) End(...trace.SpanEndOption)                    { s.ended = true }
+
+func TestFake_Tracing(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	tracer := &recordingTracer{}
+	provider.TracerProvider = tracer
+
+	if _, err := provider.AppendRecords(ctx, fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := tracer.find("directadmin.AppendRecords")
+	if len(ops) != 1 {
+		t.Fatalf("expected an operation span, got %d", len(ops))
+	}
+	op := ops[0]
+	if !op.ended || op.status != codes.Unset || op.attrs["dns.zone"].AsString() != fakeZone || len(op.attrs["directadmin.request_id"].AsString()) == 0 {
+		t.Errorf("expected an ended span with the zone and request ID, got %+v", op)
+	}
+
+	calls := tracer.find("CMD_API_DNS_CONTROL")
+	if len(calls) == 0 {
+		t.Fatal("expected a span per API call")
+	}
+	var add bool
+	for _, call := range calls {
+		if call.parent != op || call.kind != trace.SpanKindClient || !call.ended {
+			t.Errorf("expected an ended client span under the operation, got %+v", call)
+		}
+		if call.attrs["http.status_code"].AsInt64() != http.StatusOK {
+			t.Errorf("expected the status code, got %v", call.attrs["http.status_code"])
+		}
+		add = add || call.attrs["directadmin.action"].AsString() == "add"
+	}
+	if !add {
+		t.Error("expected the add action to be recorded")
+	}
+
+	// Failed operations are marked as errors
+	server.FailNext("CMD_API_DNS_CONTROL", "Internal Error")
+	if _, err := provider.GetRecords(ctx, fakeZone); err == nil {
+		t.Fatal("expected an error")
+	}
+	ops = tracer.find("directadmin.GetRecords")
+	if len(ops) != 1 || ops[0].status != codes.Error || ops[0].err == nil {
+		t.Errorf("expected the error to be recorded, got %+v", ops)
+	}
+}
@@ -0,0 +1,95 @@
+package directadmin
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDialTimeout         = 10 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// sharedClient is the http.Client all requests of a Provider share, so
+// connections to the panel are reused between calls.
+type sharedClient struct {
+	once   sync.Once
+	client *http.Client
+}
+
+// httpClient returns the shared client, building it on first use. Transport
+// settings changed after the first request have no effect.
+func (p *Provider) httpClient() *http.Client {
+	p.client.once.Do(func() {
+		var transport http.RoundTripper = p.Transport
+		if transport == nil {
+			transport = p.newTransport()
+		}
+		if p.DebugHTTP {
+			transport = &debugTransport{p: p, next: transport}
+		}
+		p.client.client = &http.Client{Transport: transport}
+	})
+
+	return p.client.client
+}
+
+func (p *Provider) newTransport() *http.Transport {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: p.InsecureRequests,
+			ServerName:         p.TLSServerName,
+		},
+		DialContext: (&net.Dialer{
+			Timeout:   transportTimeout(p.DialTimeout, defaultDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   transportTimeout(p.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: transportTimeout(p.ResponseHeaderTimeout, 0),
+		IdleConnTimeout:       p.IdleConnTimeout,
+		MaxConnsPerHost:       p.MaxConnsPerHost,
+		DisableKeepAlives:     p.DisableKeepAlives,
+	}
+
+	// All requests go to the same panel, so the per-host limit is the one
+	// that matters
+	if p.MaxIdleConns > 0 {
+		transport.MaxIdleConns = p.MaxIdleConns
+		transport.MaxIdleConnsPerHost = p.MaxIdleConns
+	}
+
+	return transport
+}
+
+// transportTimeout returns the timeout for a transport setting, where zero
+// means no timeout.
+func transportTimeout(timeout, fallback time.Duration) time.Duration {
+	switch {
+	case timeout < 0:
+		return 0
+	case timeout == 0:
+		return fallback
+	default:
+		return timeout
+	}
+}
+
+// defaultMaxResponseBytes is the response size limit when MaxResponseBytes
+// is unset, generous enough for zones with tens of thousands of records.
+const defaultMaxResponseBytes = 64 << 20
+
+// maxResponseBytes returns the response size limit for daapi.Client, where
+// zero means unlimited.
+func (p *Provider) maxResponseBytes() int64 {
+	switch {
+	case p.MaxResponseBytes < 0:
+		return 0
+	case p.MaxResponseBytes == 0:
+		return defaultMaxResponseBytes
+	default:
+		return p.MaxResponseBytes
+	}
+}
@@ -0,0 +1,65 @@
+package directadmin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProvider_NewTransport(t *testing.T) {
+	provider := &Provider{
+		MaxIdleConns:      5,
+		MaxConnsPerHost:   10,
+		IdleConnTimeout:   time.Minute,
+		DisableKeepAlives: true,
+		InsecureRequests:  true,
+		TLSServerName:     "da.example.com",
+	}
+
+	transport := provider.newTransport()
+	if transport.MaxIdleConns != 5 || transport.MaxIdleConnsPerHost != 5 || transport.MaxConnsPerHost != 10 {
+		t.Errorf("expected the connection limits to be applied, got %d, %d and %d",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute || !transport.DisableKeepAlives {
+		t.Errorf("expected the keep-alive settings to be applied, got %v and %v", transport.IdleConnTimeout, transport.DisableKeepAlives)
+	}
+	if !transport.TLSClientConfig.InsecureSkipVerify || transport.TLSClientConfig.ServerName != "da.example.com" {
+		t.Errorf("expected the TLS settings to be applied, got %+v", transport.TLSClientConfig)
+	}
+
+	// Zero keeps Go's defaults
+	transport = (&Provider{}).newTransport()
+	if transport.MaxIdleConns != 0 || transport.MaxConnsPerHost != 0 || transport.DisableKeepAlives {
+		t.Errorf("expected Go's defaults, got %+v", transport)
+	}
+}
+
+func TestFake_CustomTransport(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+	provider.Headers = map[string]string{"X-Proxy-Token": "secret"}
+
+	var seen []http.Header
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.Header.Clone())
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	if _, err := provider.GetRecords(context.Background(), fakeZone); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) == 0 {
+		t.Fatal("expected the requests to go through the custom transport")
+	}
+	for _, header := range seen {
+		if header.Get("X-Proxy-Token") != "secret" {
+			t.Errorf("expected the configured headers on every request, got %v", header)
+		}
+	}
+
+	// The client, and with it the transport, is built once
+	if provider.httpClient() != provider.httpClient() {
+		t.Error("expected the client to be shared between requests")
+	}
+}
@@ -0,0 +1,37 @@
+package directadmin
+
+import "strings"
+
+// maxTXTString is the longest character string a TXT record can hold, longer
+// values have to be split into several strings.
+const maxTXTString = 255
+
+// quoteTXT returns value as TXT record data, split into quoted strings of at
+// most 255 characters.
+func quoteTXT(value string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	var parts []string
+	for len(value) > maxTXTString {
+		parts = append(parts, `"`+escape.Replace(value[:maxTXTString])+`"`)
+		value = value[maxTXTString:]
+	}
+	parts = append(parts, `"`+escape.Replace(value)+`"`)
+
+	return strings.Join(parts, " ")
+}
+
+// unquoteTXT joins the quoted strings of TXT record data. Values without
+// quotes are returned as they are.
+func unquoteTXT(value string) string {
+	if !strings.HasPrefix(strings.TrimSpace(value), `"`) {
+		return value
+	}
+
+	var joined strings.Builder
+	for _, s := range zoneFileFields(value) {
+		joined.WriteString(unquoteZoneString(s))
+	}
+
+	return joined.String()
+}
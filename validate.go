@@ -0,0 +1,120 @@
+package directadmin
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidationError lists every problem Validate found with the configuration.
+type ValidationError struct {
+	Errs []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "invalid directadmin configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errs
+}
+
+// Validate checks the configuration without contacting DirectAdmin: that
+// ServerURL parses, has a host and uses https, that credentials are
+// configured exactly one way, and that numeric options are in range. It
+// returns a *ValidationError listing every problem found, or nil.
+func (p *Provider) Validate() error {
+	var errs []error
+
+	u, err := p.baseURL()
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case u.User != nil:
+		errs = append(errs, errors.New("server url must not contain credentials, use User and LoginKey"))
+	}
+
+	for i := range p.Accounts {
+		errs = append(errs, p.Accounts[i].validate(i)...)
+	}
+
+	switch {
+	case p.Credentials != nil:
+		if len(p.LoginKey) > 0 || len(p.LoginKeyFile) > 0 {
+			errs = append(errs, errors.New("login key and login key file can't be combined with a credentials provider"))
+		}
+		if len(p.Accounts) > 0 {
+			errs = append(errs, errors.New("accounts can't be combined with a credentials provider"))
+		}
+	case len(p.Accounts) > 0 && len(p.User) == 0:
+		if len(p.LoginKey) > 0 || len(p.LoginKeyFile) > 0 {
+			errs = append(errs, errors.New("user is required with a login key"))
+		}
+	default:
+		if len(p.User) == 0 {
+			errs = append(errs, errors.New("user is required"))
+		}
+		if len(p.LoginKey) > 0 && len(p.LoginKeyFile) > 0 {
+			errs = append(errs, errors.New("login key and login key file are mutually exclusive"))
+		}
+		if len(p.LoginKey) == 0 && len(p.LoginKeyFile) == 0 {
+			errs = append(errs, errors.New("login key or login key file is required"))
+		}
+	}
+
+	if p.CircuitBreakerThreshold < 0 {
+		errs = append(errs, errors.New("circuit breaker threshold must not be negative"))
+	}
+	if p.CircuitBreakerCooldown < 0 {
+		errs = append(errs, errors.New("circuit breaker cooldown must not be negative"))
+	}
+
+	errs = append(errs, p.RetryPolicy.validate()...)
+
+	if p.MaxIdleConns < 0 || p.MaxConnsPerHost < 0 {
+		errs = append(errs, errors.New("connection limits must not be negative"))
+	}
+	if p.IdleConnTimeout < 0 {
+		errs = append(errs, errors.New("idle connection timeout must not be negative"))
+	}
+
+	if strings.ContainsAny(p.OwnerID, ",= \t\"") {
+		errs = append(errs, fmt.Errorf("owner id %q must not contain commas, equals signs, quotes or spaces", p.OwnerID))
+	}
+
+	for _, rule := range p.ProtectedRecords {
+		if _, err := path.Match(rule.Name, ""); err != nil {
+			errs = append(errs, fmt.Errorf("protected record name %q is not a valid pattern", rule.Name))
+		}
+	}
+
+	for _, resolver := range p.PropagationResolvers {
+		if resolver == AuthoritativeNameservers {
+			continue
+		}
+		if _, err := resolverAddress(resolver); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if p.DefaultTTL < 0 || p.DefaultTTL > maxTTL {
+		errs = append(errs, fmt.Errorf("default ttl %v is out of range", p.DefaultTTL))
+	}
+
+	if _, ok := parseDAVersion(p.DirectAdminVersion); len(p.DirectAdminVersion) > 0 && !ok {
+		errs = append(errs, fmt.Errorf("directadmin version %q must look like 1.62", p.DirectAdminVersion))
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errs: errs}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrNotPersisted is returned with VerifyWrites set when DirectAdmin
+// accepted a record but the zone doesn't hold it afterwards. The error is a
+// *NotPersistedError.
+var ErrNotPersisted = errors.New("record not persisted")
+
+// NotPersistedError lists the records DirectAdmin accepted but didn't
+// store.
+type NotPersistedError struct {
+	Zone    string
+	Records []libdns.Record
+}
+
+func (e *NotPersistedError) Error() string {
+	names := make([]string, 0, len(e.Records))
+	for _, rec := range e.Records {
+		names = append(names, fmt.Sprintf("%v %v %q", rec.Type, rec.Name, rec.Value))
+	}
+
+	return fmt.Sprintf("%v in %v: %v", ErrNotPersisted, e.Zone, strings.Join(names, ", "))
+}
+
+// Is reports whether target is ErrNotPersisted.
+func (e *NotPersistedError) Is(target error) bool {
+	return target == ErrNotPersisted
+}
+
+// verified wraps a function writing records so the zone is read back
+// afterwards, if VerifyWrites is set, to check that it holds every record
+// the function reports as written.
+func (p *Provider) verified(fn func(context.Context, string, []libdns.Record) ([]libdns.Record, error)) func(context.Context, string, []libdns.Record) ([]libdns.Record, error) {
+	return func(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+		written, err := fn(ctx, zone, records)
+		if err != nil || !p.VerifyWrites || p.DryRun || len(written) == 0 {
+			return written, err
+		}
+
+		current, err := p.getZoneRecords(ctx, zone)
+		if err != nil {
+			return written, fmt.Errorf("failed to verify the records written to %v: %w", zone, err)
+		}
+
+		stored := make(map[string]bool, len(current))
+		for _, rec := range current {
+			stored[verifyKey(zone, rec)] = true
+		}
+
+		var missing []libdns.Record
+		for _, rec := range written {
+			if !stored[verifyKey(zone, rec)] {
+				missing = append(missing, rec)
+			}
+		}
+		if len(missing) > 0 {
+			p.logger(ctx).Errorw("records accepted but not persisted", "zone", zone, "missing", len(missing))
+			return written, &NotPersistedError{Zone: zone, Records: missing}
+		}
+
+		return written, nil
+	}
+}
+
+// verifyKey identifies a record by its name, type, value and priority,
+// normalizing the ways DirectAdmin may return a value differently from how
+// it was written.
+func verifyKey(zone string, rec libdns.Record) string {
+	switch strings.ToUpper(rec.Type) {
+	case "TXT":
+		rec.Value = unquoteTXT(rec.Value)
+	case "MX":
+		if target, err := mxTarget(rec.Value, zone, true); err == nil {
+			rec.Value = target
+		}
+	case "CNAME", "NS", "PTR":
+		rec.Value = strings.ToLower(strings.TrimSuffix(rec.Value, "."))
+	}
+
+	return recordKey(zone, rec)
+}
@@ -0,0 +1,61 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_VerifyWrites(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := newFakeProvider(t)
+	provider.VerifyWrites = true
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token"},
+		{Type: "MX", Name: "@", Value: "mail", Priority: 10},
+		{Type: "CNAME", Name: "docs", Value: "www.example.com."},
+	}
+	if _, err := provider.AppendRecords(ctx, fakeZone, records); err != nil {
+		t.Fatalf("expected the stored records to verify, got %v", err)
+	}
+
+	set := []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.2"}}
+	if _, err := provider.SetRecords(ctx, fakeZone, set); err != nil {
+		t.Fatalf("expected the set record to verify, got %v", err)
+	}
+}
+
+func TestProvider_VerifyWritesNotPersisted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") == "add" {
+			_, _ = w.Write([]byte(`{"success":"Record Added","result":""}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"records":[]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(server.URL, "admin", "key", WithAllowInsecureHTTP(), WithVerifyWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	created, err := provider.AppendRecords(context.Background(), "example.com", []libdns.Record{rec})
+
+	var persistErr *NotPersistedError
+	if !errors.As(err, &persistErr) || !errors.Is(err, ErrNotPersisted) {
+		t.Fatalf("expected a NotPersistedError, got %v", err)
+	}
+	if len(persistErr.Records) != 1 || persistErr.Records[0].Name != "_acme-challenge" {
+		t.Errorf("expected the TXT record to be reported missing, got %v", persistErr.Records)
+	}
+	if len(created) != 1 {
+		t.Errorf("expected the accepted record to be returned, got %v", created)
+	}
+}
@@ -0,0 +1,15 @@
+package directadmin
+
+// Version is the version of this library, reported in the User-Agent.
+const Version = "0.3.0"
+
+// userAgent identifies the library, followed by the caller's product token
+// if one is configured.
+func (p *Provider) userAgent() string {
+	ua := "libdns-directadmin/" + Version
+	if len(p.UserAgent) > 0 {
+		ua += " " + p.UserAgent
+	}
+
+	return ua
+}
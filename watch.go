@@ -0,0 +1,134 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ChangeKind describes how a record changed between two polls of a zone.
+type ChangeKind string
+
+const (
+	RecordAdded    ChangeKind = "add"
+	RecordModified ChangeKind = "modify"
+	RecordDeleted  ChangeKind = "delete"
+)
+
+// ZoneChange is emitted by WatchZone for every record that changed.
+type ZoneChange struct {
+	Zone string
+	Kind ChangeKind
+
+	// Record is the record after the change, or the removed record for
+	// RecordDeleted
+	Record libdns.Record
+
+	// Previous is the record before a RecordModified change
+	Previous libdns.Record
+
+	// Detected is when the poll that noticed the change completed
+	Detected time.Time
+}
+
+// WatchZone polls the zone every interval and emits a ZoneChange for each
+// record that was added, modified or deleted since the previous poll, no
+// matter whether the change was made through the provider, the panel or
+// anything else. The initial state is fetched before WatchZone returns, so
+// configuration errors are reported immediately; failed polls after that
+// are skipped. The channel is closed once ctx is done.
+func (p *Provider) WatchZone(ctx context.Context, zone string, interval time.Duration) (_ <-chan ZoneChange, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	// The operation's deadline only covers fetching the initial state, the
+	// watch itself runs until the caller's ctx is done
+	watchCtx := ctx
+	ctx, end := p.startOperation(ctx, "WatchZone", zone)
+	defer end(&err)
+
+	if id, ok := RequestIDFromContext(ctx); ok {
+		watchCtx = WithRequestID(watchCtx, id)
+	}
+
+	if interval <= 0 {
+		return nil, errors.New("watch interval must be positive")
+	}
+
+	previous, err := p.getZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan ZoneChange)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			pollCtx, cancel := p.withDefaultDeadline(watchCtx)
+			current, err := p.getZoneRecords(pollCtx, zone)
+			cancel()
+			if err != nil {
+				p.logger(watchCtx).Warnw("failed to poll zone", "zone", zone, "error", err)
+				continue
+			}
+
+			for _, change := range diffRecords(zone, previous, current) {
+				select {
+				case changes <- change:
+				case <-watchCtx.Done():
+					return
+				}
+			}
+
+			previous = current
+		}
+	}()
+
+	return changes, nil
+}
+
+func diffRecords(zone string, previous, current []libdns.Record) []ZoneChange {
+	now := time.Now()
+
+	before := make(map[string]libdns.Record, len(previous))
+	for _, rec := range previous {
+		before[recordKey(zone, rec)] = rec
+	}
+
+	var changes []ZoneChange
+
+	after := make(map[string]bool, len(current))
+	for _, rec := range current {
+		key := recordKey(zone, rec)
+		after[key] = true
+
+		prev, ok := before[key]
+		switch {
+		case !ok:
+			changes = append(changes, ZoneChange{Zone: zone, Kind: RecordAdded, Record: rec, Detected: now})
+		case prev.TTL != rec.TTL:
+			changes = append(changes, ZoneChange{Zone: zone, Kind: RecordModified, Record: rec, Previous: prev, Detected: now})
+		}
+	}
+
+	for _, rec := range previous {
+		if !after[recordKey(zone, rec)] {
+			changes = append(changes, ZoneChange{Zone: zone, Kind: RecordDeleted, Record: rec, Detected: now})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,97 @@
+package directadmin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestDiffRecords(t *testing.T) {
+	var (
+		www    = libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 5 * time.Minute}
+		wwwTTL = libdns.Record{Type: "A", Name: "www.example.com.", Value: "192.0.2.1", TTL: time.Minute}
+		www2   = libdns.Record{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 5 * time.Minute}
+		txt    = libdns.Record{Type: "TXT", Name: "new", Value: "token", TTL: time.Minute}
+	)
+
+	type change struct {
+		kind     ChangeKind
+		record   libdns.Record
+		previous libdns.Record
+	}
+
+	var tests = []struct {
+		name     string
+		previous []libdns.Record
+		current  []libdns.Record
+		want     []change
+	}{
+		{name: "unchanged", previous: []libdns.Record{www, txt}, current: []libdns.Record{txt, www}},
+		{name: "added", previous: []libdns.Record{www}, current: []libdns.Record{www, txt},
+			want: []change{{kind: RecordAdded, record: txt}}},
+		{name: "deleted", previous: []libdns.Record{www, txt}, current: []libdns.Record{www},
+			want: []change{{kind: RecordDeleted, record: txt}}},
+		{name: "TTL modified", previous: []libdns.Record{www}, current: []libdns.Record{wwwTTL},
+			want: []change{{kind: RecordModified, record: wwwTTL, previous: www}}},
+		{name: "value replaced", previous: []libdns.Record{www}, current: []libdns.Record{www2},
+			want: []change{{kind: RecordAdded, record: www2}, {kind: RecordDeleted, record: www}}},
+		{name: "from empty", current: []libdns.Record{www},
+			want: []change{{kind: RecordAdded, record: www}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffRecords("example.com", tt.previous, tt.current)
+			if len(changes) != len(tt.want) {
+				t.Fatalf("expected %d changes, got %+v", len(tt.want), changes)
+			}
+			for i, want := range tt.want {
+				got := changes[i]
+				if got.Zone != "example.com" || got.Kind != want.kind || got.Record != want.record || got.Previous != want.previous {
+					t.Errorf("expected %+v, got %+v", want, got)
+				}
+				if got.Detected.IsZero() {
+					t.Error("expected the detection time to be set")
+				}
+			}
+		})
+	}
+}
+
+func TestFake_WatchZone(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := provider.WatchZone(ctx, fakeZone, 0); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+	if _, err := provider.WatchZone(ctx, "example.org", time.Millisecond); err == nil {
+		t.Error("expected an error for a zone that doesn't exist")
+	}
+
+	changes, err := provider.WatchZone(ctx, fakeZone+".", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.AppendRecords(context.Background(), fakeZone, []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case change := <-changes:
+		if change.Kind != RecordAdded || change.Record.Name != "new" {
+			t.Errorf("expected the TXT record to be added, got %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change to be emitted")
+	}
+
+	cancel()
+	for range changes {
+	}
+}
@@ -0,0 +1,238 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// CreateZoneOptions configures the domain DirectAdmin creates alongside a new
+// zone. The zero value creates a domain with unlimited bandwidth and quota
+// and all optional features disabled.
+type CreateZoneOptions struct {
+	// Bandwidth limit in megabytes, 0 for unlimited
+	Bandwidth int
+
+	// Quota limit in megabytes, 0 for unlimited
+	Quota int
+
+	// SSL, CGI and PHP enable the respective domain features
+	SSL bool
+	CGI bool
+	PHP bool
+}
+
+// DeleteZoneOptions configures DeleteZone.
+type DeleteZoneOptions struct {
+	// Confirm must be set for the zone to be deleted. Deleting the zone also
+	// deletes the DirectAdmin domain and everything hosted under it.
+	Confirm bool
+}
+
+// ResetZoneOptions configures ResetZone.
+type ResetZoneOptions struct {
+	// Confirm must be set for the zone to be reset, since every record that
+	// isn't part of DirectAdmin's template is lost.
+	Confirm bool
+}
+
+// ErrNotConfirmed is returned by destructive operations called without
+// explicit confirmation.
+var ErrNotConfirmed = errors.New("operation requires explicit confirmation")
+
+// ListZones returns the zones of the domains the configured user owns. With
+// Accounts, the zones of every account are listed, limited to the zones
+// each account is configured for. In reseller mode, the zones of the
+// reseller's users are included.
+func (p *Provider) ListZones(ctx context.Context) (_ []libdns.Zone, err error) {
+	ctx, end := p.startOperation(ctx, "ListZones", "")
+	defer end(&err)
+
+	domains, err := p.listZones(ctx)
+
+	var zones []libdns.Zone
+	for _, domain := range domains {
+		zones = append(zones, libdns.Zone{Name: domain + "."})
+	}
+
+	return zones, err
+}
+
+// listZones returns the names of the zones ListZones lists.
+func (p *Provider) listZones(ctx context.Context) ([]string, error) {
+	var zones []string
+	seen := map[string]bool{}
+	add := func(domains []string, account *Account) {
+		for _, domain := range domains {
+			if seen[domain] || (account != nil && p.account(context.Background(), domain) != account) {
+				continue
+			}
+			seen[domain] = true
+			zones = append(zones, domain)
+		}
+	}
+
+	if p.Reseller {
+		domains, err := p.resellerDomains(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(domains, nil)
+	} else if len(p.Accounts) == 0 || len(p.User) > 0 || p.Credentials != nil {
+		domains, err := p.listDomains(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(domains, nil)
+	}
+
+	for i := range p.Accounts {
+		account := &p.Accounts[i]
+		domains, err := p.listDomains(withAccount(ctx, account))
+		if err != nil {
+			return zones, fmt.Errorf("failed to list the domains of %v: %w", account.User, err)
+		}
+		add(domains, account)
+	}
+
+	return zones, nil
+}
+
+// GetZoneInfo returns the DNS settings DirectAdmin reports for the zone, such
+// as its DNSSEC state, default TTL and which record types may be edited.
+func (p *Provider) GetZoneInfo(ctx context.Context, zone string) (_ ZoneInfo, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "GetZoneInfo", zone)
+	defer end(&err)
+
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+
+	return respData.zoneInfo(zone)
+}
+
+// CreateZone creates the domain, and with it its DNS zone, for the user the
+// login key belongs to. The key needs the `CMD_API_DOMAIN` permission.
+func (p *Provider) CreateZone(ctx context.Context, zone string, opts CreateZoneOptions) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "CreateZone", zone)
+	defer end(&err)
+
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	queryString := make(url.Values)
+	queryString.Set("action", "create")
+	queryString.Set("json", "yes")
+	queryString.Set("domain", zone)
+	queryString.Set("ssl", onOff(opts.SSL))
+	queryString.Set("cgi", onOff(opts.CGI))
+	queryString.Set("php", onOff(opts.PHP))
+
+	if opts.Bandwidth > 0 {
+		queryString.Set("bandwidth", strconv.Itoa(opts.Bandwidth))
+	} else {
+		queryString.Set("ubandwidth", "unlimited")
+	}
+
+	if opts.Quota > 0 {
+		queryString.Set("quota", strconv.Itoa(opts.Quota))
+	} else {
+		queryString.Set("uquota", "unlimited")
+	}
+
+	return p.executeRequest(ctx, &APIRequest{
+		Command: "CMD_API_DOMAIN",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	})
+}
+
+// DeleteZone deletes the domain, and with it its DNS zone. Since this removes
+// everything DirectAdmin hosts for the domain, opts.Confirm has to be set,
+// otherwise ErrNotConfirmed is returned. The key needs the `CMD_API_DOMAIN`
+// permission.
+func (p *Provider) DeleteZone(ctx context.Context, zone string, opts DeleteZoneOptions) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "DeleteZone", zone)
+	defer end(&err)
+
+	if !opts.Confirm {
+		return ErrNotConfirmed
+	}
+
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	queryString := make(url.Values)
+	queryString.Set("action", "select")
+	queryString.Set("json", "yes")
+	queryString.Set("delete", "yes")
+	queryString.Set("confirmed", "yes")
+	queryString.Set("select0", zone)
+
+	return p.executeRequest(ctx, &APIRequest{
+		Command: "CMD_API_DOMAIN",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	})
+}
+
+// ResetZone replaces all records of the zone with DirectAdmin's default
+// records, the same as the "Reset Defaults" button in the panel. Any record
+// added through the provider or the panel is lost, so opts.Confirm has to be
+// set, otherwise ErrNotConfirmed is returned.
+func (p *Provider) ResetZone(ctx context.Context, zone string, opts ResetZoneOptions) (err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "ResetZone", zone)
+	defer end(&err)
+
+	if !opts.Confirm {
+		return ErrNotConfirmed
+	}
+
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	queryString := make(url.Values)
+	queryString.Set("action", "reset")
+	queryString.Set("json", "yes")
+	queryString.Set("domain", zone)
+
+	return p.executeRequest(ctx, &APIRequest{
+		Command: "CMD_API_DNS_CONTROL",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    zone,
+	})
+}
+
+func onOff(v bool) string {
+	if v {
+		return "ON"
+	}
+	return "OFF"
+}
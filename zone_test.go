@@ -0,0 +1,116 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestFake_GetZoneInfo(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	info, err := provider.GetZoneInfo(ctx, fakeZone+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Zone != fakeZone || !info.TTL || info.FullMXRecords || info.DNSSEC {
+		t.Errorf("expected the zone's settings, got %+v", info)
+	}
+	if info.DefaultTTL != time.Hour {
+		t.Errorf("expected a default TTL of 1h, got %v", info.DefaultTTL)
+	}
+
+	server.FullMXRecords = true
+	if info, err = provider.GetZoneInfo(ctx, fakeZone); err != nil || !info.FullMXRecords {
+		t.Errorf("expected full MX records to be reported, got %+v and %v", info, err)
+	}
+
+	if _, err := provider.GetZoneInfo(ctx, "example.org"); err == nil {
+		t.Error("expected an error for a zone that doesn't exist")
+	}
+}
+
+func TestFake_CreateZone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	var params url.Values
+	provider.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/CMD_API_DOMAIN" {
+			params = req.URL.Query()
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	if err := provider.CreateZone(ctx, "example.org.", CreateZoneOptions{Quota: 500, SSL: true}); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("domain") != "example.org" || params.Get("quota") != "500" || params.Get("ubandwidth") != "unlimited" {
+		t.Errorf("expected the domain and limits to be passed, got %v", params)
+	}
+	if params.Get("ssl") != "ON" || params.Get("php") != "OFF" {
+		t.Errorf("expected the features to be passed, got %v", params)
+	}
+
+	// The new zone can be managed straight away
+	if _, err := provider.AppendRecords(ctx, "example.org", []libdns.Record{{Type: "TXT", Name: "new", Value: "token"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Records("example.org")) != 4 {
+		t.Errorf("expected the template records and the new one, got %v", server.Records("example.org"))
+	}
+
+	if err := provider.CreateZone(ctx, "example.org", CreateZoneOptions{}); err == nil {
+		t.Error("expected an error for a zone that exists")
+	}
+}
+
+func TestFake_DeleteZone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	if err := provider.DeleteZone(ctx, fakeZone, DeleteZoneOptions{}); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed, got %v", err)
+	}
+	if server.RequestCount("CMD_API_DOMAIN") != 0 || server.Records(fakeZone) == nil {
+		t.Fatal("expected nothing to be deleted without confirmation")
+	}
+
+	if err := provider.DeleteZone(ctx, fakeZone+".", DeleteZoneOptions{Confirm: true}); err != nil {
+		t.Fatal(err)
+	}
+	if server.Records(fakeZone) != nil {
+		t.Errorf("expected the zone to be deleted, got %v", server.Records(fakeZone))
+	}
+
+	if err := provider.DeleteZone(ctx, fakeZone, DeleteZoneOptions{Confirm: true}); err == nil {
+		t.Error("expected an error for a zone that doesn't exist")
+	}
+}
+
+func TestFake_ResetZone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	if err := provider.ResetZone(ctx, fakeZone, ResetZoneOptions{}); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed, got %v", err)
+	}
+	if len(server.Records(fakeZone)) != 2 {
+		t.Fatalf("expected the zone to be untouched without confirmation, got %v", server.Records(fakeZone))
+	}
+
+	if err := provider.ResetZone(ctx, fakeZone+".", ResetZoneOptions{Confirm: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range server.Records(fakeZone) {
+		if rec.Name == "www" {
+			t.Errorf("expected the zone to be reset to the template, got %v", server.Records(fakeZone))
+		}
+	}
+}
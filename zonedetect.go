@@ -0,0 +1,331 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/libdns/directadmin/daapi"
+	"github.com/libdns/libdns"
+)
+
+// ErrZoneNotFound is returned when no zone DirectAdmin manages for the
+// configured user contains the requested zone. The error is a
+// *ZoneNotFoundError listing the zones that are available.
+var ErrZoneNotFound = errors.New("zone not found")
+
+// ZoneNotFoundError is returned when zone detection finds no managed zone
+// for a requested zone.
+type ZoneNotFoundError struct {
+	Zone      string
+	Available []string
+}
+
+func (e *ZoneNotFoundError) Error() string {
+	if len(e.Available) == 0 {
+		return fmt.Sprintf("%v: %v, the user has no zones", ErrZoneNotFound, e.Zone)
+	}
+
+	return fmt.Sprintf("%v: %v is not in any of the zones %v", ErrZoneNotFound, e.Zone, strings.Join(e.Available, ", "))
+}
+
+// Is reports whether target is ErrZoneNotFound, or ErrDomainNotFound which
+// DirectAdmin reports for the same condition.
+func (e *ZoneNotFoundError) Is(target error) bool {
+	return target == ErrZoneNotFound || target == ErrDomainNotFound
+}
+
+// ErrZoneMismatch is returned when records for a zone would be written to
+// a managed zone that isn't a parent of it, which would put them under the
+// wrong names. The error is a *ZoneMismatchError.
+var ErrZoneMismatch = errors.New("zone is not part of the managed zone")
+
+// ZoneMismatchError is returned when the records of Zone can't be adjusted
+// to ManagedZone because it isn't one of Zone's parents.
+type ZoneMismatchError struct {
+	Zone        string
+	ManagedZone string
+}
+
+func (e *ZoneMismatchError) Error() string {
+	return fmt.Sprintf("%v: %v is not a subdomain of %v", ErrZoneMismatch, e.Zone, e.ManagedZone)
+}
+
+// Is reports whether target is ErrZoneMismatch.
+func (e *ZoneMismatchError) Is(target error) bool {
+	return target == ErrZoneMismatch
+}
+
+// inManagedZone runs fn, a record operation, against the zone DirectAdmin
+// manages the records of zone in. DirectAdmin keeps subdomains in their
+// parent domain's zone, so when it doesn't know zone, the closest parent
+// zone the user has is used instead, with the record names adjusted to it.
+// The records fn returns are adjusted back to zone. Only fn that change the
+// zone, write, may create the subdomain, see managedZone.
+func (p *Provider) inManagedZone(ctx context.Context, zone string, write bool, records []libdns.Record, fn func(context.Context, string, []libdns.Record) ([]libdns.Record, error)) ([]libdns.Record, error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	managed := zone
+	cached, detected := p.managedZones.Load(zone)
+	if detected {
+		managed = cached.(string)
+	}
+
+	adjusted, err := p.adjustRecordsForZone(records, zone, managed)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := fn(ctx, managed, adjusted)
+	if err != nil && !detected && len(result) == 0 && errors.Is(err, ErrDomainNotFound) {
+		// DirectAdmin doesn't know the zone, it may be a subdomain
+		managed, detectErr := p.managedZone(ctx, zone, write)
+		if detectErr != nil {
+			return nil, detectErr
+		}
+		if managed == zone {
+			return nil, err
+		}
+
+		adjusted, err := p.adjustRecordsForZone(records, zone, managed)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = fn(ctx, managed, adjusted)
+		return p.restoreRecordsForZone(result, zone, managed), err
+	}
+
+	return p.restoreRecordsForZone(result, zone, managed), err
+}
+
+// adjustRecordsForZone makes the names of records in zone relative to
+// managedZone, unless DisableZoneAdjustment is set.
+func (p *Provider) adjustRecordsForZone(records []libdns.Record, zone, managedZone string) ([]libdns.Record, error) {
+	if p.DisableZoneAdjustment {
+		return records, nil
+	}
+
+	return adjustRecordsForZone(records, zone, managedZone)
+}
+
+// restoreRecordsForZone makes the names of records in managedZone relative
+// to zone again, unless DisableZoneAdjustment is set.
+func (p *Provider) restoreRecordsForZone(records []libdns.Record, zone, managedZone string) []libdns.Record {
+	if p.DisableZoneAdjustment {
+		return records
+	}
+
+	return restoreRecordsForZone(records, zone, managedZone)
+}
+
+// managedZone returns the zone holding the records of zone: zone itself if
+// the user has it, or else the closest parent zone. With
+// AutoCreateSubdomains set, the subdomain is created in the parent domain
+// if it doesn't exist yet and write is set; reads of a subdomain that
+// doesn't exist fail with ErrDomainNotFound instead.
+func (p *Provider) managedZone(ctx context.Context, zone string, write bool) (string, error) {
+	if managed, ok := p.managedZones.Load(zone); ok {
+		return managed.(string), nil
+	}
+
+	managed, err := p.detectZone(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	if managed != zone && p.AutoCreateSubdomains {
+		subdomain := zone[:len(zone)-len(managed)-1]
+		if write {
+			if err := p.createSubdomain(ctx, managed, subdomain); err != nil {
+				return "", err
+			}
+		} else if exists, err := p.hasSubdomain(ctx, managed, subdomain); err != nil {
+			return "", err
+		} else if !exists {
+			return "", fmt.Errorf("%w: %v", ErrDomainNotFound, zone)
+		}
+	}
+
+	p.logger(ctx).Debugw("detected zone", "zone", zone, "managed_zone", managed)
+	p.managedZones.Store(zone, managed)
+
+	return managed, nil
+}
+
+// detectZone returns zone itself if the user has it, or else the closest
+// parent zone the user has.
+func (p *Provider) detectZone(ctx context.Context, zone string) (string, error) {
+	zones, err := p.listZones(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the zone of %v: %w", zone, err)
+	}
+
+	var managed string
+	for _, candidate := range zones {
+		if strings.EqualFold(candidate, zone) {
+			managed = candidate
+			break
+		}
+		if strings.HasSuffix(strings.ToLower(zone), "."+strings.ToLower(candidate)) && len(candidate) > len(managed) {
+			managed = candidate
+		}
+	}
+	if len(managed) == 0 {
+		return "", &ZoneNotFoundError{Zone: zone, Available: zones}
+	}
+
+	return managed, nil
+}
+
+// ResolveZone reports the DirectAdmin domain the record methods would
+// change for zone, e.g. `example.com` for `shop.example.com` whose records
+// DirectAdmin keeps in its parent, and the user owning it. Nothing is
+// changed, not even with AutoCreateSubdomains set, so it can be used to
+// check a configuration before records are written. If no zone matches,
+// the error is a *ZoneNotFoundError.
+func (p *Provider) ResolveZone(ctx context.Context, zone string) (managedZone, owner string, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "ResolveZone", zone)
+	defer end(&err)
+
+	if cached, ok := p.managedZones.Load(zone); ok {
+		managedZone = cached.(string)
+	} else if managedZone, err = p.detectZone(ctx, zone); err != nil {
+		return "", "", err
+	}
+
+	creds, err := p.baseCredentials(ctx, managedZone)
+	if err != nil {
+		return "", "", err
+	}
+	owner = creds.User
+
+	if p.Reseller {
+		user, err := p.zoneOwner(ctx, managedZone)
+		if err != nil {
+			return "", "", err
+		}
+		if len(user) > 0 {
+			owner = user
+		}
+	}
+
+	return managedZone, owner, nil
+}
+
+// hasSubdomain reports whether DirectAdmin has the subdomain of domain.
+func (p *Provider) hasSubdomain(ctx context.Context, domain, subdomain string) (bool, error) {
+	body, err := p.showList(ctx, "CMD_API_SUBDOMAINS", url.Values{"domain": {domain}})
+	if err != nil {
+		return false, fmt.Errorf("failed to list the subdomains of %v: %w", domain, err)
+	}
+
+	subdomains, err := daapi.ParseList("CMD_API_SUBDOMAINS", body)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the subdomains of %v: %w", domain, err)
+	}
+
+	for _, existing := range subdomains {
+		if strings.EqualFold(existing, subdomain) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// createSubdomain creates the subdomain of domain in DirectAdmin, unless it
+// exists already.
+func (p *Provider) createSubdomain(ctx context.Context, domain, subdomain string) error {
+	queryString := make(url.Values)
+	queryString.Set("action", "create")
+	queryString.Set("json", "yes")
+	queryString.Set("domain", domain)
+	queryString.Set("subdomain", subdomain)
+
+	err := p.executeRequest(ctx, &APIRequest{
+		Command: "CMD_API_SUBDOMAINS",
+		Method:  http.MethodGet,
+		Params:  queryString,
+		Zone:    domain,
+	})
+	if errors.Is(err, ErrDuplicateRecord) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create subdomain %v of %v: %w", subdomain, domain, err)
+	}
+
+	p.logger(ctx).Infow("created subdomain", "domain", domain, "subdomain", subdomain)
+
+	return nil
+}
+
+func adjustRecordsForZone(records []libdns.Record, zone, managedZone string) ([]libdns.Record, error) {
+	if zone == managedZone || records == nil {
+		return records, nil
+	}
+
+	adjusted := make([]libdns.Record, len(records))
+	for i, rec := range records {
+		var err error
+		if adjusted[i], err = adjustRecordForZone(rec, zone, managedZone); err != nil {
+			return nil, err
+		}
+	}
+
+	return adjusted, nil
+}
+
+// adjustRecordForZone makes the name of a record in zone relative to
+// managedZone, a parent of zone. Anything else is a *ZoneMismatchError,
+// writing the record unadjusted would put it under the wrong name.
+func adjustRecordForZone(record libdns.Record, zone, managedZone string) (libdns.Record, error) {
+	if !strings.HasSuffix(strings.ToLower(zone), "."+strings.ToLower(managedZone)) {
+		return record, &ZoneMismatchError{Zone: zone, ManagedZone: managedZone}
+	}
+	prefix := zone[:len(zone)-len(managedZone)-1]
+
+	switch name := relativeName(record.Name, zone); name {
+	case "@":
+		record.Name = prefix
+	default:
+		record.Name = name + "." + prefix
+	}
+
+	return record, nil
+}
+
+// restoreRecordsForZone makes the names of records in managedZone relative
+// to zone again, dropping records outside zone.
+func restoreRecordsForZone(records []libdns.Record, zone, managedZone string) []libdns.Record {
+	if zone == managedZone || records == nil {
+		return records
+	}
+	if !strings.HasSuffix(strings.ToLower(zone), "."+strings.ToLower(managedZone)) {
+		// None of the records can be in zone
+		return nil
+	}
+
+	prefix := strings.ToLower(zone[:len(zone)-len(managedZone)-1])
+
+	restored := make([]libdns.Record, 0, len(records))
+	for _, rec := range records {
+		switch name := relativeName(rec.Name, managedZone); {
+		case name == prefix:
+			rec.Name = "@"
+		case strings.HasSuffix(name, "."+prefix):
+			rec.Name = strings.TrimSuffix(name, "."+prefix)
+		default:
+			continue
+		}
+		restored = append(restored, rec)
+	}
+
+	return restored
+}
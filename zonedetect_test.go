@@ -0,0 +1,223 @@
+package directadmin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestAdjustRecordForZone(t *testing.T) {
+	var tests = []struct {
+		name string
+		want string
+	}{
+		{name: "@", want: "shop"},
+		{name: "", want: "shop"},
+		{name: "www", want: "www.shop"},
+		{name: "www.shop.example.com.", want: "www.shop"},
+	}
+
+	for _, tt := range tests {
+		got, err := adjustRecordForZone(libdns.Record{Name: tt.name}, "shop.example.com", "example.com")
+		if err != nil {
+			t.Fatalf("%q: %v", tt.name, err)
+		}
+		if got.Name != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.name, tt.want, got.Name)
+		}
+
+		restored := restoreRecordsForZone([]libdns.Record{got}, "shop.example.com", "example.com")
+		if len(restored) != 1 || restored[0].Name != relativeName(tt.name, "shop.example.com") {
+			t.Errorf("%q: expected the name to be restored, got %v", tt.name, restored)
+		}
+	}
+
+	if restored := restoreRecordsForZone([]libdns.Record{{Name: "www"}}, "shop.example.com", "example.com"); len(restored) != 0 {
+		t.Errorf("expected records outside the zone to be dropped, got %v", restored)
+	}
+
+	_, err := adjustRecordForZone(libdns.Record{Name: "www"}, "shop.example.com", "example.org")
+	var mismatch *ZoneMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrZoneMismatch) || mismatch.ManagedZone != "example.org" {
+		t.Errorf("expected a ZoneMismatchError, got %v", err)
+	}
+}
+
+func TestFake_ZoneDetection(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	_, err := provider.AppendRecords(ctx, "shop."+fakeZone, []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, rec := range server.Records(fakeZone) {
+		found = found || (rec.Type == "TXT" && rec.Name == "_acme-challenge.shop")
+	}
+	if !found {
+		t.Errorf("expected the record in the parent zone, got %v", server.Records(fakeZone))
+	}
+	if subdomains := server.Subdomains(fakeZone); len(subdomains) != 0 {
+		t.Errorf("expected no subdomain to be created, got %v", subdomains)
+	}
+
+	records, err := provider.GetRecords(ctx, "shop."+fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Name != "_acme-challenge" {
+		t.Errorf("expected only the subdomain's record, got %v", records)
+	}
+
+	if _, err := provider.DeleteRecords(ctx, "shop."+fakeZone, records); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Records(fakeZone)) != 2 {
+		t.Errorf("expected the record to be deleted, got %v", server.Records(fakeZone))
+	}
+
+	_, err = provider.GetRecords(ctx, "example.org")
+	var notFound *ZoneNotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("expected a ZoneNotFoundError, got %v", err)
+	}
+	if !reflect.DeepEqual(notFound.Available, []string{fakeZone}) {
+		t.Errorf("expected the available zones to be listed, got %v", notFound.Available)
+	}
+}
+
+func TestFake_AutoCreateSubdomains(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.AutoCreateSubdomains = true
+
+	// Reads don't create the subdomain
+	if _, err := provider.GetRecords(ctx, "shop."+fakeZone); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("expected ErrDomainNotFound, got %v", err)
+	}
+	if subdomains := server.Subdomains(fakeZone); len(subdomains) != 0 {
+		t.Fatalf("expected nothing to be created, got subdomains %v", subdomains)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := provider.SetRecords(ctx, "shop."+fakeZone, []libdns.Record{
+			{Type: "A", Name: "@", Value: "192.0.2.2"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if subdomains := server.Subdomains(fakeZone); !reflect.DeepEqual(subdomains, []string{"shop"}) {
+		t.Errorf("expected the subdomain to be created once, got %v", subdomains)
+	}
+
+	records, err := provider.GetRecords(ctx, "shop."+fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Value != "192.0.2.2" {
+		t.Errorf("expected the subdomain's A record to be set, got %v", records)
+	}
+
+	// Subdomains that exist can be read without writing them first
+	other, _ := newFakeProvider(t)
+	other.ServerURL = provider.ServerURL
+	other.AutoCreateSubdomains = true
+	if records, err := other.GetRecords(ctx, "shop."+fakeZone); err != nil || len(records) != 1 {
+		t.Errorf("expected the subdomain's A record, got %v, %v", records, err)
+	}
+}
+
+func TestFake_DisableZoneAdjustment(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.DisableZoneAdjustment = true
+
+	_, err := provider.AppendRecords(ctx, "shop."+fakeZone, []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge.shop", Value: "token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, rec := range server.Records(fakeZone) {
+		found = found || (rec.Type == "TXT" && rec.Name == "_acme-challenge.shop")
+	}
+	if !found {
+		t.Errorf("expected the name to be passed through, got %v", server.Records(fakeZone))
+	}
+
+	records, err := provider.GetRecords(ctx, "shop."+fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Errorf("expected all records of the managed zone, got %v", records)
+	}
+}
+
+func TestFake_ResolveZone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+	provider.AutoCreateSubdomains = true
+
+	managed, owner, err := provider.ResolveZone(ctx, "shop."+fakeZone+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if managed != fakeZone || owner != "admin" {
+		t.Errorf("expected %v owned by admin, got %v owned by %v", fakeZone, managed, owner)
+	}
+	if subdomains := server.Subdomains(fakeZone); len(subdomains) != 0 {
+		t.Errorf("expected nothing to be created, got subdomains %v", subdomains)
+	}
+
+	if _, _, err := provider.ResolveZone(ctx, "example.org"); !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("expected ErrZoneNotFound, got %v", err)
+	}
+}
+
+func TestFake_ZoneDetectionInOperation(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+	var buf bytes.Buffer
+	provider.Logger = NewStdLogger(log.New(&buf, "", 0), true)
+
+	_, err := provider.AppendRecords(context.Background(), "example.org", []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token"},
+	})
+
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Op != "AppendRecords" || !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("expected the ZoneNotFoundError in an OperationError, got %v", err)
+	}
+
+	// Zone detection logs under the operation's request ID, and retrying in
+	// the detected zone doesn't start another operation
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, opErr.RequestID) {
+			t.Errorf("expected every log line to carry request ID %v, got %q", opErr.RequestID, line)
+		}
+	}
+
+	// The operation's deadline covers zone detection
+	provider.OperationTimeout = time.Nanosecond
+	_, err = provider.AppendRecords(context.Background(), "shop.example.org", []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token"},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the operation to time out, got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package directadmin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ExportZone renders all records of the zone, including SOA and NS records
+// as reported by DirectAdmin, as an RFC 1035 zone file. Unlike GetRecords,
+// record types the provider can't convert to libdns records are included.
+func (p *Provider) ExportZone(ctx context.Context, zone string) (_ string, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "ExportZone", zone)
+	defer end(&err)
+
+	respData, err := p.getZone(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := respData.zoneInfo(zone)
+	if err != nil {
+		return "", err
+	}
+
+	return respData.zoneFile(zone, info.DefaultTTL), nil
+}
+
+func (z daZone) zoneFile(zone string, defaultTTL time.Duration) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "; %s exported from DirectAdmin\n", zone)
+	fmt.Fprintf(&sb, "$ORIGIN %s.\n", zone)
+	if defaultTTL > 0 {
+		fmt.Fprintf(&sb, "$TTL %d\n", int(defaultTTL.Seconds()))
+	}
+	sb.WriteString("\n")
+
+	tw := tabwriter.NewWriter(&sb, 0, 8, 1, '\t', 0)
+
+	// SOA first, then NS, then everything else in the order DirectAdmin returned
+	for _, pass := range []func(string) bool{
+		func(t string) bool { return t == "SOA" },
+		func(t string) bool { return t == "NS" },
+		func(t string) bool { return t != "SOA" && t != "NS" },
+	} {
+		for _, rec := range z.Records {
+			if !pass(rec.Type) {
+				continue
+			}
+
+			name := rec.Name
+			if len(name) == 0 || name == zone+"." {
+				name = "@"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\tIN\t%s\t%s\n", name, rec.TTL, rec.Type, zoneFileValue(rec.Type, rec.Value))
+		}
+	}
+
+	_ = tw.Flush()
+
+	return sb.String()
+}
+
+// zoneFileValue quotes TXT data DirectAdmin returned without quotes, split
+// into strings of at most 255 characters.
+func zoneFileValue(recordType, value string) string {
+	switch recordType {
+	case "TXT", "SPF":
+		if !strings.HasPrefix(value, "\"") {
+			return quoteTXT(value)
+		}
+	}
+
+	return value
+}
+
+// ParseZoneFile reads the records of an RFC 1035 zone file, such as one
+// written by ExportZone, for use with SyncZone. Names are returned relative
+// to zone. $ORIGIN and $TTL are honored; $INCLUDE is not supported. TXT
+// values are unquoted, and MX and SRV priorities and weights are moved to
+// the record's fields.
+func ParseZoneFile(r io.Reader, zone string) ([]libdns.Record, error) {
+	zone = strings.TrimSuffix(zone, ".")
+	origin := zone
+	var defaultTTL time.Duration
+	var owner string
+
+	var records []libdns.Record
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := stripComment(scanner.Text())
+
+		// Join records spanning several lines in parentheses
+		startLine := lineNo
+		for parenDepth(line) > 0 && scanner.Scan() {
+			lineNo++
+			line += " " + stripComment(scanner.Text())
+		}
+
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		continued := line[0] == ' ' || line[0] == '\t'
+		fields := zoneFileFields(line)
+
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: $ORIGIN without a name", startLine)
+			}
+			origin = strings.TrimSuffix(fields[1], ".")
+			continue
+		case "$TTL":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: $TTL without a value", startLine)
+			}
+			ttl, err := parseZoneTTL(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", startLine, err)
+			}
+			defaultTTL = ttl
+			continue
+		case "$INCLUDE":
+			return nil, fmt.Errorf("line %d: $INCLUDE is not supported", startLine)
+		}
+
+		if !continued {
+			owner = zoneFileName(fields[0], origin, zone)
+			fields = fields[1:]
+		} else if len(owner) == 0 {
+			return nil, fmt.Errorf("line %d: record without an owner name", startLine)
+		}
+
+		rec := libdns.Record{Name: owner, TTL: defaultTTL}
+
+		// The TTL and class are optional and may come in either order
+		for len(fields) > 0 {
+			if strings.EqualFold(fields[0], "IN") {
+				fields = fields[1:]
+				continue
+			}
+			if ttl, err := parseZoneTTL(fields[0]); err == nil {
+				rec.TTL = ttl
+				fields = fields[1:]
+				continue
+			}
+			break
+		}
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: incomplete record", startLine)
+		}
+
+		rec.Type = strings.ToUpper(fields[0])
+		rdata := fields[1:]
+
+		switch rec.Type {
+		case "TXT", "SPF":
+			var value strings.Builder
+			for _, s := range rdata {
+				value.WriteString(unquoteZoneString(s))
+			}
+			rec.Value = value.String()
+		case "MX":
+			priority, err := strconv.ParseUint(rdata[0], 10, 16)
+			if err != nil || len(rdata) != 2 {
+				return nil, fmt.Errorf("line %d: invalid MX record", startLine)
+			}
+			rec.Priority = uint(priority)
+			rec.Value = rdata[1]
+		case "SRV":
+			if len(rdata) != 4 {
+				return nil, fmt.Errorf("line %d: invalid SRV record", startLine)
+			}
+			priority, err1 := strconv.ParseUint(rdata[0], 10, 16)
+			weight, err2 := strconv.ParseUint(rdata[1], 10, 16)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("line %d: invalid SRV record", startLine)
+			}
+			rec.Priority = uint(priority)
+			rec.Weight = uint(weight)
+			rec.Value = rdata[2] + " " + rdata[3]
+		default:
+			rec.Value = strings.Join(rdata, " ")
+		}
+
+		records = append(records, rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// stripComment removes a comment from a zone file line, ignoring
+// semicolons in quoted strings.
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
+
+// parenDepth returns how many parentheses outside quoted strings are left
+// open at the end of the line.
+func parenDepth(line string) int {
+	depth := 0
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+			}
+		}
+	}
+
+	return depth
+}
+
+// zoneFileFields splits a zone file line at whitespace and parentheses,
+// keeping quoted strings together including their quotes.
+func zoneFileFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			field.WriteByte(c)
+			field.WriteByte(line[i+1])
+			i++
+		case c == '"':
+			quoted = !quoted
+			field.WriteByte(c)
+		case (c == ' ' || c == '\t' || c == '(' || c == ')') && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+// zoneFileName converts an owner name, relative to origin unless it ends
+// with a dot, to a name relative to zone.
+func zoneFileName(name, origin, zone string) string {
+	if name == "@" {
+		name = origin + "."
+	} else if !strings.HasSuffix(name, ".") {
+		name = name + "." + origin + "."
+	}
+
+	return libdnsName(name, zone)
+}
+
+func unquoteZoneString(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// parseZoneTTL parses a TTL in seconds or with BIND's unit suffixes, e.g.
+// 1h30m.
+func parseZoneTTL(s string) (time.Duration, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	units := map[byte]time.Duration{
+		's': time.Second,
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+
+	var ttl time.Duration
+	start := 0
+	for i := 0; i < len(s); i++ {
+		unit, ok := units[s[i]|0x20]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(s[start:i], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		ttl += time.Duration(n) * unit
+		start = i + 1
+	}
+
+	if start != len(s) || len(s) == 0 {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+
+	return ttl, nil
+}
@@ -0,0 +1,139 @@
+package directadmin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/directadmin/directadmintest"
+	"github.com/libdns/libdns"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	const zoneFile = `; example
+$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2024010101 ; serial
+		3600 3600 1209600 86400 )
+	IN	NS	ns1.example.com.
+www	300	IN	A	192.0.2.1
+mail.example.com.	IN	3600	AAAA	2001:db8::1
+@	MX	10 mail
+_sip._tcp	SRV	10 20 5060 sip.example.com.
+txt	TXT	"v=spf1 -all; really" "(second)"
+$ORIGIN sub.example.com.
+deep	1d	CNAME	www.example.com.
+`
+
+	records, err := ParseZoneFile(strings.NewReader(zoneFile), "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []libdns.Record{
+		{Type: "SOA", Name: "@", Value: "ns1.example.com. hostmaster.example.com. 2024010101 3600 3600 1209600 86400", TTL: time.Hour},
+		{Type: "NS", Name: "@", Value: "ns1.example.com.", TTL: time.Hour},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300 * time.Second},
+		{Type: "AAAA", Name: "mail", Value: "2001:db8::1", TTL: time.Hour},
+		{Type: "MX", Name: "@", Value: "mail", Priority: 10, TTL: time.Hour},
+		{Type: "SRV", Name: "_sip._tcp", Value: "5060 sip.example.com.", Priority: 10, Weight: 20, TTL: time.Hour},
+		{Type: "TXT", Name: "txt", Value: "v=spf1 -all; really(second)", TTL: time.Hour},
+		{Type: "CNAME", Name: "deep.sub", Value: "www.example.com.", TTL: 24 * time.Hour},
+	}
+
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("expected\n%v\ngot\n%v", want, records)
+	}
+}
+
+func TestParseZoneFile_RoundTrip(t *testing.T) {
+	exported := daZone{Records: []daRecord{
+		{Type: "NS", Name: "example.com.", Value: "ns1.example.com.", TTL: "3600"},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: "300"},
+		{Type: "TXT", Name: "_acme-challenge", Value: `say "hi"`, TTL: "60"},
+	}}.zoneFile("example.com", time.Hour)
+
+	records, err := ParseZoneFile(strings.NewReader(exported), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []libdns.Record{
+		{Type: "NS", Name: "@", Value: "ns1.example.com.", TTL: time.Hour},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300 * time.Second},
+		{Type: "TXT", Name: "_acme-challenge", Value: `say "hi"`, TTL: time.Minute},
+	}
+
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("expected\n%v\ngot\n%v\nfrom\n%s", want, records, exported)
+	}
+}
+
+func TestFake_ExportZone(t *testing.T) {
+	provider, server := newFakeProvider(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkim := "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+
+	// DirectAdmin returns TXT values without quotes
+	server.AddZone(fakeZone,
+		directadmintest.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300},
+		directadmintest.Record{Type: "TXT", Name: "mail._domainkey", Value: dkim, TTL: 3600},
+		directadmintest.Record{Type: "NS", Name: fakeZone + ".", Value: "ns1.example.net.", TTL: 3600},
+	)
+
+	exported, err := provider.ExportZone(context.Background(), fakeZone+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(exported, "\n")
+	if !strings.HasPrefix(lines[1], "$ORIGIN example.com.") || !strings.HasPrefix(lines[2], "$TTL 3600") {
+		t.Errorf("expected the origin and default TTL, got\n%s", exported)
+	}
+	if !strings.HasPrefix(lines[4], "@") || !strings.Contains(lines[4], "NS") {
+		t.Errorf("expected the NS record first, got\n%s", exported)
+	}
+
+	var txt string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "mail._domainkey") {
+			txt = line[strings.Index(line, `"`):]
+		}
+	}
+	strs := zoneFileFields(txt)
+	if len(strs) != 2 {
+		t.Fatalf("expected the key to be split into 2 strings, got %v", txt)
+	}
+	for _, s := range strs {
+		if len(unquoteZoneString(s)) > maxTXTString {
+			t.Errorf("string longer than %d characters: %v", maxTXTString, s)
+		}
+	}
+
+	records, err := ParseZoneFile(strings.NewReader(exported), fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, rec := range records {
+		found = found || (rec.Type == "TXT" && rec.Value == dkim)
+	}
+	if !found {
+		t.Errorf("expected the DKIM key to round trip, got %v", records)
+	}
+}
@@ -0,0 +1,106 @@
+package directadmin
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// zoneQueue serializes the changes to each zone while changes to different
+// zones proceed in parallel. Callers waiting for the same zone are served in
+// the order they arrived. Reads don't take a turn, so they never wait for
+// changes. The zero value is ready to use.
+type zoneQueue struct {
+	mutex sync.Mutex
+	zones map[string]*zoneSlot
+}
+
+// zoneSlot is held by the change in progress on a zone. refs counts it and
+// the changes waiting, so the slot can be dropped once the zone is idle.
+type zoneSlot struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits until it's the caller's turn to change the zone and returns
+// the function ending it, or the context's error if it's done first.
+func (q *zoneQueue) lock(ctx context.Context, zone string) (func(), error) {
+	zone = normalizeZoneKey(zone)
+
+	q.mutex.Lock()
+	if q.zones == nil {
+		q.zones = make(map[string]*zoneSlot)
+	}
+	slot, ok := q.zones[zone]
+	if !ok {
+		slot = &zoneSlot{held: make(chan struct{}, 1)}
+		q.zones[zone] = slot
+	}
+	slot.refs++
+	q.mutex.Unlock()
+
+	// Goroutines blocked sending on a channel are woken in order, which
+	// makes the queue fair
+	select {
+	case slot.held <- struct{}{}:
+	case <-ctx.Done():
+		q.release(zone, slot)
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-slot.held
+		q.release(zone, slot)
+	}, nil
+}
+
+func (q *zoneQueue) release(zone string, slot *zoneSlot) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	slot.refs--
+	if slot.refs == 0 {
+		delete(q.zones, zone)
+	}
+}
+
+// normalizeZoneKey returns the key the zone is queued under.
+func normalizeZoneKey(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+// zoneLockKey marks the zones a context holds the queue's turn for.
+type zoneLockKey struct{ zone string }
+
+// lockZone waits for the turn to change the zone, unless ctx already holds
+// it, and returns a context marking it as held.
+func (p *Provider) lockZone(ctx context.Context, zone string) (context.Context, func(), error) {
+	key := zoneLockKey{normalizeZoneKey(zone)}
+	if ctx.Value(key) != nil {
+		return ctx, func() {}, nil
+	}
+
+	unlock, err := p.writes.lock(ctx, zone)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return context.WithValue(ctx, key, true), unlock, nil
+}
+
+// serialized wraps a function changing a zone so it runs in the zone's
+// turn, making the reads and writes of a whole batch atomic with respect to
+// other changes made through the provider.
+func (p *Provider) serialized(fn func(context.Context, string, []libdns.Record) ([]libdns.Record, error)) func(context.Context, string, []libdns.Record) ([]libdns.Record, error) {
+	return func(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+		ctx, unlock, err := p.lockZone(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		return fn(ctx, zone, records)
+	}
+}
@@ -0,0 +1,155 @@
+package directadmin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestZoneQueue(t *testing.T) {
+	t.Run("fifo", func(t *testing.T) {
+		var q zoneQueue
+		unlock, err := q.lock(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			mutex sync.Mutex
+			order []int
+			wg    sync.WaitGroup
+		)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				unlock, err := q.lock(context.Background(), "Example.com.")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mutex.Lock()
+				order = append(order, i)
+				mutex.Unlock()
+				unlock()
+			}(i)
+
+			// Wait for the goroutine to queue up before starting the next
+			for !queued(&q, "example.com", i+2) {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		unlock()
+		wg.Wait()
+
+		if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(order, want) {
+			t.Errorf("expected the changes in order %v, got %v", want, order)
+		}
+		if len(q.zones) != 0 {
+			t.Errorf("expected idle zones to be dropped, got %v", q.zones)
+		}
+	})
+
+	t.Run("parallel zones", func(t *testing.T) {
+		var q zoneQueue
+		unlock, err := q.lock(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		other, err := q.lock(ctx, "example.org")
+		if err != nil {
+			t.Fatalf("expected another zone not to wait, got %v", err)
+		}
+		other()
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		var q zoneQueue
+		unlock, err := q.lock(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := q.lock(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, got %v", err)
+		}
+
+		unlock()
+		if len(q.zones) != 0 {
+			t.Errorf("expected idle zones to be dropped, got %v", q.zones)
+		}
+	})
+}
+
+func TestProvider_LockZoneReentrant(t *testing.T) {
+	provider := &Provider{}
+
+	ctx, unlock, err := provider.lockZone(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	_, inner, err := provider.lockZone(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("expected a context holding the zone to proceed, got %v", err)
+	}
+	inner()
+}
+
+func queued(q *zoneQueue, zone string, refs int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	slot, ok := q.zones[zone]
+	return ok && slot.refs == refs
+}
+
+func TestFake_ReadsDuringWrites(t *testing.T) {
+	provider, _ := newFakeProvider(t)
+
+	// Hold the zone's turn as a change in progress would
+	_, unlock, err := provider.lockZone(context.Background(), fakeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.GetRecords(ctx, fakeZone)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected reads not to wait for the change, got %v", err)
+		}
+	}
+}
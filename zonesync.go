@@ -0,0 +1,215 @@
+package directadmin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// SyncOptions configures SyncZone.
+type SyncOptions struct {
+	// Apply makes SyncZone carry out the plan. Without it, SyncZone only
+	// reports the changes it would make.
+	Apply bool
+
+	// IgnoreTypes lists record types that are neither created, updated nor
+	// deleted. SOA records are always ignored.
+	IgnoreTypes []string
+
+	// Merge keeps records that aren't in the desired state instead of
+	// deleting them.
+	Merge bool
+}
+
+// SyncPlan lists the changes needed to bring a zone to its desired state.
+type SyncPlan struct {
+	Create []libdns.Record `json:"create"`
+	Update []libdns.Record `json:"update"`
+	Delete []libdns.Record `json:"delete"`
+}
+
+// Empty reports whether the zone already matches the desired state.
+func (sp *SyncPlan) Empty() bool {
+	return len(sp.Create) == 0 && len(sp.Update) == 0 && len(sp.Delete) == 0
+}
+
+// SyncZone reconciles the zone with the desired records: records missing from
+// the zone are created, records whose TTL differs are updated and records not
+// in desired are deleted. Records are matched by name, type, value and
+// priority. The returned plan is only applied when opts.Apply is set; if
+// applying fails, the plan is returned with the error. With OwnerID set,
+// records the provider doesn't own are neither updated nor deleted, and a
+// plan changing ProtectedRecords isn't applied at all. Leases are never
+// changed; with LeaseLock set, the plan is applied holding the zone's lease.
+func (p *Provider) SyncZone(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (_ *SyncPlan, err error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	ctx, end := p.startOperation(ctx, "SyncZone", zone)
+	defer end(&err)
+
+	if !opts.Apply {
+		return p.syncZone(ctx, zone, desired, opts)
+	}
+
+	var plan *SyncPlan
+	err = p.leased(ctx, zone, func(ctx context.Context) (err error) {
+		plan, err = p.syncZone(ctx, zone, desired, opts)
+		return err
+	})
+
+	return plan, err
+}
+
+func (p *Provider) syncZone(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (_ *SyncPlan, err error) {
+	var current []libdns.Record
+	var owned *ownership
+	if len(p.OwnerID) > 0 {
+		current, owned, err = p.readOwnership(ctx, zone)
+	} else {
+		current, err = p.getZoneRecords(ctx, zone)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Another instance's lease must survive the sync
+	current = withoutLeases(current)
+	if p.HideDefaultRecords {
+		current = withoutDefaultRecords(zone, current)
+	}
+
+	withTTL := make([]libdns.Record, len(desired))
+	for i, rec := range desired {
+		rec.TTL = p.recordTTL(rec.TTL)
+		withTTL[i] = rec
+	}
+
+	plan := planSync(zone, current, withTTL, opts.IgnoreTypes)
+	if opts.Merge {
+		plan.Delete = nil
+	}
+	if owned != nil {
+		plan.Update = owned.filter(p, plan.Update)
+		plan.Delete = owned.filter(p, plan.Delete)
+	}
+	if !opts.Apply {
+		return plan, nil
+	}
+
+	if err := p.checkProtected(zone, plan.Update); err != nil {
+		return plan, err
+	}
+	if err := p.checkDeletable(zone, plan.Delete); err != nil {
+		return plan, err
+	}
+
+	for _, rec := range plan.Delete {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		if _, err := p.deleteZoneRecord(ctx, zone, rec); err != nil {
+			return plan, fmt.Errorf("failed to delete %v %v: %w", rec.Type, rec.Name, err)
+		}
+		if owned != nil {
+			if err := p.release(ctx, owned, rec); err != nil {
+				return plan, err
+			}
+		}
+	}
+
+	for _, rec := range plan.Update {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		if _, err := p.setZoneRecord(ctx, zone, rec); err != nil {
+			return plan, fmt.Errorf("failed to update %v %v: %w", rec.Type, rec.Name, err)
+		}
+	}
+
+	for _, rec := range plan.Create {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		created, err := p.appendZoneRecord(ctx, zone, rec)
+		if err != nil {
+			return plan, fmt.Errorf("failed to create %v %v: %w", rec.Type, rec.Name, err)
+		}
+		if owned != nil {
+			if err := p.claim(ctx, owned, created); err != nil {
+				return plan, err
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+func planSync(zone string, current, desired []libdns.Record, ignoreTypes []string) *SyncPlan {
+	ignored := map[string]bool{"SOA": true}
+	for _, t := range ignoreTypes {
+		ignored[strings.ToUpper(t)] = true
+	}
+
+	existing := make(map[string]libdns.Record, len(current))
+	for _, rec := range current {
+		if ignored[rec.Type] {
+			continue
+		}
+		existing[recordKey(zone, rec)] = rec
+	}
+
+	plan := &SyncPlan{}
+
+	seen := make(map[string]bool, len(desired))
+	for _, rec := range desired {
+		if ignored[rec.Type] {
+			continue
+		}
+
+		key := recordKey(zone, rec)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		cur, ok := existing[key]
+		switch {
+		case !ok:
+			plan.Create = append(plan.Create, rec)
+		case cur.TTL != rec.TTL:
+			rec.ID = cur.ID
+			plan.Update = append(plan.Update, rec)
+		}
+	}
+
+	for _, rec := range current {
+		if ignored[rec.Type] || seen[recordKey(zone, rec)] {
+			continue
+		}
+		plan.Delete = append(plan.Delete, rec)
+	}
+
+	return plan
+}
+
+// recordKey identifies a record by everything but its TTL and ID.
+func recordKey(zone string, rec libdns.Record) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d", strings.ToUpper(rec.Type), relativeName(rec.Name, zone), rec.Value, rec.Priority)
+}
+
+// relativeName normalizes a record name to be relative to the zone, with
+// "@" for the apex.
+func relativeName(name, zone string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	zone = strings.ToLower(zone)
+
+	switch {
+	case len(name) == 0 || name == "@" || name == zone:
+		return "@"
+	case strings.HasSuffix(name, "."+zone):
+		return strings.TrimSuffix(name, "."+zone)
+	default:
+		return name
+	}
+}
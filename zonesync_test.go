@@ -0,0 +1,142 @@
+package directadmin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestPlanSync(t *testing.T) {
+	var (
+		soa   = libdns.Record{ID: "soa", Type: "SOA", Name: "@", Value: "ns1.example.com. hostmaster.example.com. 1 3600 3600 1209600 86400", TTL: time.Hour}
+		ns    = libdns.Record{ID: "ns", Type: "NS", Name: "@", Value: "ns1.example.net.", TTL: time.Hour}
+		www   = libdns.Record{ID: "www", Type: "A", Name: "www", Value: "192.0.2.1", TTL: 5 * time.Minute}
+		mx10  = libdns.Record{ID: "mx", Type: "MX", Name: "@", Value: "mail.example.com.", Priority: 10, TTL: time.Hour}
+		mx20  = libdns.Record{Type: "MX", Name: "@", Value: "mail.example.com.", Priority: 20, TTL: time.Hour}
+		txt   = libdns.Record{Type: "TXT", Name: "new", Value: "token", TTL: time.Minute}
+		www2  = libdns.Record{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 5 * time.Minute}
+		wwwLo = libdns.Record{Type: "a", Name: "WWW.example.com.", Value: "192.0.2.1", TTL: time.Minute}
+	)
+	current := []libdns.Record{soa, ns, www, mx10}
+
+	var tests = []struct {
+		name    string
+		desired []libdns.Record
+		ignore  []string
+		want    *SyncPlan
+	}{
+		{
+			name:    "in sync",
+			desired: []libdns.Record{ns, www, mx10},
+			want:    &SyncPlan{},
+		},
+		{
+			name:    "create",
+			desired: []libdns.Record{ns, www, mx10, txt},
+			want:    &SyncPlan{Create: []libdns.Record{txt}},
+		},
+		{
+			name:    "delete",
+			desired: []libdns.Record{ns, mx10},
+			want:    &SyncPlan{Delete: []libdns.Record{www}},
+		},
+		{
+			name:    "new value replaces the record",
+			desired: []libdns.Record{ns, www2, mx10},
+			want:    &SyncPlan{Create: []libdns.Record{www2}, Delete: []libdns.Record{www}},
+		},
+		{
+			name:    "priority is part of the record",
+			desired: []libdns.Record{ns, www, mx20},
+			want:    &SyncPlan{Create: []libdns.Record{mx20}, Delete: []libdns.Record{mx10}},
+		},
+		{
+			name:    "TTL updates keep the ID",
+			desired: []libdns.Record{ns, wwwLo, mx10},
+			want:    &SyncPlan{Update: []libdns.Record{{ID: "www", Type: "a", Name: "WWW.example.com.", Value: "192.0.2.1", TTL: time.Minute}}},
+		},
+		{
+			name:    "duplicates",
+			desired: []libdns.Record{ns, www, www, mx10, txt, txt},
+			want:    &SyncPlan{Create: []libdns.Record{txt}},
+		},
+		{
+			name:    "SOA is never changed",
+			desired: []libdns.Record{{Type: "SOA", Name: "@", Value: "changed"}, ns, www, mx10},
+			want:    &SyncPlan{},
+		},
+		{
+			name:    "ignored types",
+			desired: []libdns.Record{www, txt},
+			ignore:  []string{"ns", "MX"},
+			want:    &SyncPlan{Create: []libdns.Record{txt}},
+		},
+		{
+			name:    "empty",
+			desired: nil,
+			want:    &SyncPlan{Delete: []libdns.Record{ns, www, mx10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planSync("example.com", current, tt.desired, tt.ignore)
+			if !reflect.DeepEqual(plan, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, plan)
+			}
+			if plan.Empty() != tt.want.Empty() {
+				t.Errorf("expected Empty() to be %v", tt.want.Empty())
+			}
+		})
+	}
+}
+
+func TestFake_SyncZone(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newFakeProvider(t)
+
+	desired := []libdns.Record{
+		{Type: "NS", Name: "@", Value: "ns1.example.net.", TTL: time.Hour},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: time.Minute},
+		{Type: "TXT", Name: "new", Value: "token", TTL: time.Minute},
+	}
+
+	plan, err := provider.SyncZone(ctx, fakeZone, desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Create) != 1 || len(plan.Update) != 1 || len(plan.Delete) != 0 {
+		t.Errorf("expected one record to be created and one updated, got %+v", plan)
+	}
+	if server.RequestCount("CMD_API_DNS_CONTROL") != 1 {
+		t.Errorf("expected the plan not to be applied, got %d requests", server.RequestCount("CMD_API_DNS_CONTROL"))
+	}
+
+	if _, err := provider.SyncZone(ctx, fakeZone, desired, SyncOptions{Apply: true}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = provider.SyncZone(ctx, fakeZone, desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected the zone to be in sync, got %+v", plan)
+	}
+
+	// Merge keeps records that aren't desired
+	if _, err := provider.SyncZone(ctx, fakeZone, desired[:1], SyncOptions{Apply: true, Merge: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Records(fakeZone)) != 3 {
+		t.Errorf("expected no records to be deleted, got %v", server.Records(fakeZone))
+	}
+	if _, err := provider.SyncZone(ctx, fakeZone, desired[:1], SyncOptions{Apply: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Records(fakeZone)) != 1 {
+		t.Errorf("expected only the NS record to be left, got %v", server.Records(fakeZone))
+	}
+}